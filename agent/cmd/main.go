@@ -7,6 +7,7 @@ import (
 	"agent/web/router"
 	"flag"
 	"log"
+	"path/filepath"
 	"time"
 
 	"github.com/kardianos/service"
@@ -19,6 +20,8 @@ var (
 	action  = flag.String("action", "", "Install or uninstall the service (use 'install' or 'uninstall' or 'run')")
 	server  = flag.String("server", "", "Server address")
 	version = flag.Bool("version", false, "Show version")
+	config  = flag.String("config", "", "Monitor config file (JSON), e.g. process watch list")
+	token   = flag.String("token", "", "Agent token configured on the server (system.agent_token)")
 )
 
 func (p *program) Start(s service.Service) error {
@@ -32,6 +35,8 @@ func (p *program) run() {
 	time.Sleep(1 * time.Second)
 
 	clglobal.Address = server
+	clglobal.ConfigPath = config
+	clglobal.Token = token
 	err := request.SendHostInfoRequest()
 	if err != nil {
 		log.Panicf("Error querying host info: %v", err)
@@ -120,11 +125,25 @@ func main() {
 		}
 	}
 
+	arguments := []string{"-action", "run", "-server", *server}
+	if *config != "" {
+		// 服务的工作目录与当前目录不同，使用绝对路径
+		path, err := filepath.Abs(*config)
+		if err != nil {
+			log.Println("Invalid config path:", err)
+			return
+		}
+		arguments = append(arguments, "-config", path)
+	}
+	if *token != "" {
+		arguments = append(arguments, "-token", *token)
+	}
+
 	svcConfig := &service.Config{
 		Name:        "ccagent",
 		DisplayName: "CC Agent Service",
 		Description: "Agent service of ccagent",
-		Arguments:   []string{"-action", "run", "-server", *server},
+		Arguments:   arguments,
 		// 在系统层面设置自动启动
 		Dependencies: []string{"Requires=network.target", "After=network-online.target"},
 	}
@@ -0,0 +1,72 @@
+package calculator
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// CPUTimesPercent 两次采集之间各类CPU时间的占比（百分比）
+type CPUTimesPercent struct {
+	User   float64
+	System float64
+	Iowait float64
+	Steal  float64
+	Idle   float64
+}
+
+// CPUCalculator CPU时间占比和上下文切换速率计算器
+type CPUCalculator struct {
+	prevTimes *cpu.TimesStat
+	prevCtxt  uint64
+	prevAt    time.Time
+	mutex     sync.Mutex
+}
+
+// NewCPUCalculator 创建新的CPU计算器
+func NewCPUCalculator() *CPUCalculator {
+	return &CPUCalculator{}
+}
+
+// cpuTotal 各类时间之和，guest 已计入 user，不重复累加
+func cpuTotal(t *cpu.TimesStat) float64 {
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+// Calculate 计算各类CPU时间占比和每秒上下文切换次数，首次采集返回 0
+func (cc *CPUCalculator) Calculate(current cpu.TimesStat, ctxt uint64) (CPUTimesPercent, float64) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	now := time.Now()
+	var percent CPUTimesPercent
+	var ctxtRate float64
+	if cc.prevTimes != nil {
+		prev := cc.prevTimes
+		if total := cpuTotal(&current) - cpuTotal(prev); total > 0 {
+			share := func(cur, old float64) float64 {
+				if cur < old {
+					return 0
+				}
+				return (cur - old) / total * 100
+			}
+			percent = CPUTimesPercent{
+				User:   share(current.User+current.Nice, prev.User+prev.Nice),
+				System: share(current.System+current.Irq+current.Softirq, prev.System+prev.Irq+prev.Softirq),
+				Iowait: share(current.Iowait, prev.Iowait),
+				Steal:  share(current.Steal, prev.Steal),
+				Idle:   share(current.Idle, prev.Idle),
+			}
+		}
+		if duration := now.Sub(cc.prevAt).Seconds(); duration > 0 {
+			ctxtRate = counterRate(cc.prevCtxt, ctxt, duration)
+		}
+	}
+
+	// 保存当前状态
+	cc.prevTimes = &current
+	cc.prevCtxt = ctxt
+	cc.prevAt = now
+	return percent, ctxtRate
+}
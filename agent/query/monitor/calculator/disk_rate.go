@@ -1,47 +1,78 @@
 package calculator
 
 import (
+	"agent/query/monitor/models"
 	"sync"
 	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
 )
 
-// DiskCalculator 磁盘速率计算器
+// diskSample 上次采集的IO计数器
+type diskSample struct {
+	counters disk.IOCountersStat
+	at       time.Time
+}
+
+// DiskCalculator 磁盘速率计算器，按设备分别记录上次的计数器
 type DiskCalculator struct {
-	prevReadBytes  uint64
-	prevWriteBytes uint64
-	prevTime       time.Time
-	mutex          sync.Mutex
+	prev  map[string]diskSample
+	mutex sync.Mutex
 }
 
 // NewDiskCalculator 创建新的磁盘速率计算器
 func NewDiskCalculator() *DiskCalculator {
 	return &DiskCalculator{
-		prevTime: time.Now(),
+		prev: make(map[string]diskSample),
 	}
 }
 
-// CalculateDiskRates 计算磁盘读写速率
-func (dc *DiskCalculator) CalculateDiskRates(readBytes, writeBytes uint64) (float64, float64) {
+// CalculateDiskRates 根据累计计数器计算设备的读写速率、IOPS 和繁忙时间占比，首次采集的设备速率为 0
+func (dc *DiskCalculator) CalculateDiskRates(current disk.IOCountersStat) models.DiskIOStats {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
 
-	duration := time.Since(dc.prevTime).Seconds()
-	var readRate, writeRate float64
-
-	if duration > 0 {
-		// 处理计数器溢出
-		if readBytes >= dc.prevReadBytes {
-			readRate = float64(readBytes-dc.prevReadBytes) / duration
-		}
-		if writeBytes >= dc.prevWriteBytes {
-			writeRate = float64(writeBytes-dc.prevWriteBytes) / duration
+	now := time.Now()
+	stats := models.DiskIOStats{
+		Name:       current.Name,
+		ReadBytes:  current.ReadBytes,
+		WriteBytes: current.WriteBytes,
+	}
+	if prev, exists := dc.prev[current.Name]; exists {
+		duration := now.Sub(prev.at).Seconds()
+		if duration > 0 {
+			// 计数器重置（如设备重新挂载）时本次速率为 0
+			stats.ReadRate = counterRate(prev.counters.ReadBytes, current.ReadBytes, duration)
+			stats.WriteRate = counterRate(prev.counters.WriteBytes, current.WriteBytes, duration)
+			stats.ReadIOPS = counterRate(prev.counters.ReadCount, current.ReadCount, duration)
+			stats.WriteIOPS = counterRate(prev.counters.WriteCount, current.WriteCount, duration)
+			// IoTime 为毫秒
+			stats.UtilPercent = counterRate(prev.counters.IoTime, current.IoTime, duration) / 10
+			if stats.UtilPercent > 100 {
+				stats.UtilPercent = 100
+			}
 		}
 	}
 
 	// 保存当前状态
-	dc.prevReadBytes = readBytes
-	dc.prevWriteBytes = writeBytes
-	dc.prevTime = time.Now()
+	dc.prev[current.Name] = diskSample{counters: current, at: now}
+	return stats
+}
 
-	return readRate, writeRate
+// Forget 删除已不存在的设备
+func (dc *DiskCalculator) Forget(present map[string]bool) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+	for name := range dc.prev {
+		if !present[name] {
+			delete(dc.prev, name)
+		}
+	}
+}
+
+func counterRate(prev, current uint64, duration float64) float64 {
+	if current < prev {
+		return 0
+	}
+	return float64(current-prev) / duration
 }
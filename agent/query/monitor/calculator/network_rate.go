@@ -6,10 +6,10 @@ import (
 	"time"
 )
 
-// NetworkCalculator 网络速率计算器
+// NetworkCalculator 网络速率计算器，每块网卡分别记录上次的统计和时间
 type NetworkCalculator struct {
 	prevStats map[string]*models.InterfaceStats
-	prevTime  time.Time
+	prevTimes map[string]time.Time
 	mutex     sync.Mutex
 }
 
@@ -17,7 +17,7 @@ type NetworkCalculator struct {
 func NewNetworkCalculator() *NetworkCalculator {
 	return &NetworkCalculator{
 		prevStats: make(map[string]*models.InterfaceStats),
-		prevTime:  time.Now(),
+		prevTimes: make(map[string]time.Time),
 	}
 }
 
@@ -31,7 +31,7 @@ func (nc *NetworkCalculator) CalculateNetworkRates(current *models.InterfaceStat
 	defer nc.mutex.Unlock()
 
 	if prev, exists := nc.prevStats[current.Name]; exists {
-		duration := time.Since(nc.prevTime).Seconds()
+		duration := time.Since(nc.prevTimes[current.Name]).Seconds()
 		if duration > 0 {
 			// 处理计数器重置或溢出的情况
 			if current.TotalRecvBytes < prev.TotalRecvBytes {
@@ -61,5 +61,17 @@ func (nc *NetworkCalculator) CalculateNetworkRates(current *models.InterfaceStat
 	// 保存当前状态的副本
 	clone := *current
 	nc.prevStats[current.Name] = &clone
-	nc.prevTime = time.Now()
+	nc.prevTimes[current.Name] = time.Now()
+}
+
+// Forget 删除已不存在的网卡
+func (nc *NetworkCalculator) Forget(present map[string]bool) {
+	nc.mutex.Lock()
+	defer nc.mutex.Unlock()
+	for name := range nc.prevStats {
+		if !present[name] {
+			delete(nc.prevStats, name)
+			delete(nc.prevTimes, name)
+		}
+	}
 }
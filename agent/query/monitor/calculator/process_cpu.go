@@ -0,0 +1,56 @@
+package calculator
+
+import (
+	"sync"
+	"time"
+)
+
+// processSample 上次采集的进程CPU时间
+type processSample struct {
+	createTime int64   // 进程启动时间，用于识别进程号复用
+	cpuSeconds float64 // 用户态和内核态CPU时间之和（秒）
+	at         time.Time
+}
+
+// ProcessCalculator 进程CPU使用率计算器
+type ProcessCalculator struct {
+	prev  map[int32]processSample
+	mutex sync.Mutex
+}
+
+// NewProcessCalculator 创建新的进程CPU使用率计算器
+func NewProcessCalculator() *ProcessCalculator {
+	return &ProcessCalculator{
+		prev: make(map[int32]processSample),
+	}
+}
+
+// CalculateCPUPercent 根据两次采集之间的CPU时间计算使用率，首次采集的进程为 0
+func (pc *ProcessCalculator) CalculateCPUPercent(pid int32, createTime int64, cpuSeconds float64) float64 {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	now := time.Now()
+	var percent float64
+	if prev, exists := pc.prev[pid]; exists && prev.createTime == createTime {
+		duration := now.Sub(prev.at).Seconds()
+		if duration > 0 && cpuSeconds >= prev.cpuSeconds {
+			percent = (cpuSeconds - prev.cpuSeconds) / duration * 100
+		}
+	}
+
+	// 保存当前状态
+	pc.prev[pid] = processSample{createTime: createTime, cpuSeconds: cpuSeconds, at: now}
+	return percent
+}
+
+// Forget 删除已退出的进程
+func (pc *ProcessCalculator) Forget(present map[int32]bool) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	for pid := range pc.prev {
+		if !present[pid] {
+			delete(pc.prev, pid)
+		}
+	}
+}
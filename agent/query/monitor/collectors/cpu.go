@@ -1,15 +1,22 @@
 package collectors
 
 import (
+	"agent/query/monitor/calculator"
+
 	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/load"
 )
 
 // CPUCollector CPU信息收集器
-type CPUCollector struct{}
+type CPUCollector struct {
+	calculator *calculator.CPUCalculator
+}
 
 // NewCPUCollector 创建新的CPU收集器
 func NewCPUCollector() *CPUCollector {
-	return &CPUCollector{}
+	return &CPUCollector{
+		calculator: calculator.NewCPUCalculator(),
+	}
 }
 
 // Collect 收集CPU使用率
@@ -28,3 +35,18 @@ func (cc *CPUCollector) Collect() (float64, error) {
 
 	return 0, nil
 }
+
+// CollectBreakdown 收集用户态、内核态、IO等待、steal、空闲时间占比和每秒上下文切换次数
+func (cc *CPUCollector) CollectBreakdown() (calculator.CPUTimesPercent, float64, error) {
+	times, err := cpu.Times(false)
+	if err != nil || len(times) == 0 {
+		return calculator.CPUTimesPercent{}, 0, err
+	}
+	// 上下文切换次数只有 Linux 提供，其他系统为 0
+	var ctxt uint64
+	if misc, err := load.Misc(); err == nil && misc.Ctxt > 0 {
+		ctxt = uint64(misc.Ctxt)
+	}
+	percent, ctxtRate := cc.calculator.Calculate(times[0], ctxt)
+	return percent, ctxtRate, nil
+}
@@ -0,0 +1,311 @@
+package collectors
+
+import (
+	"agent/query/monitor/config"
+	"agent/query/monitor/models"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 检查脚本和 exporter 默认超时时间
+	defaultCustomTimeout = 10 * time.Second
+	// 一次抓取最多上报的序列数，避免 exporter 的指标过多
+	maxExporterSeries = 1000
+)
+
+// CustomCollector 自定义指标收集器，按各自的间隔执行检查脚本、抓取 exporter
+type CustomCollector struct {
+	config          config.CustomConfig
+	defaultInterval time.Duration
+	client          *http.Client
+}
+
+// NewCustomCollector 创建新的自定义指标收集器，配置应已通过 CustomConfig.Validate 检查
+func NewCustomCollector(cfg config.CustomConfig, defaultInterval time.Duration) *CustomCollector {
+	return &CustomCollector{
+		config:          cfg,
+		defaultInterval: defaultInterval,
+		client:          &http.Client{},
+	}
+}
+
+// Enabled 是否配置了检查脚本或 exporter
+func (cc *CustomCollector) Enabled() bool {
+	return len(cc.config.Scripts) > 0 || len(cc.config.Exporters) > 0
+}
+
+// Start 启动所有检查脚本和 exporter 的定时任务，结果写入 metricsChan
+func (cc *CustomCollector) Start(metricsChan chan<- *models.CustomMetrics) {
+	for _, script := range cc.config.Scripts {
+		script := script
+		go cc.loop(script.Interval, func() {
+			metrics, err := cc.RunScript(script)
+			if err != nil {
+				log.Printf("执行检查脚本 %s 时出错: %v", script.Name, err)
+			}
+			cc.emit(metricsChan, metrics)
+		})
+	}
+	for _, exporter := range cc.config.Exporters {
+		exporter := exporter
+		go cc.loop(exporter.Interval, func() {
+			metrics, err := cc.ScrapeExporter(exporter)
+			if err != nil {
+				log.Printf("抓取 exporter %s 时出错: %v", exporter.URL, err)
+				return
+			}
+			cc.emit(metricsChan, metrics)
+		})
+	}
+}
+
+func (cc *CustomCollector) loop(interval int, run func()) {
+	d := cc.defaultInterval
+	if interval > 0 {
+		d = time.Duration(interval) * time.Second
+	}
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+
+	run()
+	for range ticker.C {
+		run()
+	}
+}
+
+func (cc *CustomCollector) emit(metricsChan chan<- *models.CustomMetrics, metrics []models.CustomMetric) {
+	if len(metrics) == 0 {
+		return
+	}
+	metricsChan <- &models.CustomMetrics{CollectedAt: time.Now().Unix(), Metrics: metrics}
+}
+
+func customTimeout(seconds int) time.Duration {
+	if seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultCustomTimeout
+}
+
+// RunScript 执行检查脚本，返回输出的指标和 <Name>_exit_code。
+// 脚本执行失败（超时、退出码非 0）时仍返回退出码，输出无法解析时返回错误
+func (cc *CustomCollector) RunScript(script config.ScriptCheck) ([]models.CustomMetric, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), customTimeout(script.Timeout))
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", script.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", script.Command)
+	}
+	// 超时后 sh 被结束，但其子进程可能仍持有输出管道，不再等待
+	cmd.WaitDelay = time.Second
+	output, runErr := cmd.Output()
+
+	exitCode := 0
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) || ctx.Err() != nil {
+			// 无法启动或超时，只上报退出码 -1
+			return []models.CustomMetric{{Name: script.Name + "_exit_code", Tags: script.Tags, Value: -1}}, runErr
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	metrics, err := ParseExposition(bytes.NewReader(output), script.Name)
+	for i := range metrics {
+		metrics[i].Tags = mergeTags(metrics[i].Tags, script.Tags)
+	}
+	metrics = append(metrics, models.CustomMetric{Name: script.Name + "_exit_code", Tags: script.Tags, Value: float64(exitCode)})
+	return metrics, err
+}
+
+// ScrapeExporter 抓取 exporter 的指标，只保留 Include 前缀匹配的指标
+func (cc *CustomCollector) ScrapeExporter(exporter config.ExporterTarget) ([]models.CustomMetric, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), customTimeout(exporter.Timeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, exporter.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+	resp, err := cc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("状态码: %d", resp.StatusCode)
+	}
+
+	metrics, err := ParseExposition(resp.Body, "")
+	if err != nil {
+		return nil, err
+	}
+	result := metrics[:0]
+	for _, metric := range metrics {
+		if !hasAnyPrefix(metric.Name, exporter.Include) {
+			continue
+		}
+		if len(result) >= maxExporterSeries {
+			log.Printf("exporter %s 的指标超过 %d 个，其余的不再上报", exporter.URL, maxExporterSeries)
+			break
+		}
+		metric.Tags = mergeTags(metric.Tags, exporter.Tags)
+		result = append(result, metric)
+	}
+	return result, nil
+}
+
+func hasAnyPrefix(name string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeTags 合并标签，配置的标签优先
+func mergeTags(tags, extra map[string]string) map[string]string {
+	if len(extra) == 0 {
+		return tags
+	}
+	merged := make(map[string]string, len(tags)+len(extra))
+	for k, v := range tags {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+// ParseExposition 解析 Prometheus 文本格式，每行为 name{label="value",...} value [timestamp]，
+// 忽略空行、注释和 NaN、Inf。defaultName 不为空时，只有一个数值的行作为该名称的指标
+func ParseExposition(r io.Reader, defaultName string) ([]models.CustomMetric, error) {
+	var metrics []models.CustomMetric
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if defaultName != "" {
+			if value, err := strconv.ParseFloat(line, 64); err == nil {
+				if !math.IsNaN(value) && !math.IsInf(value, 0) {
+					metrics = append(metrics, models.CustomMetric{Name: defaultName, Value: value})
+				}
+				continue
+			}
+		}
+		metric, err := parseExpositionLine(line)
+		if err != nil {
+			return metrics, fmt.Errorf("第 %d 行: %v", lineNo, err)
+		}
+		if !math.IsNaN(metric.Value) && !math.IsInf(metric.Value, 0) {
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics, scanner.Err()
+}
+
+func parseExpositionLine(line string) (models.CustomMetric, error) {
+	var metric models.CustomMetric
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return metric, errors.New("缺少数值")
+	}
+	metric.Name = line[:end]
+	rest := line[end:]
+
+	if rest[0] == '{' {
+		tags, n, err := parseLabels(rest)
+		if err != nil {
+			return metric, err
+		}
+		metric.Tags = tags
+		rest = rest[n:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return metric, errors.New("缺少数值")
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return metric, fmt.Errorf("数值 %q 不合法", fields[0])
+	}
+	metric.Value = value
+	return metric, nil
+}
+
+// parseLabels 解析 {a="1",b="2"}，返回标签和消耗的长度
+func parseLabels(s string) (map[string]string, int, error) {
+	tags := make(map[string]string)
+	i := 1
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, errors.New("标签未结束")
+		}
+		if s[i] == '}' {
+			return tags, i + 1, nil
+		}
+		eq := strings.IndexByte(s[i:], '=')
+		if eq <= 0 {
+			return nil, 0, errors.New("标签格式错误")
+		}
+		name := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, errors.New("标签值缺少引号")
+		}
+		i++
+		var value strings.Builder
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			value.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return nil, 0, errors.New("标签值未结束")
+		}
+		i++
+		tags[name] = value.String()
+	}
+}
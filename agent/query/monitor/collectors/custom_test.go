@@ -0,0 +1,131 @@
+package collectors
+
+import (
+	"agent/query/monitor/models"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// formatMetrics 把指标写成 name{k="v",...}=value 的形式，标签按名称排序
+func formatMetrics(metrics []models.CustomMetric) string {
+	parts := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		keys := make([]string, 0, len(m.Tags))
+		for k := range m.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		tags := make([]string, 0, len(keys))
+		for _, k := range keys {
+			tags = append(tags, fmt.Sprintf("%s=%q", k, m.Tags[k]))
+		}
+		text := m.Name
+		if len(tags) > 0 {
+			text += "{" + strings.Join(tags, ",") + "}"
+		}
+		parts = append(parts, fmt.Sprintf("%s=%v", text, m.Value))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestParseExposition(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		defaultName string
+		want        string
+		wantErr     bool
+	}{
+		{name: "空输入", input: "", want: ""},
+		{name: "注释和空行", input: "# HELP up 是否存活\n# TYPE up gauge\n\n   \nup 1\n", want: "up=1"},
+		{name: "带时间戳", input: "http_requests_total 1027 1395066363000", want: "http_requests_total=1027"},
+		{name: "标签", input: `queue_depth{queue="orders",region="cn"} 12`, want: `queue_depth{queue="orders",region="cn"}=12`},
+		{name: "标签中的空格和尾部逗号", input: `queue_depth{ queue = "orders", } 3.5`, want: `queue_depth{queue="orders"}=3.5`},
+		{name: "空标签", input: `up{} 1`, want: `up=1`},
+		{name: "标签值转义", input: `msg{text="a\"b\\c\nd"} 1`, want: `msg{text="a\"b\\c\nd"}=1`},
+		{name: "标签值中的特殊字符", input: `path{p="{a,b}=c"} 2`, want: `path{p="{a,b}=c"}=2`},
+		{name: "科学计数法和负数", input: "a 1e3\nb -2.5\nc +0.5", want: "a=1000 b=-2.5 c=0.5"},
+		{name: "忽略 NaN 和 Inf", input: "a NaN\nb +Inf\nc -Inf\nd 1", want: "d=1"},
+		{name: "制表符分隔", input: "a\t7", want: "a=7"},
+		{name: "脚本只输出数值", input: "42\n", defaultName: "check", want: "check=42"},
+		{name: "脚本输出数值和指标", input: "1\nlatency_ms 30\n", defaultName: "check", want: "check=1 latency_ms=30"},
+		{name: "脚本输出 NaN", input: "NaN\n", defaultName: "check", want: ""},
+		{name: "未指定名称时只有数值的行不合法", input: "42\n", wantErr: true},
+
+		{name: "缺少数值", input: "up", wantErr: true},
+		{name: "标签后缺少数值", input: `up{a="1"}`, wantErr: true},
+		{name: "数值不合法", input: "up abc", wantErr: true},
+		{name: "多余的字段", input: "up 1 2 3", wantErr: true},
+		{name: "标签值缺少引号", input: `up{a=1} 1`, wantErr: true},
+		{name: "标签缺少等号", input: `up{a} 1`, wantErr: true},
+		{name: "标签未结束", input: `up{a="1" 1`, wantErr: true},
+		{name: "标签值未结束", input: `up{a="1} 1`, wantErr: true},
+		{name: "错误前的行仍返回", input: "a 1\nb x\nc 3", want: "a=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		metrics, err := ParseExposition(strings.NewReader(tt.input), tt.defaultName)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: 错误为 %v，是否应出错: %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got := formatMetrics(metrics); got != tt.want {
+			t.Errorf("%s: 结果为 %s，应为 %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseExpositionLineNumber(t *testing.T) {
+	_, err := ParseExposition(strings.NewReader("# 注释\na 1\n\nb x\n"), "")
+	if err == nil || !strings.HasPrefix(err.Error(), "第 4 行") {
+		t.Errorf("错误应指出第 4 行，得到 %v", err)
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    map[string]string
+		n       int // 消耗的长度
+		wantErr bool
+	}{
+		{input: `{}`, want: map[string]string{}, n: 2},
+		{input: `{} 1`, want: map[string]string{}, n: 2},
+		{input: `{a="1"} 1`, want: map[string]string{"a": "1"}, n: 7},
+		{input: `{a="1",b="2"}`, want: map[string]string{"a": "1", "b": "2"}, n: 13},
+		{input: `{a="1",}`, want: map[string]string{"a": "1"}, n: 8},
+		{input: `{ a = "1" , b="" }`, want: map[string]string{"a": "1", "b": ""}, n: 18},
+		{input: `{a="x",a="y"}`, want: map[string]string{"a": "y"}, n: 13},
+		{input: `{a="\""}`, want: map[string]string{"a": `"`}, n: 8},
+		{input: `{a="\\"}`, want: map[string]string{"a": `\`}, n: 8},
+		{input: `{a="\n"}`, want: map[string]string{"a": "\n"}, n: 8},
+		{input: `{a="}"}`, want: map[string]string{"a": "}"}, n: 7},
+
+		{input: `{`, wantErr: true},
+		{input: `{a="1"`, wantErr: true},
+		{input: `{a="1`, wantErr: true},
+		{input: `{a="1\"}`, wantErr: true},
+		{input: `{a}`, wantErr: true},
+		{input: `{=1}`, wantErr: true},
+		{input: `{a=1}`, wantErr: true},
+		{input: `{a=}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tags, n, err := parseLabels(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: 应解析失败，得到 %v", tt.input, tags)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: 解析失败: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(tags, tt.want) || n != tt.n {
+			t.Errorf("%s: 结果为 %v（%d），应为 %v（%d）", tt.input, tags, n, tt.want, tt.n)
+		}
+	}
+}
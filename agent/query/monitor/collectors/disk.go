@@ -1,7 +1,13 @@
 package collectors
 
 import (
+	"agent/query/monitor/calculator"
 	"agent/query/monitor/models"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 
 	"github.com/shirou/gopsutil/v3/disk"
 )
@@ -10,20 +16,22 @@ import (
 type DiskCollector struct {
 	// 忽略的文件系统类型
 	ignoredFSTypes map[string]bool
+	// 忽略的设备名前缀，如 loop、ram
+	ignoredDevicePrefixes []string
+	calculator            *calculator.DiskCalculator
 }
 
 // NewDiskCollector 创建新的磁盘收集器
-func NewDiskCollector() *DiskCollector {
-	return &DiskCollector{
-		ignoredFSTypes: map[string]bool{
-			"tmpfs":    true,
-			"devtmpfs": true,
-			"devfs":    true,
-			"overlay":  false, // 允许overlay文件系统
-			"squashfs": false, // 允许squashfs文件系统
-			"rootfs":   false, // 允许rootfs
-		},
+func NewDiskCollector(ignoredFSTypes, ignoredDevicePrefixes []string) *DiskCollector {
+	dc := &DiskCollector{
+		ignoredFSTypes:        make(map[string]bool),
+		ignoredDevicePrefixes: ignoredDevicePrefixes,
+		calculator:            calculator.NewDiskCalculator(),
 	}
+	for _, fsType := range ignoredFSTypes {
+		dc.ignoredFSTypes[fsType] = true
+	}
+	return dc
 }
 
 // isIgnoredFSType 检查是否是需要忽略的文件系统类型
@@ -31,7 +39,7 @@ func (dc *DiskCollector) isIgnoredFSType(fsType string) bool {
 	return dc.ignoredFSTypes[fsType]
 }
 
-// Collect 收集磁盘使用情况
+// Collect 收集所有已挂载文件系统的使用情况，根目录排在最前
 func (dc *DiskCollector) Collect() ([]models.DiskUsage, error) {
 	var diskUsages []models.DiskUsage
 
@@ -42,16 +50,18 @@ func (dc *DiskCollector) Collect() ([]models.DiskUsage, error) {
 	}
 
 	// 遍历处理每个分区
+	seen := make(map[string]bool)
 	for _, partition := range partitions {
-		// 跳过特殊文件系统
-		if dc.isIgnoredFSType(partition.Fstype) {
+		// 跳过特殊文件系统和重复挂载
+		if dc.isIgnoredFSType(partition.Fstype) || seen[partition.Mountpoint] {
 			continue
 		}
 
 		usage, err := disk.Usage(partition.Mountpoint)
-		if err != nil {
+		if err != nil || usage.Total == 0 {
 			continue
 		}
+		seen[partition.Mountpoint] = true
 
 		diskUsage := models.DiskUsage{
 			MountPoint:   partition.Mountpoint,
@@ -61,9 +71,73 @@ func (dc *DiskCollector) Collect() ([]models.DiskUsage, error) {
 			FreeBytes:    usage.Free,
 			UsagePercent: usage.UsedPercent,
 			FSType:       partition.Fstype,
+
+			InodesTotal:        usage.InodesTotal,
+			InodesUsed:         usage.InodesUsed,
+			InodesFree:         usage.InodesFree,
+			InodesUsagePercent: usage.InodesUsedPercent,
 		}
 		diskUsages = append(diskUsages, diskUsage)
 	}
 
+	sort.Slice(diskUsages, func(i, j int) bool {
+		if isRootMount(diskUsages[i].MountPoint) != isRootMount(diskUsages[j].MountPoint) {
+			return isRootMount(diskUsages[i].MountPoint)
+		}
+		return diskUsages[i].MountPoint < diskUsages[j].MountPoint
+	})
 	return diskUsages, nil
 }
+
+// CollectIO 收集每个块设备的读写速率，按设备名排序
+func (dc *DiskCollector) CollectIO() ([]models.DiskIOStats, error) {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return nil, err
+	}
+
+	present := make(map[string]bool, len(counters))
+	stats := make([]models.DiskIOStats, 0, len(counters))
+	for name, counter := range counters {
+		if dc.isIgnoredDevice(name) {
+			continue
+		}
+		present[name] = true
+		// 从未有过读写的设备（如未使用的光驱）不上报
+		if counter.ReadCount == 0 && counter.WriteCount == 0 {
+			continue
+		}
+		stat := dc.calculator.CalculateDiskRates(counter)
+		stat.Partition = isPartition(name)
+		stats = append(stats, stat)
+	}
+	dc.calculator.Forget(present)
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats, nil
+}
+
+func (dc *DiskCollector) isIgnoredDevice(name string) bool {
+	for _, prefix := range dc.ignoredDevicePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRootMount(mountPoint string) bool {
+	return mountPoint == "/" || (runtime.GOOS == "windows" && strings.EqualFold(mountPoint, "C:"))
+}
+
+// isPartition Linux 上整块磁盘在 /sys/block 下有同名目录，分区没有；其他系统都按整块磁盘处理
+func isPartition(name string) bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	if _, err := os.Stat("/sys/block"); err != nil {
+		return false
+	}
+	_, err := os.Stat(filepath.Join("/sys/block", name))
+	return err != nil
+}
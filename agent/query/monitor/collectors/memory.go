@@ -21,11 +21,21 @@ func (mc *MemoryCollector) Collect() (*models.MemoryStatus, error) {
 		return nil, err
 	}
 
-	return &models.MemoryStatus{
+	status := &models.MemoryStatus{
 		TotalBytes:     vm.Total,
 		UsedBytes:      vm.Used,
 		FreeBytes:      vm.Free,
 		AvailableBytes: vm.Available,
 		UsagePercent:   vm.UsedPercent,
-	}, nil
+	}
+
+	// 获取交换分区信息，没有交换分区时全部为 0
+	if swap, err := mem.SwapMemory(); err == nil {
+		status.SwapTotalBytes = swap.Total
+		status.SwapUsedBytes = swap.Used
+		status.SwapFreeBytes = swap.Free
+		status.SwapUsagePercent = swap.UsedPercent
+	}
+
+	return status, nil
 }
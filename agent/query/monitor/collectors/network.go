@@ -4,19 +4,27 @@ import (
 	"agent/query/monitor/calculator"
 	"agent/query/monitor/models"
 	"net"
+	"os"
+	"runtime"
 
 	psnet "github.com/shirou/gopsutil/v3/net"
 )
 
 // NetworkCollector 网络信息收集器
 type NetworkCollector struct {
-	calculator *calculator.NetworkCalculator
+	calculator     *calculator.NetworkCalculator
+	ignoreLoopback bool     // 跳过本地回环
+	ignoreDown     bool     // 跳过未启用的网卡
+	ignoredNames   []string // 跳过的网卡名前缀
 }
 
 // NewNetworkCollector 创建新的网络收集器
-func NewNetworkCollector() *NetworkCollector {
+func NewNetworkCollector(ignoreLoopback, ignoreDown bool, ignoredNames []string) *NetworkCollector {
 	return &NetworkCollector{
-		calculator: calculator.NewNetworkCalculator(),
+		calculator:     calculator.NewNetworkCalculator(),
+		ignoreLoopback: ignoreLoopback,
+		ignoreDown:     ignoreDown,
+		ignoredNames:   ignoredNames,
 	}
 }
 
@@ -37,15 +45,20 @@ func (nc *NetworkCollector) Collect() ([]models.InterfaceStats, error) {
 	}
 
 	// 处理每个网卡
+	present := make(map[string]bool, len(interfaces))
 	for _, iface := range interfaces {
 		// 跳过本地回环和非活动接口
-		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+		if (nc.ignoreLoopback && iface.Flags&net.FlagLoopback != 0) || (nc.ignoreDown && iface.Flags&net.FlagUp == 0) {
+			continue
+		}
+		if len(nc.ignoredNames) > 0 && hasAnyPrefix(iface.Name, nc.ignoredNames) {
 			continue
 		}
 
 		stat := models.InterfaceStats{
 			Name:       iface.Name,
 			MacAddress: iface.HardwareAddr.String(),
+			Virtual:    isVirtualInterface(iface),
 		}
 
 		// 获取IP地址
@@ -73,9 +86,24 @@ func (nc *NetworkCollector) Collect() ([]models.InterfaceStats, error) {
 
 		// 计算网络速率
 		nc.calculator.CalculateNetworkRates(&stat)
+		present[iface.Name] = true
 
 		networkStats = append(networkStats, stat)
 	}
+	nc.calculator.Forget(present)
 
 	return networkStats, nil
 }
+
+// isVirtualInterface 回环、没有 MAC 地址的网卡，以及 Linux 上 /sys/devices/virtual/net 下的网卡（docker、veth、bridge 等）为虚拟网卡
+func isVirtualInterface(iface net.Interface) bool {
+	if iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) == 0 {
+		return true
+	}
+	if runtime.GOOS == "linux" {
+		if _, err := os.Stat("/sys/devices/virtual/net/" + iface.Name); err == nil {
+			return true
+		}
+	}
+	return false
+}
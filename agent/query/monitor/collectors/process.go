@@ -0,0 +1,173 @@
+package collectors
+
+import (
+	"agent/query/monitor/calculator"
+	"agent/query/monitor/config"
+	"agent/query/monitor/models"
+	"regexp"
+	"sort"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// 上报的可执行文件路径最大长度
+const maxExeLength = 256
+
+// processWatcher 编译后的进程监控项
+type processWatcher struct {
+	config.ProcessWatch
+	cmdline *regexp.Regexp
+}
+
+// ProcessCollector 进程信息收集器
+type ProcessCollector struct {
+	topN       int
+	watchers   []processWatcher
+	calculator *calculator.ProcessCalculator
+}
+
+// processInfo 一次采集中单个进程的数据
+type processInfo struct {
+	proc     *process.Process
+	name     string
+	cmdline  string
+	detailed bool // 已读取可执行文件路径、用户等详细信息
+	stats    models.ProcessStats
+}
+
+// NewProcessCollector 创建新的进程收集器，监控项应已通过 ProcessConfig.Validate 检查
+func NewProcessCollector(cfg config.ProcessConfig) *ProcessCollector {
+	pc := &ProcessCollector{
+		topN:       cfg.TopN,
+		calculator: calculator.NewProcessCalculator(),
+	}
+	for _, watch := range cfg.Watch {
+		w := processWatcher{ProcessWatch: watch}
+		if watch.Cmdline != "" {
+			w.cmdline = regexp.MustCompile(watch.Cmdline)
+		}
+		pc.watchers = append(pc.watchers, w)
+	}
+	return pc
+}
+
+// Collect 收集进程总数、CPU和内存占用最高的进程，以及监控列表中各项的汇总，totalMemory 用于计算内存使用率
+func (pc *ProcessCollector) Collect(totalMemory uint64) (total int, top []models.ProcessStats, watch []models.ProcessWatchStats, err error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	present := make(map[int32]bool, len(procs))
+	infos := make([]*processInfo, 0, len(procs))
+	for _, proc := range procs {
+		name, err := proc.Name()
+		if err != nil {
+			// 进程已退出
+			continue
+		}
+		info := &processInfo{proc: proc, name: name}
+		info.stats.PID = proc.Pid
+		info.stats.Name = name
+		if times, err := proc.Times(); err == nil {
+			createTime, _ := proc.CreateTime()
+			info.stats.CPUPercent = pc.calculator.CalculateCPUPercent(proc.Pid, createTime, times.User+times.System)
+		}
+		if mem, err := proc.MemoryInfo(); err == nil {
+			info.stats.RSSBytes = mem.RSS
+			if totalMemory > 0 {
+				info.stats.MemoryPercent = float64(mem.RSS) / float64(totalMemory) * 100
+			}
+		}
+		present[proc.Pid] = true
+		infos = append(infos, info)
+	}
+	pc.calculator.Forget(present)
+
+	watch = make([]models.ProcessWatchStats, 0, len(pc.watchers))
+	for _, w := range pc.watchers {
+		stat := models.ProcessWatchStats{Name: w.Name}
+		for _, info := range infos {
+			if !pc.matches(w, info) {
+				continue
+			}
+			pc.fillDetails(info)
+			stat.Count++
+			stat.CPUPercent += info.stats.CPUPercent
+			stat.RSSBytes += info.stats.RSSBytes
+			stat.NumFDs += info.stats.NumFDs
+			stat.NumThreads += info.stats.NumThreads
+		}
+		watch = append(watch, stat)
+	}
+
+	return len(infos), pc.top(infos), watch, nil
+}
+
+// matches 进程是否匹配监控项，只在需要时读取命令行
+func (pc *ProcessCollector) matches(w processWatcher, info *processInfo) bool {
+	if w.Process != "" && w.Process != info.name {
+		return false
+	}
+	if w.cmdline != nil {
+		return w.cmdline.MatchString(pc.cmdline(info))
+	}
+	return true
+}
+
+func (pc *ProcessCollector) cmdline(info *processInfo) string {
+	if info.cmdline == "" {
+		info.cmdline, _ = info.proc.Cmdline()
+	}
+	return info.cmdline
+}
+
+// fillDetails 读取可执行文件路径、用户、文件描述符和线程数，只对上报的进程读取。
+// 命令行参数中可能带有密码、令牌等，只用于本地匹配监控项，不上报
+func (pc *ProcessCollector) fillDetails(info *processInfo) {
+	if info.detailed {
+		return
+	}
+	info.detailed = true
+	exe, err := info.proc.Exe()
+	if err != nil || exe == "" {
+		exe = info.name
+	}
+	if len(exe) > maxExeLength {
+		exe = exe[:maxExeLength]
+	}
+	info.stats.Exe = exe
+	info.stats.Username, _ = info.proc.Username()
+	info.stats.NumFDs, _ = info.proc.NumFDs()
+	info.stats.NumThreads, _ = info.proc.NumThreads()
+}
+
+// top 分别按CPU和内存取前 N 个进程，合并后按CPU排序
+func (pc *ProcessCollector) top(infos []*processInfo) []models.ProcessStats {
+	if pc.topN <= 0 {
+		return nil
+	}
+	selected := make(map[int32]*processInfo)
+	pick := func(less func(a, b *processInfo) bool) {
+		sorted := append([]*processInfo(nil), infos...)
+		sort.Slice(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+		for i := 0; i < len(sorted) && i < pc.topN; i++ {
+			selected[sorted[i].stats.PID] = sorted[i]
+		}
+	}
+	pick(func(a, b *processInfo) bool { return a.stats.CPUPercent > b.stats.CPUPercent })
+	pick(func(a, b *processInfo) bool { return a.stats.RSSBytes > b.stats.RSSBytes })
+
+	result := make([]models.ProcessStats, 0, len(selected))
+	for _, info := range selected {
+		pc.fillDetails(info)
+		result = append(result, info.stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CPUPercent != result[j].CPUPercent {
+			return result[i].CPUPercent > result[j].CPUPercent
+		}
+		return result[i].RSSBytes > result[j].RSSBytes
+	})
+	return result
+}
@@ -0,0 +1,53 @@
+package collectors
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// SystemStatus 启动时间和系统文件描述符使用情况
+type SystemStatus struct {
+	BootTime      uint64
+	UptimeSeconds uint64
+	OpenFiles     uint64
+	MaxFiles      uint64
+}
+
+// SystemCollector 系统信息收集器
+type SystemCollector struct{}
+
+// NewSystemCollector 创建新的系统信息收集器
+func NewSystemCollector() *SystemCollector {
+	return &SystemCollector{}
+}
+
+// Collect 收集启动时间、运行时间和文件描述符数，文件描述符只有 Linux 提供
+func (sc *SystemCollector) Collect() (*SystemStatus, error) {
+	status := &SystemStatus{}
+	bootTime, err := host.BootTime()
+	if err != nil {
+		return nil, err
+	}
+	status.BootTime = bootTime
+	status.UptimeSeconds, _ = host.Uptime()
+
+	if runtime.GOOS == "linux" {
+		// file-nr: 已分配 未使用 上限
+		if data, err := os.ReadFile("/proc/sys/fs/file-nr"); err == nil {
+			fields := strings.Fields(string(data))
+			if len(fields) == 3 {
+				allocated, _ := strconv.ParseUint(fields[0], 10, 64)
+				unused, _ := strconv.ParseUint(fields[1], 10, 64)
+				status.MaxFiles, _ = strconv.ParseUint(fields[2], 10, 64)
+				if allocated >= unused {
+					status.OpenFiles = allocated - unused
+				}
+			}
+		}
+	}
+	return status, nil
+}
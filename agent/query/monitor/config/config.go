@@ -1,12 +1,28 @@
 package config
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+)
+
+var metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
 
 // Config 监控配置
 type Config struct {
 	// 采集间隔
 	CollectInterval time.Duration
 
+	// 主机信息上报间隔和公钥检查间隔
+	ReportInterval    time.Duration
+	PublicKeyInterval time.Duration
+
+	// 日志级别 debug/info/warn/error
+	LogLevel string
+
 	// CPU配置
 	CPUConfig struct {
 		Enable bool
@@ -19,22 +35,81 @@ type Config struct {
 
 	// 磁盘配置
 	DiskConfig struct {
-		Enable         bool
-		IgnoredFSTypes []string
+		Enable                bool
+		IgnoredFSTypes        []string
+		IgnoredDevicePrefixes []string // 不采集IO的设备名前缀
 	}
 
 	// 网络配置
 	NetworkConfig struct {
-		Enable         bool
-		IgnoreLoopback bool
-		IgnoreDown     bool
+		Enable            bool
+		IgnoreLoopback    bool
+		IgnoreDown        bool
+		IgnoredInterfaces []string // 不采集的网卡名前缀
 	}
+
+	// 进程配置
+	ProcessConfig ProcessConfig
+
+	// 自定义指标配置
+	CustomConfig CustomConfig
+}
+
+// ProcessConfig 进程采集配置
+type ProcessConfig struct {
+	Enable bool           `json:"enable"`
+	TopN   int            `json:"topN"`  // 分别按CPU和内存取前 N 个进程上报
+	Watch  []ProcessWatch `json:"watch"` // 进程监控列表
+}
+
+// ProcessWatch 进程监控项，Process 和 Cmdline 都配置时需同时满足
+type ProcessWatch struct {
+	Name    string `json:"name"`    // 监控项名称，告警规则按此名称匹配
+	Process string `json:"process"` // 进程名，精确匹配
+	Cmdline string `json:"cmdline"` // 命令行正则表达式
+}
+
+// CustomConfig 自定义指标配置：定时执行检查脚本、抓取本机 Prometheus exporter，结果作为自定义指标上报
+type CustomConfig struct {
+	Scripts   []ScriptCheck    `json:"scripts"`
+	Exporters []ExporterTarget `json:"exporters"`
+}
+
+// ScriptCheck 检查脚本。标准输出为一个数值时上报为 Name，
+// 也可以按 Prometheus 文本格式每行输出一个指标，如 queue_depth{queue="orders"} 12；
+// 另外上报 <Name>_exit_code 为脚本的退出码
+type ScriptCheck struct {
+	Name     string            `json:"name"`     // 指标名
+	Command  string            `json:"command"`  // 命令，由 sh -c（Windows 为 cmd /C）执行
+	Interval int               `json:"interval"` // 执行间隔（秒），为 0 时使用采集间隔
+	Timeout  int               `json:"timeout"`  // 超时时间（秒），为 0 时为 10 秒
+	Tags     map[string]string `json:"tags"`     // 附加到所有指标的标签
+}
+
+// ExporterTarget 本机 Prometheus exporter
+type ExporterTarget struct {
+	Name     string            `json:"name"`     // 名称，只用于日志
+	URL      string            `json:"url"`      // 指标地址，如 http://127.0.0.1:9100/metrics
+	Interval int               `json:"interval"` // 抓取间隔（秒），为 0 时使用采集间隔
+	Timeout  int               `json:"timeout"`  // 超时时间（秒），为 0 时为 10 秒
+	Include  []string          `json:"include"`  // 只上报以这些前缀开头的指标，为空时全部上报
+	Tags     map[string]string `json:"tags"`     // 附加到所有指标的标签
+}
+
+// fileConfig 配置文件格式，未配置的项保留默认值
+type fileConfig struct {
+	CollectInterval int            `json:"collectInterval"` // 采集间隔（秒）
+	Process         *ProcessConfig `json:"process"`
+	Custom          *CustomConfig  `json:"custom"`
 }
 
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	cfg := &Config{
-		CollectInterval: 5 * time.Second,
+		CollectInterval:   5 * time.Second,
+		ReportInterval:    2 * time.Minute,
+		PublicKeyInterval: 3 * time.Minute,
+		LogLevel:          "info",
 	}
 
 	// CPU默认配置
@@ -49,6 +124,13 @@ func DefaultConfig() *Config {
 		"tmpfs",
 		"devtmpfs",
 		"devfs",
+		"squashfs", // snap 等只读镜像，使用率总是 100%
+		"overlay",  // 容器根文件系统，与宿主机分区重复
+		"iso9660",  // 光盘镜像
+	}
+	cfg.DiskConfig.IgnoredDevicePrefixes = []string{
+		"loop",
+		"ram",
 	}
 
 	// 网络默认配置
@@ -56,5 +138,172 @@ func DefaultConfig() *Config {
 	cfg.NetworkConfig.IgnoreLoopback = true
 	cfg.NetworkConfig.IgnoreDown = true
 
+	// 进程默认配置
+	cfg.ProcessConfig.Enable = true
+	cfg.ProcessConfig.TopN = 10
+
 	return cfg
 }
+
+// LoadFile 读取 JSON 配置文件，如
+// {"collectInterval": 5, "process": {"topN": 10, "watch": [{"name": "nginx", "process": "nginx"}, {"name": "app", "cmdline": "java .*app\\.jar"}]},
+// "custom": {"scripts": [{"name": "queue_depth", "command": "/opt/check_queue.sh", "interval": 30, "tags": {"queue": "orders"}}],
+// "exporters": [{"name": "node", "url": "http://127.0.0.1:9100/metrics", "include": ["node_load"]}]}}
+func LoadFile(path string) (*Config, error) {
+	cfg := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := fileConfig{Process: &cfg.ProcessConfig, Custom: &cfg.CustomConfig}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+	}
+	if file.CollectInterval > 0 {
+		cfg.CollectInterval = time.Duration(file.CollectInterval) * time.Second
+	}
+	if err := cfg.ProcessConfig.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.CustomConfig.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate 检查进程监控列表
+func (pc *ProcessConfig) Validate() error {
+	names := make(map[string]bool)
+	for _, watch := range pc.Watch {
+		if watch.Name == "" {
+			return fmt.Errorf("进程监控项缺少名称")
+		}
+		if names[watch.Name] {
+			return fmt.Errorf("进程监控项 %s 重复", watch.Name)
+		}
+		names[watch.Name] = true
+		if watch.Process == "" && watch.Cmdline == "" {
+			return fmt.Errorf("进程监控项 %s 需要配置进程名或命令行", watch.Name)
+		}
+		if watch.Cmdline != "" {
+			if _, err := regexp.Compile(watch.Cmdline); err != nil {
+				return fmt.Errorf("进程监控项 %s 的命令行正则表达式错误: %v", watch.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate 检查检查脚本和 exporter 配置
+func (cc *CustomConfig) Validate() error {
+	names := make(map[string]bool)
+	for _, script := range cc.Scripts {
+		if !metricNameRe.MatchString(script.Name) {
+			return fmt.Errorf("检查脚本的指标名 %q 不合法", script.Name)
+		}
+		if names[script.Name] {
+			return fmt.Errorf("检查脚本 %s 重复", script.Name)
+		}
+		names[script.Name] = true
+		if script.Command == "" {
+			return fmt.Errorf("检查脚本 %s 缺少命令", script.Name)
+		}
+		if script.Interval < 0 || script.Timeout < 0 {
+			return fmt.Errorf("检查脚本 %s 的间隔和超时时间不能为负数", script.Name)
+		}
+	}
+	for _, exporter := range cc.Exporters {
+		u, err := url.Parse(exporter.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("exporter %s 的地址 %q 不合法", exporter.Name, exporter.URL)
+		}
+		if exporter.Interval < 0 || exporter.Timeout < 0 {
+			return fmt.Errorf("exporter %s 的间隔和超时时间不能为负数", exporter.Name)
+		}
+	}
+	return nil
+}
+
+// ServerConfig 服务端下发的配置，覆盖本地配置中的同名项；为 null 的项和为 0 的间隔保留本地配置。
+// 检查脚本和 exporter 只能在本地配置文件中配置
+type ServerConfig struct {
+	CollectInterval   int    `json:"collectInterval"`   // 系统指标采集间隔（秒）
+	ReportInterval    int    `json:"reportInterval"`    // 主机信息上报间隔（秒）
+	PublicKeyInterval int    `json:"publicKeyInterval"` // 公钥检查间隔（秒）
+	LogLevel          string `json:"logLevel"`
+
+	Collectors struct {
+		CPU     *bool `json:"cpu"`
+		Memory  *bool `json:"memory"`
+		Disk    *bool `json:"disk"`
+		Network *bool `json:"network"`
+		Process *bool `json:"process"`
+	} `json:"collectors"`
+
+	Disk struct {
+		IgnoredFSTypes        []string `json:"ignoredFsTypes"`
+		IgnoredDevicePrefixes []string `json:"ignoredDevicePrefixes"`
+	} `json:"disk"`
+
+	Network struct {
+		IgnoreLoopback    *bool    `json:"ignoreLoopback"`
+		IgnoreDown        *bool    `json:"ignoreDown"`
+		IgnoredInterfaces []string `json:"ignoredInterfaces"`
+	} `json:"network"`
+
+	Process *struct {
+		TopN  int            `json:"topN"`
+		Watch []ProcessWatch `json:"watch"`
+	} `json:"process"`
+}
+
+// WithServer 返回用服务端配置覆盖后的新配置，不修改 c
+func (c *Config) WithServer(sc *ServerConfig) (*Config, error) {
+	cfg := *c
+	if sc == nil {
+		return &cfg, nil
+	}
+	seconds := func(dst *time.Duration, n int) {
+		if n > 0 {
+			*dst = time.Duration(n) * time.Second
+		}
+	}
+	seconds(&cfg.CollectInterval, sc.CollectInterval)
+	seconds(&cfg.ReportInterval, sc.ReportInterval)
+	seconds(&cfg.PublicKeyInterval, sc.PublicKeyInterval)
+	if sc.LogLevel != "" {
+		cfg.LogLevel = sc.LogLevel
+	}
+
+	flag := func(dst *bool, v *bool) {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	flag(&cfg.CPUConfig.Enable, sc.Collectors.CPU)
+	flag(&cfg.MemoryConfig.Enable, sc.Collectors.Memory)
+	flag(&cfg.DiskConfig.Enable, sc.Collectors.Disk)
+	flag(&cfg.NetworkConfig.Enable, sc.Collectors.Network)
+	flag(&cfg.ProcessConfig.Enable, sc.Collectors.Process)
+	flag(&cfg.NetworkConfig.IgnoreLoopback, sc.Network.IgnoreLoopback)
+	flag(&cfg.NetworkConfig.IgnoreDown, sc.Network.IgnoreDown)
+
+	if sc.Disk.IgnoredFSTypes != nil {
+		cfg.DiskConfig.IgnoredFSTypes = sc.Disk.IgnoredFSTypes
+	}
+	if sc.Disk.IgnoredDevicePrefixes != nil {
+		cfg.DiskConfig.IgnoredDevicePrefixes = sc.Disk.IgnoredDevicePrefixes
+	}
+	if sc.Network.IgnoredInterfaces != nil {
+		cfg.NetworkConfig.IgnoredInterfaces = sc.Network.IgnoredInterfaces
+	}
+	if sc.Process != nil {
+		cfg.ProcessConfig.TopN = sc.Process.TopN
+		cfg.ProcessConfig.Watch = sc.Process.Watch
+	}
+
+	if err := cfg.ProcessConfig.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -11,6 +11,13 @@ type SystemMetrics struct {
 		Load1m       float64 `json:"load1m"`       // 1分钟负载
 		Load5m       float64 `json:"load5m"`       // 5分钟负载
 		Load15m      float64 `json:"load15m"`      // 15分钟负载
+
+		UserPercent     float64 `json:"userPercent"`     // 用户态（百分比）
+		SystemPercent   float64 `json:"systemPercent"`   // 内核态（百分比）
+		IowaitPercent   float64 `json:"iowaitPercent"`   // 等待IO（百分比）
+		StealPercent    float64 `json:"stealPercent"`    // 被虚拟化宿主占用（百分比）
+		IdlePercent     float64 `json:"idlePercent"`     // 空闲（百分比）
+		ContextSwitches float64 `json:"contextSwitches"` // 每秒上下文切换次数
 	} `json:"cpu"`
 
 	// 内存信息
@@ -18,12 +25,13 @@ type SystemMetrics struct {
 
 	// 磁盘信息
 	Disk struct {
-		AvailableBytes float64     `json:"availableBytes"` // 可用空间(GB)
-		TotalBytes     float64     `json:"totalBytes"`     // 总空间(GB)
-		UsagePercent   string      `json:"usagePercent"`   // 使用率(百分比)
-		ReadRate       uint64      `json:"readRate"`       // 读取速率(B/s)
-		WriteRate      uint64      `json:"writeRate"`      // 写入速率(B/s)
-		Volumes        []DiskUsage `json:"volumes"`        // 磁盘详情列表
+		AvailableBytes float64       `json:"availableBytes"` // 可用空间(GB)
+		TotalBytes     float64       `json:"totalBytes"`     // 总空间(GB)
+		UsagePercent   string        `json:"usagePercent"`   // 使用率(百分比)
+		ReadRate       uint64        `json:"readRate"`       // 读取速率(B/s)
+		WriteRate      uint64        `json:"writeRate"`      // 写入速率(B/s)
+		Volumes        []DiskUsage   `json:"volumes"`        // 磁盘详情列表
+		Devices        []DiskIOStats `json:"devices"`        // 块设备IO列表
 	} `json:"disk"`
 
 	// 网络信息
@@ -31,7 +39,24 @@ type SystemMetrics struct {
 		RecvRate   float64          `json:"RecvRate"`   // 总接收速率(B/s)
 		SendRate   float64          `json:"sendRate"`   // 总发送速率(B/s)
 		Interfaces []InterfaceStats `json:"interfaces"` // 网卡列表
+		TCP        TCPStates        `json:"tcp"`        // TCP连接状态
 	} `json:"network"`
+
+	// 系统信息
+	System struct {
+		BootTime         uint64  `json:"bootTime"`         // 启动时间（Unix时间戳，秒）
+		UptimeSeconds    uint64  `json:"uptimeSeconds"`    // 运行时间（秒）
+		OpenFiles        uint64  `json:"openFiles"`        // 已分配的文件描述符数
+		MaxFiles         uint64  `json:"maxFiles"`         // 文件描述符上限
+		OpenFilesPercent float64 `json:"openFilesPercent"` // 文件描述符使用率（百分比）
+	} `json:"system"`
+
+	// 进程信息
+	Processes struct {
+		Total int                 `json:"total"` // 进程总数
+		Top   []ProcessStats      `json:"top"`   // CPU、内存占用最高的进程
+		Watch []ProcessWatchStats `json:"watch"` // 进程监控列表
+	} `json:"processes"`
 }
 
 // MemoryStatus 内存状态信息
@@ -41,6 +66,11 @@ type MemoryStatus struct {
 	FreeBytes      uint64  `json:"freeBytes"`      // 空闲内存(字节)
 	AvailableBytes uint64  `json:"availableBytes"` // 可用内存(字节)
 	UsagePercent   float64 `json:"usagePercent"`   // 使用率(百分比)
+
+	SwapTotalBytes   uint64  `json:"swapTotalBytes"`   // 交换分区总量(字节)
+	SwapUsedBytes    uint64  `json:"swapUsedBytes"`    // 交换分区已用(字节)
+	SwapFreeBytes    uint64  `json:"swapFreeBytes"`    // 交换分区剩余(字节)
+	SwapUsagePercent float64 `json:"swapUsagePercent"` // 交换分区使用率(百分比)
 }
 
 // DiskUsage 单个磁盘使用情况
@@ -52,6 +82,24 @@ type DiskUsage struct {
 	FreeBytes    uint64  `json:"freeBytes"`    // 剩余空间(字节)
 	UsagePercent float64 `json:"usagePercent"` // 使用率(百分比)
 	FSType       string  `json:"fsType"`       // 文件系统类型
+
+	InodesTotal        uint64  `json:"inodesTotal"`        // inode 总数
+	InodesUsed         uint64  `json:"inodesUsed"`         // 已用 inode 数
+	InodesFree         uint64  `json:"inodesFree"`         // 剩余 inode 数
+	InodesUsagePercent float64 `json:"inodesUsagePercent"` // inode 使用率(百分比)
+}
+
+// DiskIOStats 单个块设备的IO统计
+type DiskIOStats struct {
+	Name        string  `json:"name"`        // 设备名称，如 sda、nvme0n1
+	ReadBytes   uint64  `json:"readBytes"`   // 累计读取字节数
+	WriteBytes  uint64  `json:"writeBytes"`  // 累计写入字节数
+	ReadRate    float64 `json:"readRate"`    // 读取速率（字节/秒）
+	WriteRate   float64 `json:"writeRate"`   // 写入速率（字节/秒）
+	ReadIOPS    float64 `json:"readIops"`    // 每秒读次数
+	WriteIOPS   float64 `json:"writeIops"`   // 每秒写次数
+	UtilPercent float64 `json:"utilPercent"` // 设备繁忙时间占比（百分比）
+	Partition   bool    `json:"partition"`   // 是否为分区，汇总速率时只计整块磁盘
 }
 
 // InterfaceStats 网卡统计信息
@@ -63,4 +111,57 @@ type InterfaceStats struct {
 	TotalSentBytes uint64  `json:"totalSentBytes"` // 总发送字节数
 	RecvRate       float64 `json:"recvRate"`       // 接收速率（字节/秒）
 	SendRate       float64 `json:"sendRate"`       // 发送速率（字节/秒）
+	Virtual        bool    `json:"virtual"`        // 是否为虚拟网卡，汇总速率时不计入
+}
+
+// ProcessStats 单个进程的资源占用
+type ProcessStats struct {
+	PID           int32   `json:"pid"`           // 进程号
+	Name          string  `json:"name"`          // 进程名
+	Exe           string  `json:"exe"`           // 可执行文件路径，不上报命令行参数
+	Username      string  `json:"username"`      // 运行用户
+	CPUPercent    float64 `json:"cpuPercent"`    // CPU使用率（百分比，多核可超过 100）
+	RSSBytes      uint64  `json:"rssBytes"`      // 常驻内存(字节)
+	MemoryPercent float64 `json:"memoryPercent"` // 内存使用率（百分比）
+	NumFDs        int32   `json:"numFds"`        // 打开的文件描述符数
+	NumThreads    int32   `json:"numThreads"`    // 线程数
+}
+
+// ProcessWatchStats 进程监控列表中一项匹配到的所有进程的汇总
+type ProcessWatchStats struct {
+	Name       string  `json:"name"`       // 监控项名称
+	Count      int     `json:"count"`      // 运行中的进程数
+	CPUPercent float64 `json:"cpuPercent"` // CPU使用率之和（百分比）
+	RSSBytes   uint64  `json:"rssBytes"`   // 常驻内存之和(字节)
+	NumFDs     int32   `json:"numFds"`     // 文件描述符数之和
+	NumThreads int32   `json:"numThreads"` // 线程数之和
+}
+
+// TCPStates 各状态的TCP连接数（IPv4 和 IPv6 合计）
+type TCPStates struct {
+	Total       int `json:"total"`       // 连接总数
+	Established int `json:"established"` // ESTABLISHED
+	SynSent     int `json:"synSent"`     // SYN_SENT
+	SynRecv     int `json:"synRecv"`     // SYN_RECV
+	FinWait1    int `json:"finWait1"`    // FIN_WAIT1
+	FinWait2    int `json:"finWait2"`    // FIN_WAIT2
+	TimeWait    int `json:"timeWait"`    // TIME_WAIT
+	Close       int `json:"close"`       // CLOSE
+	CloseWait   int `json:"closeWait"`   // CLOSE_WAIT
+	LastAck     int `json:"lastAck"`     // LAST_ACK
+	Listen      int `json:"listen"`      // LISTEN
+	Closing     int `json:"closing"`     // CLOSING
+}
+
+// CustomMetric 检查脚本或 exporter 产生的一个自定义指标
+type CustomMetric struct {
+	Name  string            `json:"name"`           // 指标名
+	Tags  map[string]string `json:"tags,omitempty"` // 标签
+	Value float64           `json:"value"`          // 数值
+}
+
+// CustomMetrics 一次检查或抓取得到的自定义指标
+type CustomMetrics struct {
+	CollectedAt int64          `json:"collectedAt"` // 采集时间戳（Unix时间戳，秒）
+	Metrics     []CustomMetric `json:"metrics"`
 }
@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/goccy/go-json"
 )
@@ -24,6 +27,7 @@ type HostDetailInfo struct {
 	HostName              string            `json:"hostname"`
 	IP                    string            `json:"ip"`
 	PublicIPInfo          map[string]string `json:"public_ip_info"`
+	SSHHostKeys           []string          `json:"ssh_host_keys"`
 }
 
 func RunQuery(sql string) (QueryResponse, error) {
@@ -143,6 +147,26 @@ func QueryUserAuthorizeKeys() (QueryResponse, error) {
 	return info, nil
 }
 
+// QuerySSHHostKeys 读取 sshd 的主机公钥，服务端用于校验 SSH 连接
+func QuerySSHHostKeys() ([]string, error) {
+	paths, err := filepath.Glob("/etc/ssh/ssh_host_*_key.pub")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Println("读取主机密钥失败:", path, err)
+			continue
+		}
+		if key := strings.TrimSpace(string(data)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
 func QueryUserInfo() (QueryResponse, error) {
 	info, err := RunQuery("select uid, username, gid, description, directory,shell from users")
 	if err != nil {
@@ -329,6 +353,13 @@ func QueryHostDetailInfo() (HostDetailInfo, error) {
 	}
 	info.UserAuthorizeKeysInfo = userAuthorizeKeysInfo
 
+	// 主机密钥读取失败不影响其他信息上报
+	sshHostKeys, err := QuerySSHHostKeys()
+	if err != nil {
+		log.Println("查询 ssh_host_keys 失败:", err)
+	}
+	info.SSHHostKeys = sshHostKeys
+
 	// 获取公网IP信息，如果失败则使用默认值
 	publicIPInfo, err := GetPublicIPInfo()
 	if err != nil {
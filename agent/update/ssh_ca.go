@@ -0,0 +1,148 @@
+package update
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	caKeyFile       = "/etc/ssh/ccops_user_ca.pub"
+	principalsDir   = "/etc/ssh/ccops_principals"
+	sshdConfigFile  = "/etc/ssh/sshd_config"
+	sshdDropInDir   = "/etc/ssh/sshd_config.d"
+	sshdDropInFile  = sshdDropInDir + "/ccops.conf"
+	sshdBlockBegin  = "# BEGIN ccops"
+	sshdBlockEnd    = "# END ccops"
+	staticKeySuffix = "@cc.ops"
+)
+
+// sshdSettings 信任平台 CA 签发的用户证书，证书主体需出现在对应用户的 principals 文件中
+var sshdSettings = fmt.Sprintf("TrustedUserCAKeys %s\nAuthorizedPrincipalsFile %s/%%u\n", caKeyFile, principalsDir)
+
+// ConfigureTrustedCA 写入 CA 公钥和 root 允许的证书主体，并让 sshd 信任该 CA，配置有变化时重新加载 sshd
+func ConfigureTrustedCA(caPublicKey string, principals []string) error {
+	caPublicKey = strings.TrimSpace(caPublicKey)
+	if caPublicKey == "" {
+		return nil
+	}
+	if err := os.MkdirAll(principalsDir, 0755); err != nil {
+		return fmt.Errorf("创建 principals 目录失败：%s", err)
+	}
+
+	changed, err := writeIfChanged(caKeyFile, caPublicKey+"\n", 0644)
+	if err != nil {
+		return fmt.Errorf("写入 CA 公钥失败：%s", err)
+	}
+	// principals 文件在每次登录时读取，修改后无需重新加载 sshd
+	if _, err := writeIfChanged(filepath.Join(principalsDir, "root"), strings.Join(principals, "\n")+"\n", 0644); err != nil {
+		return fmt.Errorf("写入 principals 失败：%s", err)
+	}
+
+	configChanged, err := ensureSshdConfig()
+	if err != nil {
+		return err
+	}
+	if !changed && !configChanged {
+		return nil
+	}
+	return reloadSshd()
+}
+
+// ensureSshdConfig 主配置包含 sshd_config.d 时写入独立文件，否则在主配置末尾追加带标记的配置块
+func ensureSshdConfig() (bool, error) {
+	mainConfig, err := os.ReadFile(sshdConfigFile)
+	if err != nil {
+		return false, fmt.Errorf("读取 sshd 配置失败：%s", err)
+	}
+
+	includeRe := regexp.MustCompile(`(?mi)^\s*Include\s+/etc/ssh/sshd_config\.d/\*\.conf`)
+	if includeRe.Match(mainConfig) {
+		return writeIfChanged(sshdDropInFile, "# 由 ccops agent 管理\n"+sshdSettings, 0644)
+	}
+
+	content := string(mainConfig)
+	block := sshdBlockBegin + "\n" + sshdSettings + sshdBlockEnd + "\n"
+	if begin := strings.Index(content, sshdBlockBegin); begin >= 0 {
+		end := strings.Index(content[begin:], sshdBlockEnd)
+		if end < 0 {
+			return false, fmt.Errorf("sshd 配置中的 ccops 配置块不完整")
+		}
+		end = begin + end + len(sshdBlockEnd)
+		if end < len(content) && content[end] == '\n' {
+			end++
+		}
+		content = content[:begin] + block + content[end:]
+	} else {
+		// 追加在末尾前需要确保不在 Match 块中
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if regexp.MustCompile(`(?mi)^\s*Match\s`).MatchString(content) {
+			content = block + content
+		} else {
+			content += block
+		}
+	}
+	return writeIfChanged(sshdConfigFile, content, 0644)
+}
+
+// writeIfChanged 内容相同时不写入，返回是否有修改
+func writeIfChanged(path, content string, perm os.FileMode) (bool, error) {
+	old, err := os.ReadFile(path)
+	if err == nil && string(old) == content {
+		return false, nil
+	}
+	tmp := path + ".ccops.tmp"
+	if err := os.WriteFile(tmp, []byte(content), perm); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, path)
+}
+
+// reloadSshd 校验配置后重新加载 sshd，服务名在不同发行版上为 sshd 或 ssh
+func reloadSshd() error {
+	var stderr bytes.Buffer
+	test := exec.Command("sshd", "-t")
+	test.Stderr = &stderr
+	if err := test.Run(); err != nil {
+		return fmt.Errorf("sshd 配置校验失败：%s, 错误信息：%s", err, stderr.String())
+	}
+	for _, name := range []string{"sshd", "ssh"} {
+		if err := exec.Command("systemctl", "reload", name).Run(); err == nil {
+			return nil
+		}
+	}
+	if err := exec.Command("service", "ssh", "reload").Run(); err == nil {
+		return nil
+	}
+	return fmt.Errorf("重新加载 sshd 失败")
+}
+
+// RemoveRootPublicKey 关闭静态密钥后，从 authorized_keys 中删除平台公钥
+func RemoveRootPublicKey() error {
+	authKeysFile := "/root/.ssh/authorized_keys"
+	data, err := os.ReadFile(authKeysFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取 authorized_keys 失败：%s", err)
+	}
+	lines := strings.Split(string(data), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if strings.HasSuffix(strings.TrimSpace(line), staticKeySuffix) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if len(kept) == len(lines) {
+		return nil
+	}
+	return os.WriteFile(authKeysFile, []byte(strings.Join(kept, "\n")), 0600)
+}
@@ -1,5 +1,7 @@
 package clglobal
 
 var (
-	Address *string
+	Address    *string
+	ConfigPath *string // 监控配置文件路径，为空时使用默认配置
+	Token      *string // 服务端配置的 agent 认证令牌
 )
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// 日志级别
+const (
+	LevelDebug int32 = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var level atomic.Int32
+
+func init() {
+	level.Store(LevelInfo)
+}
+
+// SetLevel 设置日志级别 debug/info/warn/error，低于该级别的日志不输出
+func SetLevel(name string) error {
+	switch strings.ToLower(name) {
+	case "debug":
+		level.Store(LevelDebug)
+	case "info", "":
+		level.Store(LevelInfo)
+	case "warn":
+		level.Store(LevelWarn)
+	case "error":
+		level.Store(LevelError)
+	default:
+		return fmt.Errorf("未知的日志级别: %s", name)
+	}
+	return nil
+}
+
+func output(l int32, prefix, format string, args ...any) {
+	if l >= level.Load() {
+		log.Output(3, prefix+fmt.Sprintf(format, args...))
+	}
+}
+
+// Debugf 调试日志
+func Debugf(format string, args ...any) { output(LevelDebug, "[DEBUG] ", format, args...) }
+
+// Infof 一般日志
+func Infof(format string, args ...any) { output(LevelInfo, "[INFO] ", format, args...) }
+
+// Warnf 警告日志
+func Warnf(format string, args ...any) { output(LevelWarn, "[WARN] ", format, args...) }
+
+// Errorf 错误日志
+func Errorf(format string, args ...any) { output(LevelError, "[ERROR] ", format, args...) }
@@ -0,0 +1,33 @@
+package request
+
+import (
+	"agent/query/monitor/config"
+	"agent/web/clglobal"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// AgentConfigResponse 服务端下发的配置，Config 为 null 时使用本地配置
+type AgentConfigResponse struct {
+	Version string               `json:"version"`
+	Config  *config.ServerConfig `json:"config"`
+}
+
+// GetAgentConfig 获取服务端为本机指定的配置
+func GetAgentConfig() (AgentConfigResponse, error) {
+	var response AgentConfigResponse
+	url := fmt.Sprintf("%s/api/client/agent_config", *clglobal.Address)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return response, err
+	}
+	data, err := do(req)
+	if err != nil {
+		return response, err
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return response, fmt.Errorf("解析服务端配置失败: %w", err)
+	}
+	return response, nil
+}
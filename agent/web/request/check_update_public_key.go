@@ -8,7 +8,7 @@ import (
 
 func CheckAndUpdatePublicKey() error {
 	// 向服务端要公钥，并存储到机器的authorized_keys文件中
-	pubKey, err := GetPublicKey()
+	keys, err := GetPublicKey()
 	if err != nil {
 		log.Println("获取公钥失败：", err)
 		return err
@@ -22,11 +22,24 @@ func CheckAndUpdatePublicKey() error {
 	}
 
 	if osType == "Linux" {
-		err = update.AddRootPublicKey(pubKey)
-		if err != nil {
-			log.Println("写入公钥失败：", err)
+		// 信任平台 CA，登录使用短期证书
+		if err := update.ConfigureTrustedCA(keys.CAPublicKey, keys.Principals); err != nil {
+			log.Println("配置 SSH CA 失败：", err)
 			return err
 		}
+		if !keys.UseStaticKey() {
+			if err := update.RemoveRootPublicKey(); err != nil {
+				log.Println("删除静态公钥失败：", err)
+				return err
+			}
+			return nil
+		}
+		if keys.PublicKey != "" {
+			if err := update.AddRootPublicKey(keys.PublicKey); err != nil {
+				log.Println("写入公钥失败：", err)
+				return err
+			}
+		}
 	}
 	return nil
 }
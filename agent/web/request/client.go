@@ -0,0 +1,71 @@
+package request
+
+import (
+	"agent/web/clglobal"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpClient 请求服务端使用的客户端，服务端无响应时不会一直阻塞
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// errNotFound 服务端没有该接口，通常是旧版服务端
+var errNotFound = errors.New("服务端没有该接口")
+
+// response 服务端统一的响应格式，code 为 0 表示成功
+type response struct {
+	Code int             `json:"code"`
+	Data json.RawMessage `json:"data"`
+	Msg  string          `json:"msg"`
+}
+
+// setToken 配置了认证令牌时带上认证头
+func setToken(req *http.Request) {
+	if clglobal.Token != nil && *clglobal.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+*clglobal.Token)
+	}
+}
+
+// postJSON 以 JSON 发送数据，返回响应中的 data
+func postJSON(url string, body any) (json.RawMessage, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return do(req)
+}
+
+// do 发送请求并解析统一的响应格式，HTTP 状态码不是 200 或 code 不为 0 时返回错误
+func do(req *http.Request) (json.RawMessage, error) {
+	setToken(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("状态码: %d", resp.StatusCode)
+	}
+	var result response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("服务端返回错误（%d）: %s", result.Code, result.Msg)
+	}
+	return result.Data, nil
+}
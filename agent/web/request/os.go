@@ -4,8 +4,10 @@ import (
 	"agent/query"
 	"agent/query/monitor/models"
 	"agent/web/clglobal"
+	"agent/web/logger"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -41,28 +43,17 @@ func SendHostInfoRequest() error {
 	return nil
 }
 
-// SendMetrics 发送系统指标数据到服务端
+// SendMetrics 发送系统指标数据到服务端，服务端确认写入后才返回 nil
 func SendMetrics(metrics *models.SystemMetrics) error {
 	url := fmt.Sprintf("%s/api/client/metrics", *clglobal.Address)
-	jsonData, err := json.Marshal(metrics)
-	if err != nil {
-		return err
-	}
-
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("发送指标数据失败，状态码: %d", resp.StatusCode)
-		return fmt.Errorf("发送指标数据失败，状态码: %d", resp.StatusCode)
+	if _, err := postJSON(url, metrics); err != nil {
+		log.Printf("发送指标数据失败: %v", err)
+		return fmt.Errorf("发送指标数据失败: %w", err)
 	}
 
 	// 记录网络状态
 	for _, netStat := range metrics.Network.Interfaces {
-		log.Printf("网卡 %s 状态: 入站速率 %.2f MB/s, 出站速率 %.2f MB/s",
+		logger.Debugf("网卡 %s 状态: 入站速率 %.2f MB/s, 出站速率 %.2f MB/s",
 			netStat.Name,
 			netStat.RecvRate/1024/1024,
 			netStat.SendRate/1024/1024)
@@ -70,3 +61,28 @@ func SendMetrics(metrics *models.SystemMetrics) error {
 
 	return nil
 }
+
+// ErrBatchUnsupported 服务端没有批量上报接口
+var ErrBatchUnsupported = errors.New("服务端不支持批量上报")
+
+// SendMetricsBatch 批量补传缓存的系统指标数据，服务端确认写入后才返回 nil
+func SendMetricsBatch(points []*models.SystemMetrics) error {
+	url := fmt.Sprintf("%s/api/client/metrics/batch", *clglobal.Address)
+	_, err := postJSON(url, map[string]any{"points": points})
+	if errors.Is(err, errNotFound) {
+		return ErrBatchUnsupported
+	}
+	if err != nil {
+		return fmt.Errorf("批量发送指标数据失败: %w", err)
+	}
+	return nil
+}
+
+// SendCustomMetrics 发送检查脚本和 exporter 产生的自定义指标到服务端
+func SendCustomMetrics(metrics *models.CustomMetrics) error {
+	url := fmt.Sprintf("%s/api/client/custom_metrics", *clglobal.Address)
+	if _, err := postJSON(url, metrics); err != nil {
+		return fmt.Errorf("发送自定义指标失败: %w", err)
+	}
+	return nil
+}
@@ -11,38 +11,50 @@ import (
 
 // 定义一个结构体来表示公钥的响应格式
 type PublicKeyResponse struct {
-	PublicKey string `json:"public_key"`
+	PublicKey   string   `json:"public_key"`
+	StaticKey   *bool    `json:"static_key"` // 旧版服务端没有该字段，视为使用静态密钥
+	CAPublicKey string   `json:"ca_public_key"`
+	Principals  []string `json:"principals"`
+}
+
+// UseStaticKey 是否继续使用平台静态密钥
+func (r PublicKeyResponse) UseStaticKey() bool {
+	return r.StaticKey == nil || *r.StaticKey
 }
 
 // 发请求到全局变量里的地址获取服务端公钥
-func GetPublicKey() (string, error) {
+func GetPublicKey() (PublicKeyResponse, error) {
 
 	url := fmt.Sprintf("%s/api/client/public_key", *clglobal.Address)
-	resp, err := http.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return PublicKeyResponse{}, err
+	}
+	setToken(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", err
+		return PublicKeyResponse{}, err
 	}
 	defer resp.Body.Close()
 
 	// 检查 HTTP 状态码
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("请求失败，状态码: %d", resp.StatusCode)
+		return PublicKeyResponse{}, fmt.Errorf("请求失败，状态码: %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return PublicKeyResponse{}, err
 	}
 
 	// 解析 JSON 响应体
 	var publicKeyResponse PublicKeyResponse
 	if err := json.Unmarshal(body, &publicKeyResponse); err != nil {
-		return "", err
+		return PublicKeyResponse{}, err
 	}
 
 	// 去掉公钥中的换行符
-	cleanPublicKey := strings.ReplaceAll(publicKeyResponse.PublicKey, "\n", " ")
+	publicKeyResponse.PublicKey = strings.TrimSpace(strings.ReplaceAll(publicKeyResponse.PublicKey, "\n", " "))
 
-	// 返回公钥内容
-	return cleanPublicKey, nil
+	return publicKeyResponse, nil
 }
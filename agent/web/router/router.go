@@ -21,6 +21,9 @@ func StartGin() {
 	routerGroupApp.RegisterHealth()
 	routerGroupApp.InfoRouter()
 
+	// 先读取本地配置，各定时任务按配置中的间隔运行，服务端配置在后台获取后再生效
+	cron_ser.InitAgentConfig()
+	go cron_ser.StartAgentConfigSync()
 	go cron_ser.StartOsqueryReport()
 	go cron_ser.StartPollingPublicKey()
 	go cron_ser.StartMetricsCollection()
@@ -0,0 +1,128 @@
+package cron_ser
+
+import (
+	"agent/query/monitor/config"
+	"agent/web/clglobal"
+	"agent/web/logger"
+	"agent/web/request"
+	"sync"
+	"time"
+)
+
+// 检查服务端配置是否变化的间隔
+const agentConfigPollInterval = time.Minute
+
+// agentConfig 当前生效的配置：本地配置文件（没有时为默认配置）被服务端下发的配置覆盖后的结果
+var agentConfig = struct {
+	sync.Mutex
+	local   *config.Config
+	current *config.Config
+	version string        // 服务端配置的摘要，没有服务端配置时为空
+	changed chan struct{} // 配置变化时关闭并替换为新的
+}{}
+
+// InitAgentConfig 读取本地配置，需在启动定时任务前调用。
+// 服务端配置由 StartAgentConfigSync 在后台获取，服务端无响应时不影响启动
+func InitAgentConfig() {
+	local := config.DefaultConfig()
+	if clglobal.ConfigPath != nil && *clglobal.ConfigPath != "" {
+		loaded, err := config.LoadFile(*clglobal.ConfigPath)
+		if err != nil {
+			logger.Warnf("读取监控配置失败，使用默认配置: %v", err)
+		} else {
+			local = loaded
+		}
+	}
+	if err := logger.SetLevel(local.LogLevel); err != nil {
+		logger.Warnf("%v", err)
+	}
+
+	agentConfig.Lock()
+	agentConfig.local = local
+	agentConfig.current = local
+	agentConfig.changed = make(chan struct{})
+	agentConfig.Unlock()
+}
+
+// currentAgentConfig 当前生效的配置，以及配置下次变化时关闭的通道
+func currentAgentConfig() (*config.Config, <-chan struct{}) {
+	agentConfig.Lock()
+	defer agentConfig.Unlock()
+	return agentConfig.current, agentConfig.changed
+}
+
+// syncAgentConfig 获取服务端配置，摘要变化时覆盖本地配置并通知各定时任务
+func syncAgentConfig() error {
+	resp, err := request.GetAgentConfig()
+	if err != nil {
+		return err
+	}
+
+	agentConfig.Lock()
+	defer agentConfig.Unlock()
+	if resp.Version == agentConfig.version {
+		return nil
+	}
+	// 无效的配置同样记录摘要，配置修改前不再重复应用
+	agentConfig.version = resp.Version
+	cfg, err := agentConfig.local.WithServer(resp.Config)
+	if err != nil {
+		logger.Errorf("服务端下发的配置无效，继续使用当前配置: %v", err)
+		return nil
+	}
+	if err := logger.SetLevel(cfg.LogLevel); err != nil {
+		logger.Warnf("%v", err)
+	}
+	agentConfig.current = cfg
+	close(agentConfig.changed)
+	agentConfig.changed = make(chan struct{})
+
+	if resp.Config == nil {
+		logger.Infof("服务端未指定配置，使用本地配置")
+	} else {
+		logger.Infof("已应用服务端配置 %s，采集间隔 %v", resp.Version, cfg.CollectInterval)
+	}
+	return nil
+}
+
+// StartAgentConfigSync 立即获取一次服务端配置，之后定时检查，变化后不需要重启即可生效
+func StartAgentConfigSync() {
+	if err := syncAgentConfig(); err != nil {
+		logger.Warnf("获取服务端配置失败，使用本地配置: %v", err)
+	}
+
+	ticker := time.NewTicker(agentConfigPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := syncAgentConfig(); err != nil {
+			// 旧版服务端没有该接口，不反复输出警告
+			logger.Debugf("获取服务端配置失败: %v", err)
+		}
+	}
+}
+
+// intervalTicker 按配置中的间隔触发的定时器，配置中的间隔变化时重置
+type intervalTicker struct {
+	*time.Ticker
+	interval func(*config.Config) time.Duration
+	current  time.Duration
+	changed  <-chan struct{}
+}
+
+func newIntervalTicker(interval func(*config.Config) time.Duration) *intervalTicker {
+	cfg, changed := currentAgentConfig()
+	t := &intervalTicker{interval: interval, current: interval(cfg), changed: changed}
+	t.Ticker = time.NewTicker(t.current)
+	return t
+}
+
+// reload 配置变化后调用，间隔不同时重置定时器
+func (t *intervalTicker) reload() {
+	cfg, changed := currentAgentConfig()
+	t.changed = changed
+	if d := t.interval(cfg); d != t.current {
+		t.current = d
+		t.Reset(d)
+	}
+}
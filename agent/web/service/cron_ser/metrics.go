@@ -2,30 +2,46 @@ package cron_ser
 
 import (
 	"agent/query/monitor"
+	"agent/query/monitor/collectors"
+	"agent/query/monitor/models"
 	"agent/web/request"
 	"log"
-	"time"
 )
 
-// startMetricsCollection 启动系统指标采集定时任务
+// startMetricsCollection 启动系统指标采集定时任务，采集所有文件系统、网卡、块设备和进程，以及配置的自定义指标
 func StartMetricsCollection() {
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+	cfg, changed := currentAgentConfig()
 
-	for {
-		select {
-		case <-ticker.C:
-			metrics, err := monitor.CollectMetrics()
-			if err != nil {
-				log.Printf("采集系统指标时出错: %v", err)
-				continue
+	// 检查脚本和 exporter 按各自的间隔采集，单独上报
+	if custom := collectors.NewCustomCollector(cfg.CustomConfig, cfg.CollectInterval); custom.Enabled() {
+		customChan := make(chan *models.CustomMetrics, 16)
+		custom.Start(customChan)
+		go func() {
+			for metrics := range customChan {
+				if err := request.SendCustomMetrics(metrics); err != nil {
+					log.Printf("上传自定义指标时出错: %v", err)
+				}
 			}
+		}()
+	}
 
-			// 将采集到的指标数据上传到服务端
-			err = request.SendMetrics(metrics)
-			if err != nil {
-				log.Printf("上传系统指标时出错: %v", err)
-			}
+	metricsChan := make(chan *models.SystemMetrics, 1)
+	mon := monitor.NewMonitor(cfg)
+	go mon.Start(metricsChan)
+
+	// 服务端配置变化时更新采集项和采集间隔
+	go func() {
+		for {
+			<-changed
+			cfg, changed = currentAgentConfig()
+			mon.Apply(cfg)
 		}
+	}()
+
+	// 采集到的指标先放入缓存，由上报协程上传到服务端，服务端不可达时保留并在恢复后补传
+	spool := newMetricsSpool(spoolCapacity)
+	go spool.Run()
+	for metrics := range metricsChan {
+		spool.Push(metrics)
 	}
 }
@@ -1,6 +1,7 @@
 package cron_ser
 
 import (
+	"agent/query/monitor/config"
 	"agent/web/request"
 	"log"
 	"time"
@@ -8,13 +9,15 @@ import (
 
 // 定时任务入口函数，启动心跳检测
 func StartOsqueryReport() {
-	// 设置定时时间间隔，比如每 1 分钟执行一次
-	ticker := time.NewTicker(2 * time.Minute)
+	// 上报间隔由配置决定，默认 2 分钟
+	ticker := newIntervalTicker(func(cfg *config.Config) time.Duration { return cfg.ReportInterval })
 	defer ticker.Stop()
 
 	// 每当 ticker 触发时，执行查询和处理逻辑
 	for {
 		select {
+		case <-ticker.changed:
+			ticker.reload()
 		case <-ticker.C:
 			// 查询主机详细信息
 			err := request.SendHostInfoRequest()
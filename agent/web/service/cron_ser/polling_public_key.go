@@ -1,6 +1,7 @@
 package cron_ser
 
 import (
+	"agent/query/monitor/config"
 	"agent/web/request"
 	"log"
 	"time"
@@ -9,12 +10,15 @@ import (
 // 定时任务入口函数，公钥更新检查
 func StartPollingPublicKey() {
 
-	ticker := time.NewTicker(3 * time.Minute)
+	// 检查间隔由配置决定，默认 3 分钟
+	ticker := newIntervalTicker(func(cfg *config.Config) time.Duration { return cfg.PublicKeyInterval })
 	defer ticker.Stop()
 
 	// 每当 ticker 触发时，执行查询和处理逻辑
 	for {
 		select {
+		case <-ticker.changed:
+			ticker.reload()
 		case <-ticker.C:
 			// 查询主机详细信息
 			err := request.CheckAndUpdatePublicKey()
@@ -0,0 +1,146 @@
+package cron_ser
+
+import (
+	"agent/query/monitor/models"
+	"agent/web/logger"
+	"agent/web/request"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// 缓存的数据点上限，按 5 秒采集一次约为 1 小时，超出时丢弃最早的数据点
+	spoolCapacity = 720
+	// 一次批量上报的数据点上限，与服务端一致
+	spoolBatchSize = 500
+	// 上报失败后的重试间隔，每次失败翻倍
+	minBackoff = 5 * time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// metricsSpool 待上报的系统指标缓存。服务端不可达时数据点留在缓存中，恢复后按批补传
+type metricsSpool struct {
+	mutex    sync.Mutex
+	points   []*models.SystemMetrics
+	capacity int
+	dropped  int // 因缓存已满丢弃、尚未记录日志的数据点数
+	notify   chan struct{}
+}
+
+func newMetricsSpool(capacity int) *metricsSpool {
+	return &metricsSpool{
+		capacity: capacity,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// Push 加入一个数据点，缓存已满时丢弃最早的数据点
+func (s *metricsSpool) Push(metrics *models.SystemMetrics) {
+	s.mutex.Lock()
+	if len(s.points) >= s.capacity {
+		s.points[0] = nil
+		s.points = s.points[1:]
+		s.dropped++
+	}
+	s.points = append(s.points, metrics)
+	s.mutex.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// peek 最早的至多 n 个数据点，不从缓存中移除
+func (s *metricsSpool) peek(n int) []*models.SystemMetrics {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if n > len(s.points) {
+		n = len(s.points)
+	}
+	return append([]*models.SystemMetrics(nil), s.points[:n]...)
+}
+
+// ack 移除 last 及之前的数据点；last 在上报期间已因缓存已满被丢弃时，之前的也都已丢弃
+func (s *metricsSpool) ack(last *models.SystemMetrics) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i, point := range s.points {
+		if point == last {
+			clear(s.points[:i+1])
+			s.points = s.points[i+1:]
+			return
+		}
+	}
+}
+
+// takeDropped 返回并清零丢弃计数
+func (s *metricsSpool) takeDropped() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	dropped := s.dropped
+	s.dropped = 0
+	return dropped
+}
+
+func (s *metricsSpool) len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.points)
+}
+
+// Run 持续上报缓存中的数据点：只有一个时直接上报，有积压时按批补传；失败后按指数退避重试
+func (s *metricsSpool) Run() {
+	backoff := time.Duration(0)
+	batchSupported := true
+	for {
+		batch := s.peek(spoolBatchSize)
+		if len(batch) == 0 {
+			<-s.notify
+			continue
+		}
+
+		var err error
+		if len(batch) == 1 || !batchSupported {
+			batch = batch[:1]
+			err = request.SendMetrics(batch[0])
+		} else {
+			err = request.SendMetricsBatch(batch)
+			if errors.Is(err, request.ErrBatchUnsupported) {
+				// 服务端版本较旧，逐个补传
+				logger.Infof("服务端不支持批量上报，逐个补传缓存的数据点")
+				batchSupported = false
+				continue
+			}
+		}
+
+		if dropped := s.takeDropped(); dropped > 0 {
+			logger.Warnf("指标缓存已满，丢弃了最早的 %d 个数据点", dropped)
+		}
+		if err != nil {
+			backoff = nextBackoff(backoff)
+			logger.Warnf("上传系统指标时出错，%d 个数据点待上报，%v 后重试: %v", s.len(), backoff.Round(time.Second), err)
+			time.Sleep(backoff)
+			continue
+		}
+		if backoff > 0 {
+			logger.Infof("已恢复上传系统指标，%d 个数据点待补传", s.len()-len(batch))
+			backoff = 0
+		}
+		s.ack(batch[len(batch)-1])
+	}
+}
+
+// nextBackoff 下一次重试间隔，加入至多 20% 的随机抖动，避免大量 agent 同时重试
+func nextBackoff(current time.Duration) time.Duration {
+	next := current * 2
+	if next < minBackoff {
+		next = minBackoff
+	}
+	if next > maxBackoff {
+		next = maxBackoff
+	}
+	return next + time.Duration(rand.Int63n(int64(next)/5+1))
+}
@@ -0,0 +1,138 @@
+package agent_config_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/agent_ser"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+type AgentConfigRequest struct {
+	Name        string                 `json:"name" binding:"required"` // 配置名称
+	HostIds     []uint                 `json:"hostIds"`                 // 生效的主机
+	LabelIds    []uint                 `json:"labelIds"`                // 生效的主机标签，与主机都为空时对所有主机生效
+	Priority    int                    `json:"priority"`                // 同一层级中大的优先
+	Config      models.AgentConfigSpec `json:"config"`                  // 配置内容
+	Enabled     bool                   `json:"enabled"`                 // 是否启用
+	Description string                 `json:"description"`             // 描述
+}
+
+func (cr AgentConfigRequest) apply(config *models.AgentConfigModel) {
+	config.Name = cr.Name
+	config.HostIds = cr.HostIds
+	config.LabelIds = cr.LabelIds
+	config.Priority = cr.Priority
+	config.Config = datatypes.NewJSONType(cr.Config)
+	config.Enabled = cr.Enabled
+	config.Description = cr.Description
+}
+
+// AgentConfigListView agent 配置列表
+func (AgentConfigApi) AgentConfigListView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	var configs []models.AgentConfigModel
+	global.DB.Order("id").Find(&configs)
+	res.OkWithList(configs, int64(len(configs)), c)
+}
+
+// AgentConfigCreateView 创建 agent 配置
+func (AgentConfigApi) AgentConfigCreateView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	var cr AgentConfigRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithError(err, &cr, c)
+		return
+	}
+	if err := agent_ser.ValidateAgentConfig(cr.Config); err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	var config models.AgentConfigModel
+	cr.apply(&config)
+	if err := global.DB.Create(&config).Error; err != nil {
+		global.Log.Error(err)
+		res.FailWithMessage("创建配置失败", c)
+		return
+	}
+	res.OkWithData(config, c)
+}
+
+// AgentConfigUpdateView 更新 agent 配置，agent 下次检查配置时生效
+func (AgentConfigApi) AgentConfigUpdateView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	var cr AgentConfigRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithError(err, &cr, c)
+		return
+	}
+	if err := agent_ser.ValidateAgentConfig(cr.Config); err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	var config models.AgentConfigModel
+	if err := global.DB.Take(&config, c.Param("id")).Error; err != nil {
+		res.FailWithMessage("配置不存在", c)
+		return
+	}
+	cr.apply(&config)
+	if err := global.DB.Save(&config).Error; err != nil {
+		global.Log.Error(err)
+		res.FailWithMessage("更新配置失败", c)
+		return
+	}
+	res.OkWithMessage("更新成功", c)
+}
+
+// AgentConfigRemoveView 删除 agent 配置，agent 恢复使用本地配置
+func (AgentConfigApi) AgentConfigRemoveView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	if err := global.DB.Delete(&models.AgentConfigModel{}, c.Param("id")).Error; err != nil {
+		res.FailWithMessage("删除配置失败", c)
+		return
+	}
+	res.OkWithMessage("删除成功", c)
+}
+
+// AgentConfigHostView 对主机生效的 agent 配置，没有时 data 为 null
+func (AgentConfigApi) AgentConfigHostView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	hostID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	if !permission.IsPermission(claims.UserID, uint(hostID)) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	res.OkWithData(agent_ser.ResolveAgentConfig(uint(hostID)), c)
+}
@@ -0,0 +1,4 @@
+package agent_config_api
+
+type AgentConfigApi struct {
+}
@@ -7,6 +7,7 @@ import (
 	"ccops/models/alert"
 	"ccops/models/res"
 	alertService "ccops/service/alert"
+	"ccops/service/metrics_ser"
 
 	"time"
 
@@ -73,9 +74,20 @@ func (AlertApi) CreateAlertRule(c *gin.Context) {
 		Duration:       req.Duration,
 		Operator:       req.Operator,
 		Threshold:      req.Threshold,
+		Target:         req.Target,
 		RecoverNotify:  req.RecoverNotify,
 		NotificationId: req.NotificationId,
 	}
+	if err := alert.ValidateRule(rule); err != nil {
+		tx.Rollback()
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := normalizeTarget(rule); err != nil {
+		tx.Rollback()
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
 
 	// 保存规则
 	if err := tx.Create(rule).Error; err != nil {
@@ -273,7 +285,20 @@ func (AlertApi) UpdateAlertRule(c *gin.Context) {
 	if req.Threshold != 0 {
 		rule.Threshold = req.Threshold
 	}
+	if req.Target != "" {
+		rule.Target = req.Target
+	}
 	rule.RecoverNotify = req.RecoverNotify
+	if err := alert.ValidateRule(&rule); err != nil {
+		tx.Rollback()
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := normalizeTarget(&rule); err != nil {
+		tx.Rollback()
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
 
 	// 保存规则基本信息
 	if err := tx.Save(&rule).Error; err != nil {
@@ -480,6 +505,7 @@ func (AlertApi) GetAlertRule(c *gin.Context) {
 		Duration:       rule.Duration,
 		Operator:       rule.Operator,
 		Threshold:      rule.Threshold,
+		Target:         rule.Target,
 		RecoverNotify:  rule.RecoverNotify,
 		NotificationId: rule.NotificationId,
 		CreatedAt:      rule.CreatedAt,
@@ -638,6 +664,7 @@ func (AlertApi) GetAlertRuleList(c *gin.Context) {
 			Duration:       rule.Duration,
 			Operator:       rule.Operator,
 			Threshold:      rule.Threshold,
+			Target:         rule.Target,
 			RecoverNotify:  rule.RecoverNotify,
 			NotificationId: rule.NotificationId,
 			CreatedAt:      rule.CreatedAt,
@@ -1102,3 +1129,16 @@ func (AlertApi) GetAlertAggregation(c *gin.Context) {
 		List:  list,
 	}, c)
 }
+
+// normalizeTarget 自定义指标规则的序列名按标签排序，与推送时保存的序列名一致
+func normalizeTarget(rule *alert.AlertRule) error {
+	if rule.Type != alert.RuleTypeCustom {
+		return nil
+	}
+	key, err := metrics_ser.NormalizeSeriesKey(rule.Target)
+	if err != nil {
+		return err
+	}
+	rule.Target = key
+	return nil
+}
@@ -15,6 +15,7 @@ type CreateAlertRule struct {
 	Duration       int     `json:"duration" binding:"required"`       // 持续时间(秒)
 	Operator       string  `json:"operator" binding:"required"`       // 运算符(>, <, >=, <=, ==)
 	Threshold      float64 `json:"threshold"`                         // 阈值
+	Target         string  `json:"target"`                            // 指标对象，进程类规则为进程监控列表中的名称，自定义指标规则为序列名
 	RecoverNotify  bool    `json:"recoverNotify"`                     // 是否发送恢复通知
 	NotificationId uint64  `json:"notificationId" binding:"required"` // 通知配置ID
 
@@ -39,6 +40,7 @@ type UpdateAlertRule struct {
 	Duration       int     `json:"duration"`              // 持续时间(秒)
 	Operator       string  `json:"operator"`              // 运算符(>, <, >=, <=, ==)
 	Threshold      float64 `json:"threshold"`             // 阈值
+	Target         string  `json:"target"`                // 指标对象，进程类规则为进程监控列表中的名称，自定义指标规则为序列名
 	RecoverNotify  bool    `json:"recoverNotify"`         // 是否发送恢复通知
 	NotificationId uint64  `json:"notificationId"`        // 通知配置ID
 
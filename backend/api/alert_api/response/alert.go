@@ -16,6 +16,7 @@ type AlertRuleInfo struct {
 	Duration       int       `json:"duration"`       // 持续时间(秒)
 	Operator       string    `json:"operator"`       // 运算符(>, <, >=, <=, ==)
 	Threshold      float64   `json:"threshold"`      // 阈值
+	Target         string    `json:"target"`         // 指标对象
 	RecoverNotify  bool      `json:"recoverNotify"`  // 是否发送恢复通知
 	NotificationId uint64    `json:"notificationId"` // 通知配置ID
 	CreatedAt      time.Time `json:"createdAt"`      // 创建时间
@@ -0,0 +1,30 @@
+package client_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/agent_ser"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AgentConfigResponse 下发给 agent 的配置，Config 为 null 时 agent 使用本地配置
+type AgentConfigResponse struct {
+	Version string                  `json:"version"` // 配置内容的摘要，变化时 agent 重新应用配置
+	Config  *models.AgentConfigSpec `json:"config"`
+}
+
+// GetAgentConfig agent 获取对本机生效的配置，按来源 IP 查找主机
+func (ClientApi) GetAgentConfig(c *gin.Context) {
+	var response AgentConfigResponse
+	var host models.HostModel
+	if err := global.DB.Select("id").Take(&host, "host_server_url = ?", c.ClientIP()).Error; err == nil {
+		if config := agent_ser.ResolveAgentConfig(host.ID); config != nil {
+			spec := config.Config.Data()
+			response.Version = agent_ser.ConfigVersion(config)
+			response.Config = &spec
+		}
+	}
+	res.OkWithData(response, c)
+}
@@ -0,0 +1,19 @@
+package client_api
+
+import (
+	"ccops/global"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// agentAuthenticated agent 是否携带了正确的认证令牌，未配置令牌时视为未认证
+func agentAuthenticated(c *gin.Context) bool {
+	token := global.Config.System.AgentToken
+	if token == "" {
+		return false
+	}
+	given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
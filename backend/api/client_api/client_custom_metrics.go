@@ -0,0 +1,47 @@
+package client_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/metrics_ser"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomMetricsRequest 客户端推送的自定义指标，来自 agent 的检查脚本和 exporter，也可以由主机上的程序直接推送
+type CustomMetricsRequest struct {
+	CollectedAt int64                      `json:"collectedAt"` // 采集时间戳（秒），为 0 时使用当前时间
+	Metrics     []metrics_ser.CustomMetric `json:"metrics" binding:"required"`
+}
+
+// ClientCustomMetricsReceive 接收客户端推送的自定义指标，按来源 IP 查找主机
+func (ClientApi) ClientCustomMetricsReceive(c *gin.Context) {
+	var cr CustomMetricsRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithMessage("参数错误", c)
+		return
+	}
+
+	var hostID uint64
+	global.DB.Model(&models.HostModel{}).Where("host_server_url = ?", c.ClientIP()).Select("id").First(&hostID)
+	if hostID == 0 {
+		res.FailWithMessage("主机未注册", c)
+		return
+	}
+
+	if cr.CollectedAt <= 0 {
+		cr.CollectedAt = time.Now().Unix()
+	}
+	if inFuture(cr.CollectedAt) {
+		res.FailWithMessage("采集时间晚于服务端时间，请检查主机时钟", c)
+		return
+	}
+	result, err := metrics_ser.PushCustomMetrics(hostID, cr.CollectedAt, cr.Metrics)
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	res.Ok(result, "自定义指标接收成功", c)
+}
@@ -3,23 +3,39 @@ package client_api
 import (
 	"ccops/global"
 	"ccops/models"
+	"ccops/service/ssh_ser"
+	"ccops/utils/permission"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
 
 // 定义一个结构体来表示公钥的响应格式
 type PublicKeyResponse struct {
-	PublicKey string `json:"public_key"`
+	PublicKey   string   `json:"public_key"`    // 平台静态公钥，关闭静态密钥后为空
+	StaticKey   bool     `json:"static_key"`    // 为 false 时 agent 从 authorized_keys 中移除静态公钥
+	CAPublicKey string   `json:"ca_public_key"` // 签发用户证书的 CA 公钥，写入 TrustedUserCAKeys
+	Principals  []string `json:"principals"`    // root 允许登录的证书主体，写入 AuthorizedPrincipalsFile
 }
 
 func (ClientApi) GetPublicKey(c *gin.Context) {
 
-	var PublicKey string
-	global.DB.Model(&models.Configuration{}).Where("field_name = ?", "PublicKey").Select("field_value").First(&PublicKey)
-
-	// 创建响应体
 	response := PublicKeyResponse{
-		PublicKey: PublicKey,
+		StaticKey:   !global.Config.SSHCA.Enabled || global.Config.SSHCA.StaticKey,
+		CAPublicKey: ssh_ser.CAPublicKey(),
+	}
+	if response.StaticKey {
+		global.DB.Model(&models.Configuration{}).Where("field_name = ?", "PublicKey").Select("field_value").First(&response.PublicKey)
+	}
+
+	// 证书主体：任务证书，以及有该主机终端权限的用户；用户主体只下发给携带认证令牌的 agent
+	if response.CAPublicKey != "" {
+		response.Principals = []string{ssh_ser.TaskPrincipal}
+		var host models.HostModel
+		if agentAuthenticated(c) && global.DB.Take(&host, "host_server_url = ?", c.ClientIP()).Error == nil {
+			for _, userId := range permission.TerminalUserIds(host.ID) {
+				response.Principals = append(response.Principals, ssh_ser.UserPrincipal(userId))
+			}
+		}
 	}
 
 	// 返回 JSON 响应
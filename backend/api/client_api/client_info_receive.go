@@ -4,6 +4,7 @@ import (
 	"ccops/global"
 	"ccops/models"
 	"ccops/models/res"
+	"ccops/service/ssh_ser"
 	"fmt"
 	"strconv"
 	"time"
@@ -24,6 +25,7 @@ type HostDetailInfo struct {
 	HostName              string            `json:"hostname"`
 	IP                    string            `json:"ip"`
 	PublicIPInfo          map[string]string `json:"public_ip_info"`
+	SSHHostKeys           []string          `json:"ssh_host_keys"` // /etc/ssh/ssh_host_*_key.pub 的内容
 }
 
 // 接收客户端上报来的机器信息
@@ -113,6 +115,11 @@ func (ClientApi) ClientInfoReceive(c *gin.Context) {
 		return
 	}
 
+	// 记录或校验主机密钥，未认证的上报不会被直接信任
+	if len(cr.SSHHostKeys) > 0 {
+		ssh_ser.ReportHostKeys(hostModel, cr.SSHHostKeys, agentAuthenticated(c))
+	}
+
 	// 处理磁盘信息
 	diskModel := models.DiskModel{
 		HostID:                    hostModel.ID, // 关联主机的 ID
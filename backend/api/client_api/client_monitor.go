@@ -9,6 +9,8 @@ import (
 	"ccops/service/alert"
 	"fmt"
 	"io/ioutil"
+	"sort"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,6 +33,10 @@ func (ClientApi) ClientMetricsReceive(c *gin.Context) {
 		res.FailWithMessage("参数错误", c)
 		return
 	}
+	if inFuture(metrics.CollectedAt) {
+		res.FailWithMessage("采集时间晚于服务端时间，请检查主机时钟", c)
+		return
+	}
 
 	// 查找主机ID
 	global.DB.Model(&models.HostModel{}).Where("host_server_url = ?", ip).Select("id").First(&metrics.HostID)
@@ -50,8 +56,71 @@ func (ClientApi) ClientMetricsReceive(c *gin.Context) {
 		fmt.Printf("\n[时序数据库] 最新数据点时间戳: %d\n", latestData.CollectedAt)
 	}
 
-	// 获取该主机的所有数据并打印统计信息
-	global.TimeSeriesDB.GetAllData(metrics.HostID)
-
 	res.OkWithMessage("指标数据接收成功", c)
 }
+
+// 一次批量上报的数据点数上限
+const maxBatchPoints = 500
+
+// 批量上报中只有最近这段时间内的数据点参与告警检查，补传的历史数据不触发告警
+const batchAlertWindow = 2 * time.Minute
+
+// 采集时间允许晚于服务端时间的范围，容忍主机与服务端的时钟偏差
+const maxFutureSkew = 5 * time.Minute
+
+// inFuture 采集时间是否晚于服务端当前时间加上容忍范围，这样的数据点会一直作为最新数据，不写入
+func inFuture(collectedAt int64) bool {
+	return collectedAt > time.Now().Add(maxFutureSkew).Unix()
+}
+
+// MetricsBatchRequest 客户端补传的指标数据，agent 与服务端断开期间缓存的数据点
+type MetricsBatchRequest struct {
+	Points []*monitor.MetricPoint `json:"points" binding:"required"`
+}
+
+// ClientMetricsBatchReceive 接收客户端批量上报的系统指标，数据点可以乱序，按时间顺序写入时序数据库；
+// 采集时间晚于服务端时间的数据点被丢弃，不影响其余数据点写入
+func (ClientApi) ClientMetricsBatchReceive(c *gin.Context) {
+	var cr MetricsBatchRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithMessage("参数错误", c)
+		return
+	}
+	if len(cr.Points) > maxBatchPoints {
+		res.FailWithMessage(fmt.Sprintf("一次最多上报 %d 个数据点", maxBatchPoints), c)
+		return
+	}
+
+	// 查找主机ID
+	var hostID uint64
+	global.DB.Model(&models.HostModel{}).Where("host_server_url = ?", c.ClientIP()).Select("id").First(&hostID)
+	if hostID == 0 {
+		res.FailWithMessage("主机未注册", c)
+		return
+	}
+
+	points := make([]*monitor.MetricPoint, 0, len(cr.Points))
+	for _, point := range cr.Points {
+		if point != nil && point.CollectedAt > 0 && !inFuture(point.CollectedAt) {
+			point.HostID = hostID
+			point.Partial = false
+			points = append(points, point)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].CollectedAt < points[j].CollectedAt })
+	for _, point := range points {
+		global.TimeSeriesDB.Insert(point)
+	}
+
+	// 只用最新且足够新的数据点检查告警规则
+	if len(points) > 0 {
+		newest := points[len(points)-1]
+		if newest.CollectedAt >= time.Now().Add(-batchAlertWindow).Unix() {
+			if err := alert.GetAlertService().CheckMetrics(newest); err != nil {
+				fmt.Printf("告警检查失败: %v\n", err)
+			}
+		}
+	}
+
+	res.Ok(gin.H{"accepted": len(points), "dropped": len(cr.Points) - len(points)}, "指标数据接收成功", c)
+}
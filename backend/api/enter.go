@@ -1,17 +1,25 @@
 package api
 
 import (
+	"ccops/api/agent_config_api"
 	"ccops/api/alert_api"
 	"ccops/api/auth_api"
 	"ccops/api/client_api"
 	"ccops/api/configuration_api"
 	"ccops/api/file_api"
 	"ccops/api/hosts_api"
+	"ccops/api/inventory_api"
+	"ccops/api/jump_host_api"
 	"ccops/api/labels_api"
+	"ccops/api/metrics_api"
 	"ccops/api/notification_api"
+	"ccops/api/prometheus_api"
 	"ccops/api/role_api"
 	"ccops/api/role_revision_api"
+	"ccops/api/sftp_api"
 	"ccops/api/task_api"
+	"ccops/api/terminal_api"
+	"ccops/api/tunnel_api"
 	"ccops/api/user_api"
 )
 
@@ -28,6 +36,14 @@ type ApiGroup struct {
 	LabelApi         labels_api.LabelApi
 	AlertApi         alert_api.AlertApi
 	NotificationApi  notification_api.NotificationApi
+	JumpHostApi      jump_host_api.JumpHostApi
+	InventoryApi     inventory_api.InventoryApi
+	TerminalApi      terminal_api.TerminalApi
+	SftpApi          sftp_api.SftpApi
+	TunnelApi        tunnel_api.TunnelApi
+	MetricsApi       metrics_api.MetricsApi
+	PrometheusApi    prometheus_api.PrometheusApi
+	AgentConfigApi   agent_config_api.AgentConfigApi
 }
 
 var ApiGroupApp = new(ApiGroup)
@@ -0,0 +1,64 @@
+package hosts_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/ssh_ser"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HostKeyListView 主机的 SSH 主机密钥，包括已信任和待确认的
+func (HostsApi) HostKeyListView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		res.FailWithMessage("主机ID错误", c)
+		return
+	}
+	if !permission.IsPermission(claims.UserID, uint(id)) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+
+	var keys []models.HostKeyModel
+	global.DB.Where("host_id = ?", id).Order("status DESC, id").Find(&keys)
+	res.OkWithList(keys, int64(len(keys)), c)
+}
+
+type HostKeyAcceptRequest struct {
+	Fingerprint string `json:"fingerprint" binding:"required"` // 待确认密钥的 SHA256 指纹
+}
+
+// HostKeyAcceptView 管理员核实后接受主机密钥变化
+func (HostsApi) HostKeyAcceptView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		res.FailWithMessage("主机ID错误", c)
+		return
+	}
+	var cr HostKeyAcceptRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithError(err, &cr, c)
+		return
+	}
+	if err := ssh_ser.AcceptHostKey(uint(id), 0, cr.Fingerprint); err != nil {
+		res.FailWithMessage("接受主机密钥失败: "+err.Error(), c)
+		return
+	}
+	global.Log.Infof("用户 %s 接受了主机 %d 的主机密钥 %s", claims.Username, id, cr.Fingerprint)
+	res.OkWithMessage("已接受新的主机密钥", c)
+}
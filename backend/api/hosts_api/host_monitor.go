@@ -3,6 +3,8 @@ package hosts_api
 import (
 	"ccops/global"
 	"ccops/models/res"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,8 +18,23 @@ import (
 // @Success 200 {object} map[uint64]*monitor.MetricPoint
 // @Router /api/hosts/monitor/latest [get]
 func (HostsApi) GetLatestMonitorData(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
 	// 获取所有主机的最新数据点
 	latest := global.TimeSeriesDB.GetAllLatest()
+	// 普通用户只返回有权限的主机
+	if !permission.IsAdmin(claims.UserID) {
+		permitted := map[uint64]bool{}
+		for _, id := range permission.GetUserPermissionHostIds(claims.UserID) {
+			permitted[uint64(id)] = true
+		}
+		for hostID := range latest {
+			if !permitted[hostID] {
+				delete(latest, hostID)
+			}
+		}
+	}
 	if latest == nil || len(latest) == 0 {
 		res.FailWithMessage("暂无监控数据", c)
 		return
@@ -66,6 +66,11 @@ func (HostsApi) HostRemoveView(c *gin.Context) {
 		res.FailWithMessage("删除失败", c)
 		return
 	}
+	if err := tx.Where("host_id IN ?", cr.HostIds).Delete(&models.HostKeyModel{}).Error; err != nil {
+		tx.Rollback()
+		res.FailWithMessage("删除失败", c)
+		return
+	}
 	if err := tx.Delete(&hostModel).Error; err != nil {
 		tx.Rollback()
 		res.FailWithMessage("删除失败", c)
@@ -3,302 +3,107 @@ package hosts_api
 import (
 	"ccops/global"
 	"ccops/models"
-
-	"fmt"
-	"io/ioutil"
+	"ccops/models/res"
+	"ccops/service/terminal_ser"
+	"ccops/utils"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
 	"log"
-	"net/http"
-	"path/filepath"
 	"strconv"
-	"strings"
-	"sync"
-	"time"
-	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
-	"golang.org/x/crypto/ssh"
 )
 
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
-type wsWriter struct {
-	conn *websocket.Conn
-	mu   sync.Mutex
-}
-
-func (w *wsWriter) writeMessage(messageType int, data []byte) error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	return w.conn.WriteMessage(messageType, data)
-}
-
-var (
-	// 正常关闭的错误
-	normalCloseCodes = map[int]bool{
-		websocket.CloseNormalClosure:    true, // 1000
-		websocket.CloseGoingAway:        true, // 1001
-		websocket.CloseNoStatusReceived: true, // 1005
-	}
-)
-
-// 添加一个辅助函数来判断是否是正常关闭
-func isNormalClose(err error) bool {
-	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
-		return true
-	}
-	return false
-}
-
-// 添加一个新的辅助函数来验证和清理 UTF-8 数据
-func sanitizeOutput(data []byte) []byte {
-	// 如果数据是有效的 UTF-8，直接返回
-	if utf8.Valid(data) {
-		return data
-	}
-
-	// 将无效的 UTF-8 字符替换为替换字符 (U+FFFD)
-	return []byte(strings.Map(func(r rune) rune {
-		if r == utf8.RuneError {
-			return '\uFFFD' // 使用 Unicode 替换字符
-		}
-		return r
-	}, string(data)))
+	CheckOrigin: utils.CheckOrigin,
 }
 
+// HandleWebSocket 主机 Web 终端
+//
+//	?cols=&rows=     初始终端大小
+//	?protocol=json   使用 JSON 控制协议，否则使用原始文本协议
+//	?sessionId=      挂载到已有会话：自己的会话用于断线重连，他人的会话用于观察
+//	?mode=read_write 协同操作他人的会话，默认只读
 func (HostsApi) HandleWebSocket(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
 
-	//_claims, _ := c.Get("claims")
-	//claims := _claims.(*jwts.CustomClaims)
-	hostID := c.Param("id")
-
-	//id, _ := strconv.ParseUint(hostID, 10, 64)
-	//if !permission.IsPermission(claims.UserID, uint(id)) {
-	//	res.FailWithMessage("权限错误", c)
-	//	return
-	//}
-	var host models.HostModel
-	global.DB.Model(&models.HostModel{}).First(&host, hostID)
-
-	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		log.Printf("升级到 WebSocket 协议失败: %v", err)
+		res.FailWithMessage("主机ID错误", c)
 		return
 	}
-	defer ws.Close()
-
-	keyPath := "./.ssh/ccops"
-	key, err := ioutil.ReadFile(keyPath)
-	if err != nil {
-		log.Printf("读取私钥失败: %v", err)
-		absPath, _ := filepath.Abs(keyPath)
-		log.Printf("尝试读取的私钥路径: %s", absPath)
-		ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("读取私钥失败: %v", err)))
-		return
-	}
-
-	//将私钥文件的内容转换成可用于 SSH 认证的格式。
-	signer, err := ssh.ParsePrivateKey(key)
-	if err != nil {
-		ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("解析私钥失败: %v", err)))
+	// 终端权限独立于查看权限
+	if !permission.IsTerminalPermission(claims.UserID, uint(id)) {
+		res.FailWithMessage("权限错误", c)
 		return
 	}
-
-	config := &ssh.ClientConfig{
-		User: "root",
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         10 * time.Second,
-	}
-
-	address := fmt.Sprintf("%s:22", host.HostServerUrl)
-
-	client, err := ssh.Dial("tcp", address, config)
-	if err != nil {
-		ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("SSH 连接失败: %v", err)))
-		return
-	}
-	defer client.Close()
-
-	session, err := client.NewSession()
-	if err != nil {
-		ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("创建 SSH 会话失败: %v", err)))
-		return
-	}
-	defer session.Close()
-
-	// 使用更大的默认终端尺寸并设置合适的终端模式
-	if err := session.RequestPty("xterm", 40, 120, ssh.TerminalModes{
-		ssh.ECHO:          1,     // 启用回显
-		ssh.TTY_OP_ISPEED: 14400, // 输入速度
-		ssh.TTY_OP_OSPEED: 14400, // 输出速度
-		ssh.ICANON:        0,     // 禁用规范模式，实现实时输入
-	}); err != nil {
-		ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("请求 PTY 失败: %v", err)))
-		return
-	}
-
-	stdin, err := session.StdinPipe()
-	if err != nil {
-		ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("创建 stdin 管道失败: %v", err)))
-		return
-	}
-
-	stdout, err := session.StdoutPipe()
-	if err != nil {
-		ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("创建 stdout 管道失败: %v", err)))
-		return
-	}
-
-	stderr, err := session.StderrPipe()
-	if err != nil {
-		ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("创建 stderr 管道失败: %v", err)))
-		return
-	}
-
-	if err := session.Shell(); err != nil {
-		ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("启动 shell 失败: %v", err)))
+	var host models.HostModel
+	if err := global.DB.Model(&models.HostModel{}).First(&host, id).Error; err != nil {
+		res.FailWithMessage("主机不存在", c)
 		return
 	}
-
-	// 设置空闲超时
-	// const idleTimeout = 10 * time.Minute
-	// idleTimer := time.NewTimer(idleTimeout)
-	// defer idleTimer.Stop()
-
-	// 更新最后活动时间的函数（只在真实操作时调用）
-	updateLastActivity := func() {
-		// if !idleTimer.Stop() {
-		//     select {
-		//     case <-idleTimer.C:
-		//     default:
-		//     }
-		// }
-		// idleTimer.Reset(idleTimeout)
+	clientOpts := terminal_ser.ClientOptions{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		ClientIP: c.ClientIP(),
+		JSON:     c.Query("protocol") == "json",
+		Mode:     models.TerminalModeOwner,
 	}
 
-	// 在创建 WebSocket 连接后，初始化 wsWriter
-	writer := &wsWriter{conn: ws}
-
-	// 添加一个 done channel 用于协调关闭
-	done := make(chan struct{})
-	defer close(done)
-
-	// 修改输出处理的 goroutine
-	go func() {
-		buf := make([]byte, 4096)
-		for {
-			select {
-			case <-done:
-				return
-			default:
-				n, err := stdout.Read(buf)
-				if err != nil {
-					return
-				}
-				if n > 0 {
-					updateLastActivity()
-					// 在发送之前清理输出
-					cleanData := sanitizeOutput(buf[:n])
-					if err := writer.writeMessage(websocket.TextMessage, cleanData); err != nil {
-						if !isNormalClose(err) {
-							log.Printf("写入stdout失败: %v", err)
-						}
-						return
-					}
-				}
-			}
+	// 挂载到已有会话：创建者断线重连，或其他用户观察、协同操作
+	if sessionID := c.Query("sessionId"); sessionID != "" {
+		session := terminal_ser.GetSession(sessionID)
+		if session == nil || session.Host.ID != host.ID {
+			res.FailWithMessage("会话不存在或已结束", c)
+			return
 		}
-	}()
-
-	go func() {
-		buf := make([]byte, 4096)
-		for {
-			select {
-			case <-done:
-				return
-			default:
-				n, err := stderr.Read(buf)
-				if err != nil {
+		if session.UserID != claims.UserID {
+			// 默认只读，协同操作沿用创建者的命令规则，只允许管理员
+			clientOpts.Mode = models.TerminalModeReadOnly
+			if c.Query("mode") == models.TerminalModeReadWrite {
+				if !permission.IsAdmin(claims.UserID) {
+					res.FailWithMessage("只有管理员可以协同操作他人的会话", c)
 					return
 				}
-				if n > 0 {
-					updateLastActivity()
-					// 在发送之前清理输出
-					cleanData := sanitizeOutput(buf[:n])
-					if err := writer.writeMessage(websocket.TextMessage, cleanData); err != nil {
-						if !isNormalClose(err) {
-							log.Printf("写入stderr失败: %v", err)
-						}
-						return
-					}
-				}
+				clientOpts.Mode = models.TerminalModeReadWrite
 			}
 		}
-	}()
-
-	// 修改空闲超时监听的 goroutine
-	//go func() {
-	//	for {
-	//		select {
-	//		case <-done:
-	//			return
-	//		case <-idleTimer.C:
-	//			writer.writeMessage(websocket.TextMessage, []byte("\r\n\033[33m会话已超时（10分钟无操作），连接已断开。\033[0m\r\n"))
-	//			writer.writeMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "idle timeout"))
-	//			ws.Close()
-	//			session.Close()
-	//			return
-	//		}
-	//	}
-	//}()
-	fmt.Println(1)
-	// 修改主循环的错误处理
-	for {
-		_, message, err := ws.ReadMessage()
+		ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
-			if !isNormalClose(err) {
-				log.Printf("读取 WebSocket 消息失败: %v", err)
-			}
+			log.Printf("升级到 WebSocket 协议失败: %v", err)
 			return
 		}
+		session.Serve(ws, clientOpts)
+		return
+	}
 
-		// 处理心跳包
-		if len(message) == 1 && message[0] == 0 {
-			err = writer.writeMessage(websocket.TextMessage, []byte{0})
-			if err != nil {
-				log.Printf("发送心跳响应失败: %v", err)
-				return
-			}
-			continue
-		}
-
-		// 处理终端大小调整命令
-		if len(message) > 3 && message[0] == 0x1b && message[1] == '[' && message[2] == '8' {
-			dims := strings.Split(string(message[4:]), ",")
-			if len(dims) == 2 {
-				rows, _ := strconv.Atoi(dims[0])
-				cols, _ := strconv.Atoi(dims[1])
-				session.WindowChange(rows, cols)
-				updateLastActivity() // 调整终端大小算作活动
-				continue
-			}
-		}
-
-		// 更新活动时间（真实输入）
-		updateLastActivity()
+	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("升级到 WebSocket 协议失败: %v", err)
+		return
+	}
 
-		// 直接写入消息
-		_, err = stdin.Write(message)
-		if err != nil {
-			log.Printf("写入命令失败: %v", err)
-			return
+	cols, _ := strconv.Atoi(c.Query("cols"))
+	rows, _ := strconv.Atoi(c.Query("rows"))
+	session, err := terminal_ser.NewSession(terminal_ser.SessionOptions{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		Role:     claims.Role,
+		Host:     host,
+		ClientIP: c.ClientIP(),
+		Cols:     cols,
+		Rows:     rows,
+	})
+	if err != nil {
+		if clientOpts.JSON {
+			ws.WriteJSON(terminal_ser.ControlMessage{Type: "exit", Reason: err.Error()})
+		} else {
+			ws.WriteMessage(websocket.TextMessage, []byte(err.Error()))
 		}
+		ws.Close()
+		return
 	}
+	session.Serve(ws, clientOpts)
 }
@@ -0,0 +1,4 @@
+package inventory_api
+
+type InventoryApi struct {
+}
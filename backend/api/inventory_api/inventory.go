@@ -0,0 +1,62 @@
+package inventory_api
+
+import (
+	"ccops/global"
+	"ccops/models/res"
+	"ccops/service/inventory_ser"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InventoryRequest struct {
+	Host   string `form:"host"`   // 对应 --host，只返回该主机的变量
+	Format string `form:"format"` // 默认 json；ini 导出静态 inventory 文件
+}
+
+// InventoryView ansible 动态 inventory，直接返回 ansible 需要的 JSON 结构，不经过 res 包装。
+// 普通用户只能看到有权限的主机
+func (InventoryApi) InventoryView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	var cr InventoryRequest
+	if err := c.ShouldBindQuery(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+
+	var hostIds []uint
+	if !permission.IsAdmin(claims.UserID) {
+		hostIds = permission.GetUserPermissionHostIds(claims.UserID)
+		if hostIds == nil {
+			hostIds = []uint{}
+		}
+	}
+
+	if cr.Host != "" {
+		hostvars, err := inventory_ser.Host(hostIds, cr.Host)
+		if err != nil {
+			global.Log.Error(err)
+			res.FailWithMessage("生成主机变量失败", c)
+			return
+		}
+		c.JSON(http.StatusOK, hostvars)
+		return
+	}
+
+	inventory, err := inventory_ser.Build(hostIds)
+	if err != nil {
+		global.Log.Error(err)
+		res.FailWithMessage("生成 inventory 失败", c)
+		return
+	}
+	if cr.Format == "ini" {
+		c.Header("Content-Disposition", "attachment; filename=inventory.ini")
+		c.String(http.StatusOK, inventory_ser.INI(inventory))
+		return
+	}
+	c.JSON(http.StatusOK, inventory)
+}
@@ -0,0 +1,4 @@
+package jump_host_api
+
+type JumpHostApi struct {
+}
@@ -0,0 +1,71 @@
+package jump_host_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JumpHostAssignRequest 挂载跳板机，JumpHostID 为 0 表示取消挂载
+type JumpHostAssignRequest struct {
+	JumpHostID uint   `json:"jumpHostId"`
+	HostIds    []uint `json:"hostIds"`
+	LabelIds   []uint `json:"labelIds"`
+}
+
+// JumpHostAssignView 将跳板机挂载到主机或标签上，主机上的配置优先于标签
+func (JumpHostApi) JumpHostAssignView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	var cr JumpHostAssignRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	if len(cr.HostIds) == 0 && len(cr.LabelIds) == 0 {
+		res.FailWithMessage("请选择主机或标签", c)
+		return
+	}
+
+	if cr.JumpHostID != 0 {
+		var count int64
+		global.DB.Model(&models.JumpHostModel{}).Where("id = ?", cr.JumpHostID).Count(&count)
+		if count == 0 {
+			res.FailWithMessage("跳板机不存在", c)
+			return
+		}
+	}
+
+	// 开始事务
+	tx := global.DB.Begin()
+
+	if len(cr.HostIds) > 0 {
+		if err := tx.Model(&models.HostModel{}).Where("id IN ?", cr.HostIds).Update("jump_host_id", cr.JumpHostID).Error; err != nil {
+			tx.Rollback()
+			res.FailWithMessage("主机挂载跳板机失败", c)
+			return
+		}
+	}
+	if len(cr.LabelIds) > 0 {
+		if err := tx.Model(&models.LabelModel{}).Where("id IN ?", cr.LabelIds).Update("jump_host_id", cr.JumpHostID).Error; err != nil {
+			tx.Rollback()
+			res.FailWithMessage("标签挂载跳板机失败", c)
+			return
+		}
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		res.FailWithMessage("提交事务失败", c)
+		return
+	}
+	res.OkWithMessage("挂载成功", c)
+}
@@ -0,0 +1,70 @@
+package jump_host_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/ssh_ser"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+)
+
+type JumpHostRequest struct {
+	Name        string  `json:"name" binding:"required"`    // 跳板机名称
+	Address     string  `json:"address" binding:"required"` // 跳板机地址
+	Port        int     `json:"port"`                       // SSH端口，默认22
+	User        string  `json:"user"`                       // 登录用户，默认root
+	PrivateKey  *string `json:"privateKey"`                 // 私钥，不传则保持不变，传空字符串表示使用平台密钥
+	Description string  `json:"description"`                // 描述
+}
+
+// JumpHostCreateView 创建跳板机
+func (JumpHostApi) JumpHostCreateView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	var cr JumpHostRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithError(err, &cr, c)
+		return
+	}
+
+	jumpHost := models.JumpHostModel{
+		Name:        cr.Name,
+		Address:     cr.Address,
+		Port:        cr.Port,
+		User:        cr.User,
+		Description: cr.Description,
+	}
+	if err := ssh_ser.ValidateJumpHost(cr.Address, cr.User); err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	if cr.PrivateKey != nil && *cr.PrivateKey != "" {
+		if _, err := ssh.ParsePrivateKey([]byte(*cr.PrivateKey)); err != nil {
+			res.FailWithMessage("私钥格式错误", c)
+			return
+		}
+		jumpHost.PrivateKey = *cr.PrivateKey
+	}
+	if jumpHost.Port == 0 {
+		jumpHost.Port = 22
+	}
+	if jumpHost.User == "" {
+		jumpHost.User = "root"
+	}
+
+	if err := global.DB.Create(&jumpHost).Error; err != nil {
+		global.Log.Error(err)
+		res.FailWithMessage("创建跳板机失败", c)
+		return
+	}
+	jumpHost.HasKey = jumpHost.PrivateKey != ""
+	res.OkWithData(jumpHost, c)
+}
@@ -0,0 +1,36 @@
+package jump_host_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JumpHostListResponse struct {
+	models.JumpHostModel
+	HostIds  []uint `json:"hostIds"`  // 直接挂载的主机
+	LabelIds []uint `json:"labelIds"` // 挂载的标签
+}
+
+// JumpHostListView 跳板机列表，附带挂载的主机和标签
+func (JumpHostApi) JumpHostListView(c *gin.Context) {
+	var jumpHosts []models.JumpHostModel
+	global.DB.Order("created_at DESC").Find(&jumpHosts)
+
+	list := make([]JumpHostListResponse, 0, len(jumpHosts))
+	for _, jumpHost := range jumpHosts {
+		jumpHost.HasKey = jumpHost.PrivateKey != ""
+		item := JumpHostListResponse{
+			JumpHostModel: jumpHost,
+			HostIds:       []uint{},
+			LabelIds:      []uint{},
+		}
+		global.DB.Model(&models.HostModel{}).Where("jump_host_id = ?", jumpHost.ID).Pluck("id", &item.HostIds)
+		global.DB.Model(&models.LabelModel{}).Where("jump_host_id = ?", jumpHost.ID).Pluck("id", &item.LabelIds)
+		list = append(list, item)
+	}
+
+	res.OkWithList(list, int64(len(list)), c)
+}
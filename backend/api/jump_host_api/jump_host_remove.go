@@ -0,0 +1,53 @@
+package jump_host_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JumpHostRemoveView 删除跳板机，同时解除主机和标签上的挂载
+func (JumpHostApi) JumpHostRemoveView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	id := c.Param("id")
+
+	// 开始事务
+	tx := global.DB.Begin()
+
+	if err := tx.Model(&models.HostModel{}).Where("jump_host_id = ?", id).Update("jump_host_id", 0).Error; err != nil {
+		tx.Rollback()
+		res.FailWithMessage("解除主机跳板机失败", c)
+		return
+	}
+	if err := tx.Model(&models.LabelModel{}).Where("jump_host_id = ?", id).Update("jump_host_id", 0).Error; err != nil {
+		tx.Rollback()
+		res.FailWithMessage("解除标签跳板机失败", c)
+		return
+	}
+	if err := tx.Where("jump_host_id = ?", id).Delete(&models.HostKeyModel{}).Error; err != nil {
+		tx.Rollback()
+		res.FailWithMessage("删除跳板机密钥失败", c)
+		return
+	}
+	if err := tx.Delete(&models.JumpHostModel{}, id).Error; err != nil {
+		tx.Rollback()
+		res.FailWithMessage("删除跳板机失败", c)
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		res.FailWithMessage("提交事务失败", c)
+		return
+	}
+	res.OkWithMessage("删除成功", c)
+}
@@ -0,0 +1,65 @@
+package jump_host_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/ssh_ser"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+)
+
+// JumpHostUpdateView 更新跳板机
+func (JumpHostApi) JumpHostUpdateView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	var cr JumpHostRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithError(err, &cr, c)
+		return
+	}
+
+	if err := ssh_ser.ValidateJumpHost(cr.Address, cr.User); err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	var jumpHost models.JumpHostModel
+	if err := global.DB.First(&jumpHost, c.Param("id")).Error; err != nil {
+		res.FailWithMessage("跳板机不存在", c)
+		return
+	}
+
+	jumpHost.Name = cr.Name
+	jumpHost.Address = cr.Address
+	jumpHost.Description = cr.Description
+	if cr.Port != 0 {
+		jumpHost.Port = cr.Port
+	}
+	if cr.User != "" {
+		jumpHost.User = cr.User
+	}
+	if cr.PrivateKey != nil {
+		if *cr.PrivateKey != "" {
+			if _, err := ssh.ParsePrivateKey([]byte(*cr.PrivateKey)); err != nil {
+				res.FailWithMessage("私钥格式错误", c)
+				return
+			}
+		}
+		jumpHost.PrivateKey = *cr.PrivateKey
+	}
+
+	if err := global.DB.Save(&jumpHost).Error; err != nil {
+		global.Log.Error(err)
+		res.FailWithMessage("更新跳板机失败", c)
+		return
+	}
+	res.OkWithMessage("更新成功", c)
+}
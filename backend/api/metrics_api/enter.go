@@ -0,0 +1,4 @@
+package metrics_api
+
+type MetricsApi struct {
+}
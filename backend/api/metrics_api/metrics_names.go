@@ -0,0 +1,44 @@
+package metrics_api
+
+import (
+	"ccops/global"
+	"ccops/models/monitor"
+	"ccops/models/res"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MetricsNamesRequest struct {
+	HostID uint `form:"hostId" binding:"required"`
+}
+
+// MetricsNamesView 主机当前可查询的指标路径，取自最新的数据点
+func (MetricsApi) MetricsNamesView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	var cr MetricsNamesRequest
+	if err := c.ShouldBindQuery(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	if !permission.IsPermission(claims.UserID, cr.HostID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	latest := global.TimeSeriesDB.GetLatest(uint64(cr.HostID))
+	if latest == nil {
+		res.OkWithData([]string{}, c)
+		return
+	}
+	fields := monitor.Flatten(latest)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res.OkWithData(names, c)
+}
@@ -0,0 +1,54 @@
+package metrics_api
+
+import (
+	"ccops/models/res"
+	"ccops/service/metrics_ser"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+
+	"github.com/gin-gonic/gin"
+)
+
+// allowedHosts 普通用户只能查询有权限的主机，管理员返回 nil 表示不限制
+func allowedHosts(claims *jwts.CustomClaims) []uint {
+	if permission.IsAdmin(claims.UserID) {
+		return nil
+	}
+	ids := permission.GetUserPermissionHostIds(claims.UserID)
+	if ids == nil {
+		ids = []uint{}
+	}
+	return ids
+}
+
+// MetricsQueryView 查询指标曲线，可按主机、标签或全部合并
+func (MetricsApi) MetricsQueryView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	var cr metrics_ser.QueryRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	if err := cr.Normalize(); err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	if len(cr.HostIDs) == 0 && len(cr.LabelIDs) == 0 {
+		res.FailWithMessage("请选择主机或标签", c)
+		return
+	}
+
+	hosts, err := metrics_ser.ResolveHosts(cr.HostIDs, cr.LabelIDs, allowedHosts(claims))
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	result, err := metrics_ser.Query(cr, hosts)
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	res.OkWithData(result, c)
+}
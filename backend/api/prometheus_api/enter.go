@@ -0,0 +1,4 @@
+package prometheus_api
+
+type PrometheusApi struct {
+}
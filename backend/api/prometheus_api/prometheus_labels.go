@@ -0,0 +1,43 @@
+package prometheus_api
+
+import (
+	"ccops/service/metrics_ser"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LabelsView 标签名 /api/v1/labels
+func (PrometheusApi) LabelsView(c *gin.Context) {
+	names, err := metrics_ser.PromLabelNames(matchParams(c))
+	if err != nil {
+		promFail(http.StatusBadRequest, "bad_data", err, c)
+		return
+	}
+	promSuccess(names, c)
+}
+
+// LabelValuesView 标签值 /api/v1/label/:name/values，__name__ 为所有序列名
+func (PrometheusApi) LabelValuesView(c *gin.Context) {
+	values, err := metrics_ser.PromLabelValues(c.Param("name"), matchParams(c))
+	if err != nil {
+		promFail(http.StatusBadRequest, "bad_data", err, c)
+		return
+	}
+	promSuccess(values, c)
+}
+
+// SeriesView 匹配 match[] 的序列 /api/v1/series
+func (PrometheusApi) SeriesView(c *gin.Context) {
+	series, err := metrics_ser.PromSeries(matchParams(c))
+	if err != nil {
+		promFail(http.StatusBadRequest, "bad_data", err, c)
+		return
+	}
+	promSuccess(series, c)
+}
+
+// MetadataView 指标元数据 /api/v1/metadata，没有类型和说明信息，返回空
+func (PrometheusApi) MetadataView(c *gin.Context) {
+	promSuccess(gin.H{}, c)
+}
@@ -0,0 +1,21 @@
+package prometheus_api
+
+import (
+	"bytes"
+	"ccops/global"
+	"ccops/service/metrics_ser"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsView 以 Prometheus 文本格式输出各主机的最新指标，供 Prometheus 抓取
+func (PrometheusApi) MetricsView(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := metrics_ser.WriteExposition(&buf); err != nil {
+		global.Log.Errorf("生成 Prometheus 指标失败: %v", err)
+		c.String(http.StatusInternalServerError, "生成指标失败")
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,60 @@
+package prometheus_api
+
+import (
+	"ccops/service/metrics_ser"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueryView 即时查询 /api/v1/query
+func (PrometheusApi) QueryView(c *gin.Context) {
+	query := c.Request.FormValue("query")
+	if query == "" {
+		promFail(http.StatusBadRequest, "bad_data", errors.New("缺少 query 参数"), c)
+		return
+	}
+	ts, err := parseTime(c.Request.FormValue("time"), time.Now().Unix())
+	if err != nil {
+		promFail(http.StatusBadRequest, "bad_data", err, c)
+		return
+	}
+	result, err := metrics_ser.PromQuery(query, ts)
+	if err != nil {
+		promFail(http.StatusBadRequest, "bad_data", err, c)
+		return
+	}
+	promSuccess(result, c)
+}
+
+// QueryRangeView 范围查询 /api/v1/query_range
+func (PrometheusApi) QueryRangeView(c *gin.Context) {
+	query := c.Request.FormValue("query")
+	if query == "" {
+		promFail(http.StatusBadRequest, "bad_data", errors.New("缺少 query 参数"), c)
+		return
+	}
+	start, err := parseTime(c.Request.FormValue("start"), 0)
+	if err != nil || start == 0 {
+		promFail(http.StatusBadRequest, "bad_data", errors.New("start 参数格式错误"), c)
+		return
+	}
+	end, err := parseTime(c.Request.FormValue("end"), 0)
+	if err != nil || end == 0 {
+		promFail(http.StatusBadRequest, "bad_data", errors.New("end 参数格式错误"), c)
+		return
+	}
+	step, err := parseStep(c.Request.FormValue("step"))
+	if err != nil {
+		promFail(http.StatusBadRequest, "bad_data", err, c)
+		return
+	}
+	result, err := metrics_ser.PromQueryRange(query, start, end, step)
+	if err != nil {
+		promFail(http.StatusBadRequest, "bad_data", err, c)
+		return
+	}
+	promSuccess(result, c)
+}
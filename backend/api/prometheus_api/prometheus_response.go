@@ -0,0 +1,64 @@
+package prometheus_api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prometheus HTTP API 的响应格式，Grafana 等客户端按此解析，不使用 res 包
+
+func promSuccess(data any, c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": data})
+}
+
+func promFail(code int, errorType string, err error, c *gin.Context) {
+	c.JSON(code, gin.H{"status": "error", "errorType": errorType, "error": err.Error()})
+}
+
+// parseTime 解析 Unix 秒（可带小数）或 RFC3339 时间，为空时返回 def
+func parseTime(value string, def int64) (int64, error) {
+	if value == "" {
+		return def, nil
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return int64(math.Floor(f)), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.Unix(), nil
+	}
+	return 0, errors.New("时间格式错误: " + value)
+}
+
+// parseStep 解析秒数或 15s、5m、1h、1d、1w 形式的间隔
+func parseStep(value string) (int64, error) {
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		if f < 1 {
+			return 0, errors.New("step 不能小于 1 秒")
+		}
+		return int64(f), nil
+	}
+	multiplier := time.Duration(1)
+	switch {
+	case strings.HasSuffix(value, "d"):
+		value, multiplier = strings.TrimSuffix(value, "d")+"h", 24
+	case strings.HasSuffix(value, "w"):
+		value, multiplier = strings.TrimSuffix(value, "w")+"h", 24*7
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d*multiplier < time.Second {
+		return 0, errors.New("step 格式错误")
+	}
+	return int64((d * multiplier).Seconds()), nil
+}
+
+// matchParams 请求中的 match[] 参数，GET 和 POST 表单都支持
+func matchParams(c *gin.Context) []string {
+	_ = c.Request.ParseForm()
+	return c.Request.Form["match[]"]
+}
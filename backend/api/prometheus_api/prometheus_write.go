@@ -0,0 +1,39 @@
+package prometheus_api
+
+import (
+	"ccops/global"
+	"ccops/service/metrics_ser"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RemoteWriteView 接收 Prometheus 远程写入，按主机写入为自定义指标
+func (PrometheusApi) RemoteWriteView(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 8<<20+1))
+	if err != nil {
+		promFail(http.StatusBadRequest, "bad_data", err, c)
+		return
+	}
+	if len(body) > 8<<20 {
+		promFail(http.StatusRequestEntityTooLarge, "bad_data", errors.New("请求体过大"), c)
+		return
+	}
+	series, err := metrics_ser.DecodeWriteRequest(body)
+	if err != nil {
+		promFail(http.StatusBadRequest, "bad_data", err, c)
+		return
+	}
+	result, err := metrics_ser.ApplyRemoteWrite(series)
+	if err != nil {
+		global.Log.Errorf("远程写入失败: %v", err)
+		promFail(http.StatusInternalServerError, "internal", err, c)
+		return
+	}
+	if result.Dropped > 0 {
+		global.Log.Debugf("远程写入: 写入 %d 个数据点，丢弃 %d 个", result.Accepted, result.Dropped)
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,4 @@
+package sftp_api
+
+type SftpApi struct {
+}
@@ -0,0 +1,70 @@
+package sftp_api
+
+import (
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/sftp_ser"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SftpDownloadView 下载文件，超过大小上限时拒绝
+func (SftpApi) SftpDownloadView(c *gin.Context) {
+	var cr SftpPathRequest
+	if err := c.ShouldBindQuery(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	p, err := sftp_ser.CleanPath(cr.Path)
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	client, op, ok := openHost(c)
+	if !ok {
+		return
+	}
+	defer client.Close()
+
+	op.Operation = models.FileOpDownload
+	op.Path = p
+	fi, err := client.Stat(p)
+	if err != nil {
+		sftp_ser.Audit(op, err)
+		res.FailWithMessage("读取文件信息失败: "+err.Error(), c)
+		return
+	}
+	if fi.IsDir() {
+		sftp_ser.Audit(op, fmt.Errorf("不能下载目录"))
+		res.FailWithMessage("不能下载目录", c)
+		return
+	}
+	if limit := sftp_ser.MaxDownload(); limit > 0 && fi.Size() > limit {
+		sftp_ser.Audit(op, fmt.Errorf("文件大小 %d 超过下载上限 %d", fi.Size(), limit))
+		res.FailWithMessage("文件大小超过下载上限", c)
+		return
+	}
+
+	file, err := client.Open(p)
+	if err != nil {
+		sftp_ser.Audit(op, err)
+		res.FailWithMessage("打开文件失败: "+err.Error(), c)
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", "attachment; filename*=utf-8''"+url.PathEscape(path.Base(p)))
+	c.Header("Content-Length", strconv.FormatInt(fi.Size(), 10))
+	c.Status(http.StatusOK)
+	// 响应头已发出，之后的错误只能记录到审计
+	n, err := io.Copy(c.Writer, io.LimitReader(file, fi.Size()))
+	op.Size = n
+	sftp_ser.Audit(op, err)
+}
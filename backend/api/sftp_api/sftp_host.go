@@ -0,0 +1,48 @@
+package sftp_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/sftp_ser"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openHost 检查终端权限并连接主机，返回已填好用户和主机信息的审计记录
+// 文件管理等同于终端访问，使用终端权限
+func openHost(c *gin.Context) (*sftp_ser.Client, sftp_ser.Operation, bool) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		res.FailWithMessage("主机ID错误", c)
+		return nil, sftp_ser.Operation{}, false
+	}
+	if !permission.IsTerminalPermission(claims.UserID, uint(id)) {
+		res.FailWithMessage("权限错误", c)
+		return nil, sftp_ser.Operation{}, false
+	}
+	var host models.HostModel
+	if err := global.DB.First(&host, id).Error; err != nil {
+		res.FailWithMessage("主机不存在", c)
+		return nil, sftp_ser.Operation{}, false
+	}
+
+	op := sftp_ser.Operation{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		Host:     host,
+		ClientIP: c.ClientIP(),
+	}
+	client, err := sftp_ser.Open(host, claims.UserID, claims.Username)
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return nil, op, false
+	}
+	return client, op, true
+}
@@ -0,0 +1,92 @@
+package sftp_api
+
+import (
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/sftp_ser"
+	"path"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SftpPathRequest struct {
+	Path string `form:"path" binding:"required"` // 绝对路径
+}
+
+// SftpListView 列出目录，目录在前并按名称排序
+func (SftpApi) SftpListView(c *gin.Context) {
+	var cr SftpPathRequest
+	if err := c.ShouldBindQuery(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	p, err := sftp_ser.CleanPath(cr.Path)
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	client, op, ok := openHost(c)
+	if !ok {
+		return
+	}
+	defer client.Close()
+
+	op.Operation = models.FileOpList
+	op.Path = p
+	entries, err := client.ReadDir(p)
+	sftp_ser.Audit(op, err)
+	if err != nil {
+		res.FailWithMessage("读取目录失败: "+err.Error(), c)
+		return
+	}
+
+	list := make([]sftp_ser.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, sftp_ser.NewFileInfo(p, entry))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].IsDir != list[j].IsDir {
+			return list[i].IsDir
+		}
+		return list[i].Name < list[j].Name
+	})
+	res.OkWithList(list, int64(len(list)), c)
+}
+
+// SftpStatView 查看文件信息，符号链接返回链接本身和目标
+func (SftpApi) SftpStatView(c *gin.Context) {
+	var cr SftpPathRequest
+	if err := c.ShouldBindQuery(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	p, err := sftp_ser.CleanPath(cr.Path)
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	client, op, ok := openHost(c)
+	if !ok {
+		return
+	}
+	defer client.Close()
+
+	op.Operation = models.FileOpStat
+	op.Path = p
+	fi, err := client.Lstat(p)
+	sftp_ser.Audit(op, err)
+	if err != nil {
+		res.FailWithMessage("读取文件信息失败: "+err.Error(), c)
+		return
+	}
+
+	info := sftp_ser.NewFileInfo(path.Dir(p), fi)
+	data := gin.H{"file": info}
+	if info.IsLink {
+		if target, err := client.ReadLink(p); err == nil {
+			data["linkTarget"] = target
+		}
+	}
+	res.OkWithData(data, c)
+}
@@ -0,0 +1,157 @@
+package sftp_api
+
+import (
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/sftp_ser"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SftpRenameRequest struct {
+	From string `json:"from" binding:"required"` // 原路径
+	To   string `json:"to" binding:"required"`   // 新路径
+}
+
+// SftpRenameView 重命名或移动文件，目标已存在时失败
+func (SftpApi) SftpRenameView(c *gin.Context) {
+	var cr SftpRenameRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithError(err, &cr, c)
+		return
+	}
+	from, err := sftp_ser.CleanPath(cr.From)
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	to, err := sftp_ser.CleanPath(cr.To)
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	client, op, ok := openHost(c)
+	if !ok {
+		return
+	}
+	defer client.Close()
+
+	op.Operation = models.FileOpRename
+	op.Path = from
+	op.Target = to
+	err = client.Rename(from, to)
+	sftp_ser.Audit(op, err)
+	if err != nil {
+		res.FailWithMessage("重命名失败: "+err.Error(), c)
+		return
+	}
+	res.OkWithMessage("重命名成功", c)
+}
+
+type SftpRemoveRequest struct {
+	Path      string `json:"path" binding:"required"` // 绝对路径
+	Recursive bool   `json:"recursive"`               // 递归删除目录
+}
+
+// SftpRemoveView 删除文件或目录，非空目录需要指定 recursive
+func (SftpApi) SftpRemoveView(c *gin.Context) {
+	var cr SftpRemoveRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithError(err, &cr, c)
+		return
+	}
+	p, err := sftp_ser.CleanPath(cr.Path)
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	if p == "/" {
+		res.FailWithMessage("不能删除根目录", c)
+		return
+	}
+	client, op, ok := openHost(c)
+	if !ok {
+		return
+	}
+	defer client.Close()
+
+	op.Operation = models.FileOpRemove
+	op.Path = p
+	if cr.Recursive {
+		op.Target = "recursive"
+	}
+	fi, err := client.Lstat(p)
+	if err == nil {
+		switch {
+		case fi.IsDir() && cr.Recursive:
+			err = client.RemoveAll(p)
+		case fi.IsDir():
+			err = client.RemoveDirectory(p)
+		default:
+			err = client.Remove(p)
+		}
+	}
+	sftp_ser.Audit(op, err)
+	if err != nil {
+		res.FailWithMessage("删除失败: "+err.Error(), c)
+		return
+	}
+	res.OkWithMessage("删除成功", c)
+}
+
+type SftpChmodRequest struct {
+	Path string `json:"path" binding:"required"` // 绝对路径
+	Mode string `json:"mode" binding:"required"` // 八进制权限，如 0644
+}
+
+// SftpChmodView 修改文件权限
+func (SftpApi) SftpChmodView(c *gin.Context) {
+	var cr SftpChmodRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithError(err, &cr, c)
+		return
+	}
+	p, err := sftp_ser.CleanPath(cr.Path)
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	mode, err := strconv.ParseUint(cr.Mode, 8, 32)
+	if err != nil || mode > 07777 {
+		res.FailWithMessage("权限格式错误", c)
+		return
+	}
+	client, op, ok := openHost(c)
+	if !ok {
+		return
+	}
+	defer client.Close()
+
+	op.Operation = models.FileOpChmod
+	op.Path = p
+	op.Target = cr.Mode
+	err = client.Chmod(p, sftpMode(mode))
+	sftp_ser.Audit(op, err)
+	if err != nil {
+		res.FailWithMessage("修改权限失败: "+err.Error(), c)
+		return
+	}
+	res.OkWithMessage("修改权限成功", c)
+}
+
+// sftpMode 把 setuid/setgid/sticky 位转换为 os.FileMode 的表示
+func sftpMode(mode uint64) os.FileMode {
+	m := os.FileMode(mode & 0777)
+	if mode&04000 != 0 {
+		m |= os.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		m |= os.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		m |= os.ModeSticky
+	}
+	return m
+}
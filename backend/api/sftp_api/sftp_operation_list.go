@@ -0,0 +1,64 @@
+package sftp_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SftpOperationListRequest struct {
+	models.PageInfo
+	UserID    uint   `form:"userId"`    // 用户
+	HostID    uint   `form:"hostId"`    // 主机
+	Operation string `form:"operation"` // 操作类型
+}
+
+// SftpOperationListView 文件操作审计列表，普通用户只能看到自己的操作
+func (SftpApi) SftpOperationListView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	var cr SftpOperationListRequest
+	if err := c.ShouldBindQuery(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	if cr.Page <= 0 {
+		cr.Page = 1
+	}
+	if cr.Limit <= 0 {
+		cr.Limit = 10
+	}
+
+	query := global.DB.Model(&models.FileOperationModel{})
+	if !permission.IsAdmin(claims.UserID) {
+		query = query.Where("user_id = ?", claims.UserID)
+	} else if cr.UserID != 0 {
+		query = query.Where("user_id = ?", cr.UserID)
+	}
+	if cr.HostID != 0 {
+		query = query.Where("host_id = ?", cr.HostID)
+	}
+	if cr.Operation != "" {
+		query = query.Where("operation = ?", cr.Operation)
+	}
+	if cr.Key != "" {
+		query = query.Where("path LIKE ? OR username LIKE ? OR host_name LIKE ?", "%"+cr.Key+"%", "%"+cr.Key+"%", "%"+cr.Key+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var list []models.FileOperationModel
+	if err := query.Order("id DESC").
+		Offset((cr.Page - 1) * cr.Limit).Limit(cr.Limit).
+		Find(&list).Error; err != nil {
+		res.FailWithMessage("查询失败", c)
+		return
+	}
+	res.OkWithList(list, total, c)
+}
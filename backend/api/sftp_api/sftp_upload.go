@@ -0,0 +1,91 @@
+package sftp_api
+
+import (
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/sftp_ser"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SftpUploadView 上传文件到指定目录，表单字段 path 为目录，file 为文件，overwrite=true 时覆盖同名文件
+func (SftpApi) SftpUploadView(c *gin.Context) {
+	// 先检查权限，没有权限时不读取请求体
+	client, op, ok := openHost(c)
+	if !ok {
+		return
+	}
+	defer client.Close()
+
+	// 超出上限的请求体直接拒绝，避免先落盘再检查
+	if limit := sftp_ser.MaxUpload(); limit > 0 {
+		// 预留表单其他字段和边界的空间
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1024*1024)
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		res.FailWithMessage("读取上传文件失败: "+err.Error(), c)
+		return
+	}
+	dir, err := sftp_ser.CleanPath(c.PostForm("path"))
+	if err != nil {
+		res.FailWithMessage(err.Error(), c)
+		return
+	}
+	name := path.Base(fileHeader.Filename)
+	if name == "." || name == "/" || strings.Contains(name, "\\") {
+		res.FailWithMessage("文件名错误", c)
+		return
+	}
+	if limit := sftp_ser.MaxUpload(); limit > 0 && fileHeader.Size > limit {
+		res.FailWithMessage("文件大小超过上传上限", c)
+		return
+	}
+
+	target := path.Join(dir, name)
+	op.Operation = models.FileOpUpload
+	op.Path = target
+	op.Size = fileHeader.Size
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if c.PostForm("overwrite") != "true" {
+		// sftp 服务端返回的错误不是 os 的错误类型，os.IsExist 无法判断，先检查目标是否存在
+		if _, err := client.Lstat(target); err == nil {
+			sftp_ser.Audit(op, fmt.Errorf("%s: %w", target, os.ErrExist))
+			res.FailWithMessage("文件已存在", c)
+			return
+		}
+		flags |= os.O_EXCL
+	}
+	src, err := fileHeader.Open()
+	if err != nil {
+		sftp_ser.Audit(op, err)
+		res.FailWithMessage("读取上传文件失败: "+err.Error(), c)
+		return
+	}
+	defer src.Close()
+
+	dst, err := client.OpenFile(target, flags)
+	if err != nil {
+		sftp_ser.Audit(op, err)
+		res.FailWithMessage("创建文件失败: "+err.Error(), c)
+		return
+	}
+	n, err := io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	op.Size = n
+	sftp_ser.Audit(op, err)
+	if err != nil {
+		res.FailWithMessage(fmt.Sprintf("上传失败: %v", err), c)
+		return
+	}
+	res.OkWithMessage("上传成功", c)
+}
@@ -5,39 +5,25 @@ import (
 	"ccops/global"
 	"ccops/models"
 	"ccops/models/res"
+	"ccops/service/ssh_ser"
+	"ccops/service/task_ser"
+	"ccops/utils"
 	"ccops/utils/jwts"
 	"ccops/utils/permission"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
-var (
-	mu        sync.RWMutex // 保护连接的互斥锁
-	connMutex sync.Mutex
-	dataStore = make(map[uint][]string)
-)
-
-type Task struct {
-	Output        []string
-	ActiveClients map[*websocket.Conn]bool
-	Mutex         sync.Mutex
-}
-
-var tasks = make(map[uint]*Task)
-var tasksMutex sync.Mutex
-
 type TaskCreateRequest struct {
 	TaskName              string     `json:"taskName"`
 	HostIdList            []uint     `json:"hostIdList"`
@@ -60,109 +46,109 @@ type VarContent struct {
 
 // WebSocket 升级器
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // 允许所有来源
-	},
+	CheckOrigin: utils.CheckOrigin,
 }
 
-// 全局变量存储 WebSocket 连接
-
-// 连接管理
+// 每次推送历史输出时读取的行数
+const replayBatch = 1000
 
-// WebSocket 处理函数
+// WebSocketHandler 推送任务输出，offset 参数指定从第几行开始，用于断线重连后续传
 func (TaskApi) WebSocketHandler(c *gin.Context) {
-	// 升级到 WebSocket
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		fmt.Println("升级 WebSocket 连接失败:", err)
+		res.FailWithMessage("任务ID错误", c)
 		return
 	}
-	defer conn.Close()
+	offset, _ := strconv.Atoi(c.Query("offset"))
 
-	taskIDStr := c.Param("id")
-	taskID, err := strconv.ParseUint(taskIDStr, 10, 32)
+	// 升级到 WebSocket
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		fmt.Println("解析任务ID失败:", err)
+		global.Log.Errorf("升级 WebSocket 连接失败: %v", err)
 		return
 	}
-	taskIDUint := uint(taskID)
+	defer conn.Close()
 
-	// 添加日志
-	fmt.Printf("WebSocket连接已建立，任务ID: %d\n", taskIDUint)
+	send := func(event string, seq int, message string) error {
+		jsonData := map[string]interface{}{
+			"message": message,
+			"event":   event,
+			"seq":     seq,
+			"taskID":  taskID,
+		}
+		jsonBytes, _ := json.Marshal(jsonData)
+		return conn.WriteMessage(websocket.TextMessage, jsonBytes)
+	}
 
-	tasksMutex.Lock()
-	task, exists := tasks[taskIDUint]
-	tasksMutex.Unlock()
+	// 客户端断开时结束推送
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
 
-	if !exists {
-		// 添加日志
-		fmt.Printf("任务 %d 不存在于tasks map中\n", taskIDUint)
-		var result string
-		if err := global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Select("result").First(&result).Error; err != nil {
-			conn.Close()
+	for {
+		// 先订阅再补历史，保证两段输出之间不丢行
+		var live <-chan task_ser.Line
+		var total int
+		out := task_ser.GetOutput(uint(taskID))
+		if out != nil {
+			live, total = out.Subscribe()
+		} else if total, err = task_ser.Total(uint(taskID)); err != nil {
+			global.Log.Error(err)
 			return
 		}
 
-		// 将 result 字符串按行分割
-		lines := strings.Split(result, "\n")
-		for _, line := range lines {
-			jsonData := map[string]interface{}{
-				"message": line,
-				"event":   "progress", // 事件标识，执行过程中可以一直用 "task_update"
-				"taskID":  taskID,
+		for offset < total {
+			batch := total - offset
+			if batch > replayBatch {
+				batch = replayBatch
 			}
-
-			jsonBytes, _ := json.Marshal(jsonData)
-			if err := conn.WriteMessage(websocket.TextMessage, jsonBytes); err != nil {
-				conn.Close()
+			lines, _, err := task_ser.ReadLines(uint(taskID), offset, batch)
+			if err != nil || len(lines) == 0 {
+				if out != nil {
+					out.Unsubscribe(live)
+				}
 				return
 			}
+			for _, line := range lines {
+				if err := send("progress", line.Seq, line.Text); err != nil {
+					if out != nil {
+						out.Unsubscribe(live)
+					}
+					return
+				}
+			}
+			offset = lines[len(lines)-1].Seq + 1
 		}
-		jsonData := map[string]interface{}{
-			"message": "Task completed",
-			"event":   "end", // 事件标识，执行过程中可以一直用 "task_update"
-			"taskID":  taskID,
-		}
-		jsonBytes, _ := json.Marshal(jsonData)
-		if err := conn.WriteMessage(websocket.TextMessage, jsonBytes); err != nil {
-			conn.Close()
+
+		if live == nil {
+			send("end", offset, "Task completed")
 			return
 		}
 
-	} else {
-		task.Mutex.Lock()
-		task.ActiveClients[conn] = true
-		for _, line := range task.Output {
-			jsonData := map[string]interface{}{
-				"message": line,
-				"event":   "progress", // 事件标识，执行过程中可以一直用 "task_update"
-				"taskID":  taskID,
+		// channel 关闭说明任务结束或推送过慢被断开，两种情况都回到循环开头补齐剩余输出
+		for line := range live {
+			if line.Seq < offset {
+				continue
 			}
-
-			jsonBytes, _ := json.Marshal(jsonData)
-			if err := conn.WriteMessage(websocket.TextMessage, jsonBytes); err != nil {
-				conn.Close()
-				delete(task.ActiveClients, conn)
-				task.Mutex.Unlock()
+			if err := send("progress", line.Seq, line.Text); err != nil {
+				out.Unsubscribe(live)
 				return
 			}
-		}
-		task.Mutex.Unlock()
-	}
-
-	defer func() {
-		task.Mutex.Lock()
-		delete(task.ActiveClients, conn)
-		task.Mutex.Unlock()
-		conn.Close()
-	}()
-
-	for {
-		if _, _, err := conn.ReadMessage(); err != nil {
-			break
+			offset = line.Seq + 1
+			select {
+			case <-closed:
+				out.Unsubscribe(live)
+				return
+			default:
+			}
 		}
 	}
-
 }
 
 func (TaskApi) TaskCreateView(c *gin.Context) {
@@ -286,17 +272,7 @@ func (TaskApi) TaskCreateView(c *gin.Context) {
 
 		res.OkWithData(task.ID, c)
 
-		taskWs := &Task{
-			ActiveClients: make(map[*websocket.Conn]bool),
-		}
-		tasksMutex.Lock()
-		tasks[task.ID] = taskWs
-		tasksMutex.Unlock()
-		// 使用全局 WebSocket 连接
-		connMutex.Lock()
-		defer connMutex.Unlock()
-
-		go taskWs.createAndExecutePlaybook(req.RoleIDList, req, task.ID)
+		go createAndExecutePlaybook(req.RoleIDList, req, task.ID)
 	} else if req.Type == "ad-hoc" {
 		// 开启事务
 		tx := global.DB.Begin()
@@ -346,22 +322,12 @@ func (TaskApi) TaskCreateView(c *gin.Context) {
 		}
 		res.OkWithData(task.ID, c)
 
-		taskWs := &Task{
-			ActiveClients: make(map[*websocket.Conn]bool),
-		}
-		tasksMutex.Lock()
-		tasks[task.ID] = taskWs
-		tasksMutex.Unlock()
-		// 使用全局 WebSocket 连接
-		connMutex.Lock()
-		defer connMutex.Unlock()
-
-		go taskWs.ExecuteShortcutScript(req, task.ID)
+		go ExecuteShortcutScript(req, task.ID)
 	}
 
 }
 
-func (t *Task) createAndExecutePlaybook(roleIDs []uint, req TaskCreateRequest, taskID uint) error {
+func createAndExecutePlaybook(roleIDs []uint, req TaskCreateRequest, taskID uint) error {
 	global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Update("status", "running")
 	// 创建临时目录
 	tempDir := "./ansible/roles"
@@ -369,16 +335,23 @@ func (t *Task) createAndExecutePlaybook(roleIDs []uint, req TaskCreateRequest, t
 		return fmt.Errorf("创建目录失败: %w", err)
 	}
 
-	CreateInventoryFile(req)
+	keyPath, err := CreateInventoryFile(req, taskID)
+	if err != nil {
+		global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Update("status", "exception")
+		return err
+	}
+	defer ssh_ser.RemoveTaskKey(taskID)
 
 	// 创建 ansible.cfg 文件来配置 SSH 选项
-	ansibleCfg := `[defaults]
-private_key_file = ./.ssh/ccops
-host_key_checking = False
+	// 主机密钥使用平台记录的 known_hosts 严格校验
+	ansibleCfg := fmt.Sprintf(`[defaults]
+private_key_file = %s
+host_key_checking = True
 
 [ssh_connection]
 pipelining = True
-`
+ssh_args = -C -o ControlMaster=auto -o ControlPersist=60s %s %s
+`, keyPath, ssh_ser.KnownHostsArgs(), ssh_ser.StaticKeyArgs())
 	if err := ioutil.WriteFile("./ansible.cfg", []byte(ansibleCfg), 0644); err != nil {
 		return fmt.Errorf("创建 ansible.cfg 失败: %w", err)
 	}
@@ -498,202 +471,91 @@ pipelining = True
 		return fmt.Errorf("写入 playbook 文件失败: %w", err)
 	}
 
-	// 使用 io.Pipe 捕获 ansible-playbook 的输出
-	r, w := io.Pipe()
 	cmd := exec.Command("ansible-playbook", "-i", "targets", playbookFilePath)
+	return runTaskCommand(cmd, taskID)
+}
 
-	cmd.Stdout = w
-	cmd.Stderr = w
-
-	err = cmd.Start()
-	if err != nil {
-		global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Updates(map[string]interface{}{"status": "exception"})
-		return fmt.Errorf("执行 playbook 失败: %w", err)
-	}
-
-	scanner := bufio.NewScanner(r)
-
-	go func() {
-		for scanner.Scan() {
-			line := scanner.Text()
-			t.Mutex.Lock()
-			t.Output = append(t.Output, line)
-			for client := range t.ActiveClients {
-				jsonData := map[string]interface{}{
-					"message": line,
-					"event":   "progress",
-					"taskID":  taskID,
-				}
-
-				jsonBytes, _ := json.Marshal(jsonData)
-				if err := client.WriteMessage(websocket.TextMessage, jsonBytes); err != nil {
-					client.Close()
-					delete(t.ActiveClients, client)
-				}
-			}
-			t.Mutex.Unlock()
-		}
-
-		if err := scanner.Err(); err != nil {
-			fmt.Println("Error reading from pipe:", err)
-		}
-
-		if err := w.Close(); err != nil {
-			fmt.Println("Error closing pipe writer:", err)
-		}
-	}()
-
-	err = cmd.Wait()
-	if err != nil {
-		global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Updates(map[string]interface{}{"status": "fail"})
-	}
-
-	doneMessage := "Task completed"
-	t.Mutex.Lock()
-	t.Output = append(t.Output, doneMessage)
-	for client := range t.ActiveClients {
-		jsonData := map[string]interface{}{
-			"message": doneMessage,
-			"event":   "end",
-			"taskID":  taskID,
-		}
+func ExecuteShortcutScript(req TaskCreateRequest, taskID uint) error {
+	global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Update("status", "running")
 
-		jsonBytes, _ := json.Marshal(jsonData)
-		if err := client.WriteMessage(websocket.TextMessage, jsonBytes); err != nil {
-			client.Close()
-			delete(t.ActiveClients, client)
-		}
+	if _, err := CreateInventoryFile(req, taskID); err != nil {
+		global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Update("status", "exception")
+		return err
 	}
-	t.Mutex.Unlock()
+	defer ssh_ser.RemoveTaskKey(taskID)
 
-	t.Output = t.Output[:len(t.Output)-1]
-	result := strings.Join(t.Output, "\n")
-	global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Updates(map[string]interface{}{
-		"result": result,
-		"status": "done",
-	})
-	delete(tasks, taskID)
+	defer os.Remove("./targets")
 
-	return nil
+	sshArgs := strings.TrimSpace(ssh_ser.KnownHostsArgs() + " " + ssh_ser.StaticKeyArgs())
+	cmd := exec.Command("ansible", "all", "-i", "targets", "-m", "shell", "-a", req.ShortcutScriptContent, "--ssh-extra-args="+sshArgs)
+	return runTaskCommand(cmd, taskID)
 }
 
-func (t *Task) ExecuteShortcutScript(req TaskCreateRequest, taskID uint) error {
-	global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Update("status", "running")
-
-	CreateInventoryFile(req)
+// runTaskCommand 执行 ansible 命令，输出逐行写入任务日志，结束后更新任务状态并在 result 中保存输出摘要
+func runTaskCommand(cmd *exec.Cmd, taskID uint) error {
+	out := task_ser.NewOutput(taskID)
 
-	defer os.Remove("./targets")
-
-	// 使用 io.Pipe 捕获 ansible-playbook 的输出
-	// 使用 io.Pipe 捕获 ansible-playbook 的输出
+	// 使用 io.Pipe 捕获 ansible 的输出
 	r, w := io.Pipe()
-	cmd := exec.Command("ansible", "all", "-i", "targets", "-m", "shell", "-a", req.ShortcutScriptContent, "--ssh-extra-args='-o StrictHostKeyChecking=no'")
-
-	// 将 cmd 的标准输出和错误输出重定向到 w
 	cmd.Stdout = w
 	cmd.Stderr = w
 
-	// 异步执行 ansible-playbook 命令
-	err := cmd.Start()
-	if err != nil {
+	if err := cmd.Start(); err != nil {
 		global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Updates(map[string]interface{}{"status": "exception"})
-		return fmt.Errorf("执行 ad-hoc 失败: %w", err)
+		out.Close()
+		return fmt.Errorf("执行任务失败: %w", err)
 	}
 
-	// 创建一个 Scanner 来逐行读取输出
-
-	scanner := bufio.NewScanner(r)
-
-	// 在 goroutine 中逐行读取输出
+	// 逐行读取输出
+	readDone := make(chan struct{})
 	go func() {
+		defer close(readDone)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 		for scanner.Scan() {
-			line := scanner.Text()
-			t.Mutex.Lock()
-			t.Output = append(t.Output, line)
-
-			for client := range t.ActiveClients {
-				jsonData := map[string]interface{}{
-					"message": line,
-					"event":   "progress",
-					"taskID":  taskID,
-				}
-
-				jsonBytes, _ := json.Marshal(jsonData)
-				if err := client.WriteMessage(websocket.TextMessage, jsonBytes); err != nil {
-					client.Close()
-					delete(t.ActiveClients, client)
-				}
-			}
-			t.Mutex.Unlock()
+			out.Append(scanner.Text())
 		}
-
 		if err := scanner.Err(); err != nil {
-			fmt.Println("Error reading from pipe:", err)
-		}
-
-		// 关闭管道写入端
-		if err := w.Close(); err != nil {
-			fmt.Println("Error closing pipe writer:", err)
+			global.Log.Errorf("读取任务 %d 输出失败: %v", taskID, err)
+			// 继续消费剩余输出，避免 ansible 阻塞在写管道上
+			io.Copy(io.Discard, r)
 		}
 	}()
-	err = cmd.Wait()
-	if err != nil {
-		global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Updates(map[string]interface{}{"status": "fail"})
-	}
-	doneMessage := "Task completed"
-	t.Mutex.Lock()
-	t.Output = append(t.Output, doneMessage)
-	for client := range t.ActiveClients {
-		jsonData := map[string]interface{}{
-			"message": doneMessage,
-			"event":   "end", // 事件标识，执行过程中可以一直用 "task_update"
-			"taskID":  taskID,
-		}
 
-		jsonBytes, _ := json.Marshal(jsonData)
-		if err := client.WriteMessage(websocket.TextMessage, jsonBytes); err != nil {
-			client.Close()
-			delete(t.ActiveClients, client)
-		}
+	status := "done"
+	if err := cmd.Wait(); err != nil {
+		status = "fail"
 	}
-	// 任务完成后发送通知
+	w.Close()
+	<-readDone
 
-	t.Mutex.Unlock()
-
-	t.Output = t.Output[:len(t.Output)-1] // 取到倒数第二个元素
-	result := strings.Join(t.Output, "\n")
+	// 先写入最终状态再结束输出，订阅者收到结束时能读到最终状态
 	global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Updates(map[string]interface{}{
-		"result": result,
-		"status": "done",
+		"result": task_ser.Summary(taskID),
+		"status": status,
 	})
-	delete(tasks, taskID)
-
+	if err := out.Close(); err != nil {
+		global.Log.Errorf("任务 %d 输出落库失败: %v", taskID, err)
+	}
 	return nil
 }
 
 // 创建inventory文件,固定写死只有一个[tmp]标签，判断前端传来的请求体，有三种情况
 // 1.只有hostIdList，没有hostLabelList，2.只有hostLabelList，没有hostIdList，3.都有
 // 有hostLabelList的时候，需要多查一层，根据这个查到hostID 并根据hostID查到HostServerUrl 这个就是最终写入文件的地址
-func CreateInventoryFile(req TaskCreateRequest) error {
-	// 用于存储最终的主机信息
-	type HostInfo struct {
-		IP       string
-		Hostname string
-	}
-	var hostInfos []HostInfo
-	hostInfoSet := make(map[string]HostInfo)
+// 返回为任务签发的证书私钥路径，任务结束后由调用方通过 ssh_ser.RemoveTaskKey 删除
+func CreateInventoryFile(req TaskCreateRequest, taskID uint) (string, error) {
+	// 用于存储最终的主机信息，按 HostServerUrl 去重
+	hostSet := make(map[string]models.HostModel)
 
 	// 处理 hostIdList
 	if len(req.HostIdList) > 0 {
 		var hosts []models.HostModel
 		if err := global.DB.Where("id IN ?", req.HostIdList).Find(&hosts).Error; err != nil {
-			return fmt.Errorf("获取主机信息失败: %w", err)
+			return "", fmt.Errorf("获取主机信息失败: %w", err)
 		}
 		for _, host := range hosts {
-			hostInfoSet[host.HostServerUrl] = HostInfo{
-				IP:       host.HostServerUrl,
-				Hostname: host.Name,
-			}
+			hostSet[host.HostServerUrl] = host
 		}
 	}
 
@@ -701,7 +563,7 @@ func CreateInventoryFile(req TaskCreateRequest) error {
 	if len(req.HostLabelList) > 0 {
 		var hostLabels []models.HostLabels
 		if err := global.DB.Where("label_model_id IN ?", req.HostLabelList).Find(&hostLabels).Error; err != nil {
-			return fmt.Errorf("获取主机标签信息失败: %w", err)
+			return "", fmt.Errorf("获取主机标签信息失败: %w", err)
 		}
 
 		var hostIds []uint
@@ -711,35 +573,54 @@ func CreateInventoryFile(req TaskCreateRequest) error {
 
 		var hosts []models.HostModel
 		if err := global.DB.Where("id IN ?", hostIds).Find(&hosts).Error; err != nil {
-			return fmt.Errorf("获取主机信息失败: %w", err)
+			return "", fmt.Errorf("获取主机信息失败: %w", err)
 		}
 		for _, host := range hosts {
-			hostInfoSet[host.HostServerUrl] = HostInfo{
-				IP:       host.HostServerUrl,
-				Hostname: host.Name,
-			}
+			hostSet[host.HostServerUrl] = host
 		}
 	}
 
-	// 将去重后的主机信息添加到列表中
-	for _, info := range hostInfoSet {
-		hostInfos = append(hostInfos, info)
+	keyPath, err := ssh_ser.PrepareTaskKey(taskID)
+	if err != nil {
+		return "", fmt.Errorf("签发任务证书失败: %w", err)
 	}
 
+	hosts := make([]models.HostModel, 0, len(hostSet))
+	for _, host := range hostSet {
+		hosts = append(hosts, host)
+	}
+	jumpHosts := ssh_ser.LoadJumpHosts(hosts)
+
 	// 创建 inventory 文件
 	inventoryContent := "[tmp]\n"
-	for _, info := range hostInfos {
-		inventoryContent += fmt.Sprintf("%s ansible_host=%s ansible_user=root ansible_ssh_private_key_file=~/.ssh/ccops\n",
-			info.Hostname,
-			info.IP)
+	for _, host := range hosts {
+		line := fmt.Sprintf("%s ansible_host=%s ansible_user=root ansible_ssh_private_key_file=%s",
+			host.Name,
+			host.HostServerUrl,
+			keyPath)
+
+		// 需要经过跳板机的主机追加 ssh 参数
+		sshArgs, err := ssh_ser.AnsibleSSHArgs(jumpHosts.Resolve(host), taskID)
+		if err != nil {
+			return "", fmt.Errorf("生成主机 %s 跳板机参数失败: %w", host.Name, err)
+		}
+		if sshArgs != "" {
+			line += fmt.Sprintf(" ansible_ssh_common_args='%s'", sshArgs)
+		}
+		inventoryContent += line + "\n"
 	}
 
 	inventoryFilePath := "./targets"
 	if err := ioutil.WriteFile(inventoryFilePath, []byte(inventoryContent), 0644); err != nil {
-		return fmt.Errorf("写入 inventory 文件失败: %w", err)
+		return "", fmt.Errorf("写入 inventory 文件失败: %w", err)
 	}
 
-	return nil
+	// 记录新主机的密钥并生成 known_hosts，密钥不一致的主机会在执行时连接失败
+	if err := ssh_ser.PrepareKnownHosts(hosts); err != nil {
+		global.Log.Errorf("生成 known_hosts 失败: %v", err)
+	}
+
+	return keyPath, nil
 }
 
 func getVarsContent(roleIDs []uint) string {
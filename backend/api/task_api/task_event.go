@@ -0,0 +1,133 @@
+package task_api
+
+import (
+	"ccops/global"
+	"ccops/models/res"
+	"ccops/service/task_ser"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+type TaskEventRequest struct {
+	After  *int `form:"after"`  // 最后收到的事件ID，SSE 也可以通过 Last-Event-ID 头传递
+	Output bool `form:"output"` // 是否同时推送原始输出行
+}
+
+// sseKeepalive SSE 心跳间隔，防止代理断开空闲连接
+const sseKeepalive = 15 * time.Second
+
+// TaskEventView 任务事件流，WebSocket 请求按 JSON 消息推送，其余请求使用 SSE
+func (TaskApi) TaskEventView(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		res.FailWithMessage("任务ID错误", c)
+		return
+	}
+	var cr TaskEventRequest
+	if err := c.ShouldBindQuery(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	after := -1
+	if cr.After != nil {
+		after = *cr.After
+	} else if lastID, err := strconv.Atoi(c.GetHeader("Last-Event-ID")); err == nil {
+		after = lastID
+	}
+
+	if c.IsWebsocket() {
+		taskEventWebSocket(c, uint(taskID), after, cr.Output)
+		return
+	}
+	taskEventSSE(c, uint(taskID), after, cr.Output)
+}
+
+func taskEventSSE(c *gin.Context, taskID uint, after int, withOutput bool) {
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		res.FailWithMessage("不支持流式响应", c)
+		return
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	flusher.Flush()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	events := make(chan task_ser.Event)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- task_ser.StreamEvents(ctx, taskID, after, withOutput, func(event task_ser.Event) error {
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	ticker := time.NewTicker(sseKeepalive)
+	defer ticker.Stop()
+	for {
+		select {
+		case event := <-events:
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		case <-ticker.C:
+			fmt.Fprint(c.Writer, ": keepalive\n\n")
+			flusher.Flush()
+		case err := <-errCh:
+			if err != nil && ctx.Err() == nil {
+				global.Log.Errorf("任务 %d 事件流异常: %v", taskID, err)
+				data, _ := json.Marshal(map[string]string{"message": err.Error()})
+				fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", data)
+				flusher.Flush()
+			}
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func taskEventWebSocket(c *gin.Context, taskID uint, after int, withOutput bool) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		global.Log.Errorf("升级 WebSocket 连接失败: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	// 客户端断开时结束推送
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = task_ser.StreamEvents(ctx, taskID, after, withOutput, func(event task_ser.Event) error {
+		return conn.WriteJSON(event)
+	})
+	if err != nil && ctx.Err() == nil {
+		global.Log.Errorf("任务 %d 事件流异常: %v", taskID, err)
+		conn.WriteJSON(map[string]string{"type": "error", "message": err.Error()})
+	}
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
@@ -4,7 +4,11 @@ import (
 	"ccops/global"
 	"ccops/models"
 	"ccops/models/res"
+	"ccops/service/task_ser"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // 定义新的结构体，用于响应
@@ -18,31 +22,109 @@ type HostInfo struct {
 	HostIp   string `json:"hostIp"`
 }
 
+// TaskListRequest 任务列表筛选条件，时间格式为 2006-01-02 15:04:05
+type TaskListRequest struct {
+	models.PageInfo
+	Status     string `form:"status"`     // 任务状态
+	Type       string `form:"type"`       // 任务分类 playbook/ad-hoc
+	UserID     uint   `form:"userId"`     // 发布人
+	RoleID     uint   `form:"roleId"`     // 发布的配置
+	RevisionID uint   `form:"revisionId"` // 配置版本
+	HostID     uint   `form:"hostId"`     // 目标主机
+	HostIP     string `form:"hostIp"`     // 目标主机地址
+	StartTime  string `form:"startTime"`  // 创建时间起
+	EndTime    string `form:"endTime"`    // 创建时间止
+	Output     string `form:"output"`     // 输出全文搜索，只在最近的 task_ser.SearchLimit 个符合其他条件的任务中查找
+}
+
+// TaskListResult 任务列表，输出搜索的候选任务超过 task_ser.SearchLimit 时 Truncated 为 true，更早的任务未查找
+type TaskListResult struct {
+	Count     int64              `json:"count"`
+	List      []TaskListResponse `json:"list"`
+	Truncated bool               `json:"truncated"`
+}
+
+const timeLayout = "2006-01-02 15:04:05"
+
 func (TaskApi) TaskListView(c *gin.Context) {
 	var (
-		reps     []TaskListResponse
-		pageInfo models.PageInfo
-		tasks    []models.TaskModel
-		total    int64
+		reps      []TaskListResponse
+		tasks     []models.TaskModel
+		total     int64
+		truncated bool
 	)
 
-	// 绑定请求参数到 PageInfo 结构体
-	if err := c.ShouldBind(&pageInfo); err != nil {
+	// 绑定请求参数
+	var cr TaskListRequest
+	if err := c.ShouldBind(&cr); err != nil {
 		res.FailWithMessage("参数错误", c)
 		return
 	}
+	pageInfo := cr.PageInfo
+	if pageInfo.Page <= 0 {
+		pageInfo.Page = 1
+	}
+	if pageInfo.Limit <= 0 {
+		pageInfo.Limit = 10
+	}
 
 	// 构建查询条件
 	query := global.DB.Model(&models.TaskModel{})
 
-	// 模糊匹配 Name
+	// 模糊匹配任务名
 	if pageInfo.Key != "" {
-		query = query.Where("name LIKE ?", "%"+pageInfo.Key+"%")
+		query = query.Where("task_name LIKE ?", "%"+pageInfo.Key+"%")
+	}
+	if cr.Status != "" {
+		query = query.Where("status = ?", cr.Status)
+	}
+	if cr.Type != "" {
+		query = query.Where("type = ?", cr.Type)
+	}
+	if cr.UserID != 0 {
+		query = query.Where("user_id = ?", cr.UserID)
+	}
+	if cr.RoleID != 0 {
+		query = query.Where("id IN (?)", global.DB.Model(&models.TaskAssociationModel{}).Select("task_id").Where("role_id = ?", cr.RoleID))
+	}
+	if cr.RevisionID != 0 {
+		query = query.Where("id IN (?)", global.DB.Model(&models.TaskAssociationModel{}).Select("task_id").Where("revision_id = ?", cr.RevisionID))
+	}
+	if cr.HostID != 0 {
+		var hostIP string
+		global.DB.Model(&models.HostModel{}).Where("id = ?", cr.HostID).Select("host_server_url").Scan(&hostIP)
+		query = query.Where("id IN (?)", global.DB.Model(&models.TargetAssociationModel{}).Select("task_id").Where("host_ip = ?", hostIP))
+	}
+	if cr.HostIP != "" {
+		query = query.Where("id IN (?)", global.DB.Model(&models.TargetAssociationModel{}).Select("task_id").Where("host_ip = ?", cr.HostIP))
+	}
+	if cr.StartTime != "" {
+		startTime, err := time.ParseInLocation(timeLayout, cr.StartTime, time.Local)
+		if err != nil {
+			res.FailWithMessage("开始时间格式错误", c)
+			return
+		}
+		query = query.Where("created_at >= ?", startTime)
+	}
+	if cr.EndTime != "" {
+		endTime, err := time.ParseInLocation(timeLayout, cr.EndTime, time.Local)
+		if err != nil {
+			res.FailWithMessage("结束时间格式错误", c)
+			return
+		}
+		query = query.Where("created_at <= ?", endTime)
 	}
 
-	// 处理 Tags 筛选（假设 Tags 是 JSON 字段）
-	if pageInfo.Tags != nil && len(pageInfo.Tags) > 0 {
-		query = query.Where("tags @> ?", pageInfo.Tags) // 使用 PostgreSQL 的 JSONB 操作符
+	// 输出全文搜索，先按其他条件取候选任务，再逐个检查输出
+	if cr.Output != "" {
+		var candidateIDs []uint
+		// 多取一个用于判断是否还有更早的任务没有查找
+		query.Session(&gorm.Session{}).Order("created_at DESC").Limit(task_ser.SearchLimit+1).Pluck("id", &candidateIDs)
+		if len(candidateIDs) > task_ser.SearchLimit {
+			candidateIDs = candidateIDs[:task_ser.SearchLimit]
+			truncated = true
+		}
+		query = global.DB.Model(&models.TaskModel{}).Where("id IN ?", nonEmptyIDs(task_ser.MatchOutput(candidateIDs, cr.Output)))
 	}
 
 	// 获取总记录数
@@ -97,7 +179,15 @@ func (TaskApi) TaskListView(c *gin.Context) {
 		reps = append(reps, rep)
 	}
 
-	res.OkWithList(reps, total, c)
+	res.OkWithData(TaskListResult{Count: total, List: reps, Truncated: truncated}, c)
+}
+
+// nonEmptyIDs IN 查询的参数为空时返回一个不存在的ID，保证查询结果为空
+func nonEmptyIDs(ids []uint) []uint {
+	if len(ids) == 0 {
+		return []uint{0}
+	}
+	return ids
 }
 
 // 获取任务ID的辅助函数
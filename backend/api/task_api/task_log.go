@@ -0,0 +1,71 @@
+package task_api
+
+import (
+	"ccops/global"
+	"ccops/models/res"
+	"ccops/service/task_ser"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaskLogRequest struct {
+	Offset int `form:"offset"` // 起始行号，从0开始
+	Limit  int `form:"limit"`  // 读取行数，默认500，最大5000
+	Tail   int `form:"tail"`   // 大于0时忽略 offset，读取最后 tail 行
+}
+
+type TaskLogResponse struct {
+	Lines   []task_ser.Line `json:"lines"`
+	Total   int             `json:"total"`   // 当前总行数
+	Running bool            `json:"running"` // 任务是否仍在输出
+}
+
+// TaskLogView 分页读取任务输出
+func (TaskApi) TaskLogView(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		res.FailWithMessage("任务ID错误", c)
+		return
+	}
+	var cr TaskLogRequest
+	if err := c.ShouldBindQuery(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	if cr.Limit <= 0 {
+		cr.Limit = 500
+	}
+	if cr.Limit > 5000 {
+		cr.Limit = 5000
+	}
+
+	if cr.Tail > 0 {
+		if cr.Tail > 5000 {
+			cr.Tail = 5000
+		}
+		total, err := task_ser.Total(uint(taskID))
+		if err != nil {
+			global.Log.Error(err)
+			res.FailWithMessage("读取任务输出失败", c)
+			return
+		}
+		cr.Offset = total - cr.Tail
+		if cr.Offset < 0 {
+			cr.Offset = 0
+		}
+		cr.Limit = cr.Tail
+	}
+
+	lines, total, err := task_ser.ReadLines(uint(taskID), cr.Offset, cr.Limit)
+	if err != nil {
+		global.Log.Error(err)
+		res.FailWithMessage("读取任务输出失败", c)
+		return
+	}
+	res.OkWithData(TaskLogResponse{
+		Lines:   lines,
+		Total:   total,
+		Running: task_ser.GetOutput(uint(taskID)) != nil,
+	}, c)
+}
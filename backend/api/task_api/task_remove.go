@@ -2,10 +2,12 @@ package task_api
 
 import (
 	"ccops/global"
-	"ccops/models"
 	"ccops/models/res"
+	"ccops/service/task_ser"
 	"ccops/utils/jwts"
 	"ccops/utils/permission"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,34 +18,16 @@ func (TaskApi) TaskRemove(c *gin.Context) {
 		res.FailWithMessage("权限错误", c)
 		return
 	}
-	id := c.Param("id")
-	db := global.DB.Debug()
-
-	// 开始事务
-	tx := db.Begin()
-
-	// 删除任务关联记录
-	if err := tx.Where("task_id = ?", id).Delete(&models.TaskAssociationModel{}).Error; err != nil {
-		tx.Rollback()
-		res.FailWithMessage("删除任务关联的角色失败", c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		res.FailWithMessage("任务ID错误", c)
 		return
 	}
 
-	// 删除任务发版记录
-	if err := tx.Where("task_id = ?", id).Delete(&models.TargetAssociationModel{}).Error; err != nil {
-		tx.Rollback()
-		res.FailWithMessage("删除任务关联的目标失败", c)
-		return
-	}
-
-	// 删除任务本身
-	if err := tx.Where("id = ?", id).Delete(&models.TaskModel{}).Error; err != nil {
-		tx.Rollback()
+	if err := task_ser.RemoveTask(uint(id)); err != nil {
+		global.Log.Error(err)
 		res.FailWithMessage("删除任务失败", c)
 		return
 	}
-
-	// 提交事务
-	tx.Commit()
 	res.OkWithMessage("任务删除成功", c)
 }
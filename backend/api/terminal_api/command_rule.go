@@ -0,0 +1,137 @@
+package terminal_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommandRuleRequest struct {
+	Name           string   `json:"name" binding:"required"`    // 规则名称
+	Pattern        string   `json:"pattern" binding:"required"` // 正则表达式
+	Action         string   `json:"action" binding:"required"`  // deny/confirm/alert
+	LabelIds       []uint   `json:"labelIds"`                   // 生效的主机标签
+	Roles          []string `json:"roles"`                      // 生效的用户角色
+	NotificationId uint64   `json:"notificationId"`             // 通知配置ID
+	Enabled        bool     `json:"enabled"`                    // 是否启用
+	Description    string   `json:"description"`                // 描述
+}
+
+func (cr CommandRuleRequest) validate() string {
+	if _, err := regexp.Compile(cr.Pattern); err != nil {
+		return "正则表达式错误: " + err.Error()
+	}
+	switch cr.Action {
+	case models.CommandActionDeny, models.CommandActionConfirm, models.CommandActionAlert:
+	default:
+		return "动作只能是 deny、confirm 或 alert"
+	}
+	if cr.Action == models.CommandActionAlert && cr.NotificationId == 0 {
+		return "告警动作需要选择通知配置"
+	}
+	return ""
+}
+
+func (cr CommandRuleRequest) apply(rule *models.CommandRuleModel) {
+	rule.Name = cr.Name
+	rule.Pattern = cr.Pattern
+	rule.Action = cr.Action
+	rule.LabelIds = cr.LabelIds
+	rule.Roles = cr.Roles
+	rule.NotificationId = cr.NotificationId
+	rule.Enabled = cr.Enabled
+	rule.Description = cr.Description
+}
+
+// CommandRuleListView 终端命令规则列表
+func (TerminalApi) CommandRuleListView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	var rules []models.CommandRuleModel
+	global.DB.Order("id").Find(&rules)
+	res.OkWithList(rules, int64(len(rules)), c)
+}
+
+// CommandRuleCreateView 创建终端命令规则
+func (TerminalApi) CommandRuleCreateView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	var cr CommandRuleRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithError(err, &cr, c)
+		return
+	}
+	if msg := cr.validate(); msg != "" {
+		res.FailWithMessage(msg, c)
+		return
+	}
+
+	var rule models.CommandRuleModel
+	cr.apply(&rule)
+	if err := global.DB.Create(&rule).Error; err != nil {
+		global.Log.Error(err)
+		res.FailWithMessage("创建规则失败", c)
+		return
+	}
+	res.OkWithData(rule, c)
+}
+
+// CommandRuleUpdateView 更新终端命令规则，新打开的终端会话生效
+func (TerminalApi) CommandRuleUpdateView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	var cr CommandRuleRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithError(err, &cr, c)
+		return
+	}
+	if msg := cr.validate(); msg != "" {
+		res.FailWithMessage(msg, c)
+		return
+	}
+
+	var rule models.CommandRuleModel
+	if err := global.DB.Take(&rule, c.Param("id")).Error; err != nil {
+		res.FailWithMessage("规则不存在", c)
+		return
+	}
+	cr.apply(&rule)
+	if err := global.DB.Save(&rule).Error; err != nil {
+		global.Log.Error(err)
+		res.FailWithMessage("更新规则失败", c)
+		return
+	}
+	res.OkWithMessage("更新成功", c)
+}
+
+// CommandRuleRemoveView 删除终端命令规则
+func (TerminalApi) CommandRuleRemoveView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+	if !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	if err := global.DB.Delete(&models.CommandRuleModel{}, c.Param("id")).Error; err != nil {
+		res.FailWithMessage("删除规则失败", c)
+		return
+	}
+	res.OkWithMessage("删除成功", c)
+}
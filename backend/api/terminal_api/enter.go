@@ -0,0 +1,4 @@
+package terminal_api
+
+type TerminalApi struct {
+}
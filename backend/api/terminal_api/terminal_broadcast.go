@@ -0,0 +1,100 @@
+package terminal_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/terminal_ser"
+	"ccops/utils"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// 单次广播的主机数上限
+const maxBroadcastHosts = 50
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: utils.CheckOrigin,
+}
+
+type TerminalBroadcastRequest struct {
+	HostIds string `form:"hostIds"` // 主机ID，逗号分隔
+	LabelID uint   `form:"labelId"` // 标签下的所有主机
+	Cols    int    `form:"cols"`
+	Rows    int    `form:"rows"`
+}
+
+// TerminalBroadcastView 多主机广播终端，逐台检查终端权限，没有权限的主机会在连接后返回错误
+func (TerminalApi) TerminalBroadcastView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	var cr TerminalBroadcastRequest
+	if err := c.ShouldBindQuery(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+
+	var hostIds []uint
+	for _, s := range strings.Split(cr.HostIds, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			res.FailWithMessage("主机ID错误", c)
+			return
+		}
+		hostIds = append(hostIds, uint(id))
+	}
+	if cr.LabelID != 0 {
+		var labelHostIds []uint
+		global.DB.Table("host_labels").Where("label_model_id = ?", cr.LabelID).Pluck("host_model_id", &labelHostIds)
+		hostIds = append(hostIds, labelHostIds...)
+	}
+	if len(hostIds) == 0 {
+		res.FailWithMessage("请选择主机", c)
+		return
+	}
+
+	var hosts []models.HostModel
+	global.DB.Where("id IN ?", hostIds).Order("id").Find(&hosts)
+	if len(hosts) > maxBroadcastHosts {
+		res.FailWithMessage("主机数量超过上限 "+strconv.Itoa(maxBroadcastHosts), c)
+		return
+	}
+	var allowed, denied []models.HostModel
+	for _, host := range hosts {
+		if permission.IsTerminalPermission(claims.UserID, host.ID) {
+			allowed = append(allowed, host)
+		} else {
+			denied = append(denied, host)
+		}
+	}
+	if len(allowed) == 0 {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+
+	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("升级到 WebSocket 协议失败: %v", err)
+		return
+	}
+	terminal_ser.ServeBroadcast(ws, terminal_ser.BroadcastOptions{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		Role:     claims.Role,
+		ClientIP: c.ClientIP(),
+		Cols:     cr.Cols,
+		Rows:     cr.Rows,
+		Hosts:    allowed,
+		Denied:   denied,
+	})
+}
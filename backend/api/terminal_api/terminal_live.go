@@ -0,0 +1,60 @@
+package terminal_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/terminal_ser"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TerminalLiveListView 进行中的终端会话，用于观察或协同操作
+// 管理员可以看到全部会话，普通用户只能看到有终端权限的主机上的会话
+func (TerminalApi) TerminalLiveListView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	isAdmin := permission.IsAdmin(claims.UserID)
+	allowed := map[uint]bool{}
+	list := terminal_ser.ListSessions(func(s *terminal_ser.Session) bool {
+		if isAdmin || s.UserID == claims.UserID {
+			return true
+		}
+		ok, checked := allowed[s.Host.ID]
+		if !checked {
+			ok = permission.IsTerminalPermission(claims.UserID, s.Host.ID)
+			allowed[s.Host.ID] = ok
+		}
+		return ok
+	})
+	res.OkWithList(list, int64(len(list)), c)
+}
+
+// TerminalParticipantListView 会话的加入记录，管理员或会话创建者可查看
+func (TerminalApi) TerminalParticipantListView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		res.FailWithMessage("会话ID错误", c)
+		return
+	}
+	var session models.TerminalSessionModel
+	if err := global.DB.First(&session, id).Error; err != nil {
+		res.FailWithMessage("会话不存在", c)
+		return
+	}
+	if session.UserID != claims.UserID && !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+
+	var participants []models.TerminalParticipantModel
+	global.DB.Where("session_id = ?", session.ID).Order("join_time").Find(&participants)
+	res.OkWithList(participants, int64(len(participants)), c)
+}
@@ -0,0 +1,42 @@
+package terminal_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"fmt"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TerminalRecordingView 返回 asciicast v2 格式的会话录像，可直接交给 asciinema-player 回放。
+// 普通用户只能查看自己的会话
+func (TerminalApi) TerminalRecordingView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	var session models.TerminalSessionModel
+	if err := global.DB.Take(&session, c.Param("id")).Error; err != nil {
+		res.FailWithMessage("会话不存在", c)
+		return
+	}
+	if session.UserID != claims.UserID && !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	if session.RecordingPath == "" {
+		res.FailWithMessage("该会话没有录像", c)
+		return
+	}
+	if _, err := os.Stat(session.RecordingPath); err != nil {
+		res.FailWithMessage("录像文件不存在或已过期清理", c)
+		return
+	}
+
+	c.Header("Content-Type", "application/x-asciicast")
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=session_%d.cast", session.ID))
+	c.File(session.RecordingPath)
+}
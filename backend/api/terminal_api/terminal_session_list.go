@@ -0,0 +1,70 @@
+package terminal_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TerminalSessionListRequest struct {
+	models.PageInfo
+	UserID uint `form:"userId"` // 用户
+	HostID uint `form:"hostId"` // 主机
+	Active bool `form:"active"` // 只看进行中的会话
+
+	BroadcastID string `form:"broadcastId"` // 广播终端批次
+}
+
+// TerminalSessionListView 终端会话审计列表，普通用户只能看到自己的会话
+func (TerminalApi) TerminalSessionListView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	var cr TerminalSessionListRequest
+	if err := c.ShouldBindQuery(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	if cr.Page <= 0 {
+		cr.Page = 1
+	}
+	if cr.Limit <= 0 {
+		cr.Limit = 10
+	}
+
+	query := global.DB.Model(&models.TerminalSessionModel{})
+	if !permission.IsAdmin(claims.UserID) {
+		query = query.Where("user_id = ?", claims.UserID)
+	} else if cr.UserID != 0 {
+		query = query.Where("user_id = ?", cr.UserID)
+	}
+	if cr.HostID != 0 {
+		query = query.Where("host_id = ?", cr.HostID)
+	}
+	if cr.BroadcastID != "" {
+		query = query.Where("broadcast_id = ?", cr.BroadcastID)
+	}
+	if cr.Active {
+		query = query.Where("end_time IS NULL")
+	}
+	if cr.Key != "" {
+		query = query.Where("username LIKE ? OR host_name LIKE ? OR host_ip LIKE ? OR client_ip LIKE ?",
+			"%"+cr.Key+"%", "%"+cr.Key+"%", "%"+cr.Key+"%", "%"+cr.Key+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var sessions []models.TerminalSessionModel
+	if err := query.Order("start_time DESC").
+		Offset((cr.Page - 1) * cr.Limit).Limit(cr.Limit).
+		Find(&sessions).Error; err != nil {
+		res.FailWithMessage("查询失败", c)
+		return
+	}
+	res.OkWithList(sessions, total, c)
+}
@@ -0,0 +1,4 @@
+package tunnel_api
+
+type TunnelApi struct {
+}
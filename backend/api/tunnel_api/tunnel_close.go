@@ -0,0 +1,35 @@
+package tunnel_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/tunnel_ser"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TunnelCloseView 提前关闭端口转发，断开所有连接，申请人或管理员可操作
+func (TunnelApi) TunnelCloseView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	var tunnel models.TunnelModel
+	if err := global.DB.First(&tunnel, c.Param("id")).Error; err != nil {
+		res.FailWithMessage("端口转发不存在", c)
+		return
+	}
+	if tunnel.UserID != claims.UserID && !permission.IsAdmin(claims.UserID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	if tunnel.ClosedAt == nil {
+		global.DB.Model(&tunnel).Update("closed_at", time.Now())
+	}
+	tunnel_ser.Close(tunnel.ID)
+	global.Log.Infof("用户 %s 关闭了端口转发 %d", claims.Username, tunnel.ID)
+	res.OkWithMessage("端口转发已关闭", c)
+}
@@ -0,0 +1,52 @@
+package tunnel_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/tunnel_ser"
+	"ccops/utils/permission"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// 本地助手不是浏览器，不做来源检查，凭令牌连接
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  32 * 1024,
+	WriteBufferSize: 32 * 1024,
+}
+
+// TunnelConnectView 本地助手每接受一个 TCP 连接就建立一个 WebSocket，桥接到目标端口
+func (TunnelApi) TunnelConnectView(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		res.FailWithMessage("缺少令牌", c)
+		return
+	}
+	var tunnel models.TunnelModel
+	if err := global.DB.Where("token = ?", token).First(&tunnel).Error; err != nil {
+		res.FailWithMessage("令牌无效", c)
+		return
+	}
+	if !tunnel.Active() {
+		res.FailWithMessage("端口转发已过期或已关闭", c)
+		return
+	}
+	// 申请后终端权限可能被收回，每次连接都重新检查，没有权限时关闭已建立的连接
+	if !permission.IsTerminalPermission(tunnel.UserID, tunnel.HostID) {
+		global.DB.Model(&tunnel).Update("closed_at", time.Now())
+		tunnel_ser.Close(tunnel.ID)
+		global.Log.Warnf("用户 %d 已没有主机 %d 的终端权限，关闭端口转发 %d", tunnel.UserID, tunnel.HostID, tunnel.ID)
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+
+	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		global.Log.Errorf("升级到 WebSocket 协议失败: %v", err)
+		return
+	}
+	tunnel_ser.Serve(ws, tunnel)
+}
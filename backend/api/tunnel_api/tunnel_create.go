@@ -0,0 +1,102 @@
+package tunnel_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/service/tunnel_ser"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TunnelCreateRequest struct {
+	HostID     uint   `json:"hostId" binding:"required"`                     // 经由的主机
+	TargetHost string `json:"targetHost"`                                    // 从主机上看到的目标地址，默认 127.0.0.1
+	TargetPort int    `json:"targetPort" binding:"required,min=1,max=65535"` // 目标端口
+	Duration   int    `json:"duration"`                                      // 有效期（分钟），默认使用配置
+	Reason     string `json:"reason" binding:"required"`                     // 用途
+}
+
+type TunnelCreateResponse struct {
+	models.TunnelModel
+	Token   string `json:"token"`   // 只在创建时返回一次
+	Connect string `json:"connect"` // 本地助手连接的路径
+}
+
+// TunnelCreateView 申请端口转发，需要主机的终端权限
+func (TunnelApi) TunnelCreateView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	var cr TunnelCreateRequest
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		res.FailWithError(err, &cr, c)
+		return
+	}
+	if !permission.IsTerminalPermission(claims.UserID, cr.HostID) {
+		res.FailWithMessage("权限错误", c)
+		return
+	}
+	var host models.HostModel
+	if err := global.DB.First(&host, cr.HostID).Error; err != nil {
+		res.FailWithMessage("主机不存在", c)
+		return
+	}
+
+	conf := global.Config.Tunnel
+	if len(conf.AllowedPorts) > 0 && !containsPort(conf.AllowedPorts, cr.TargetPort) {
+		res.FailWithMessage(fmt.Sprintf("不允许转发端口 %d", cr.TargetPort), c)
+		return
+	}
+	if cr.TargetHost == "" {
+		cr.TargetHost = "127.0.0.1"
+	}
+	if cr.Duration <= 0 {
+		cr.Duration = conf.DefaultDuration
+	}
+	if conf.MaxDuration > 0 && cr.Duration > conf.MaxDuration {
+		res.FailWithMessage(fmt.Sprintf("有效期不能超过 %d 分钟", conf.MaxDuration), c)
+		return
+	}
+	if cr.Duration <= 0 {
+		cr.Duration = 60
+	}
+
+	tunnel := models.TunnelModel{
+		UserID:     claims.UserID,
+		Username:   claims.Username,
+		HostID:     host.ID,
+		HostName:   host.Name,
+		TargetHost: cr.TargetHost,
+		TargetPort: cr.TargetPort,
+		Token:      tunnel_ser.NewToken(),
+		Reason:     cr.Reason,
+		ClientIP:   c.ClientIP(),
+		ExpiresAt:  time.Now().Add(time.Duration(cr.Duration) * time.Minute),
+	}
+	if err := global.DB.Create(&tunnel).Error; err != nil {
+		res.FailWithMessage("创建端口转发失败", c)
+		return
+	}
+	global.Log.Infof("用户 %s 申请端口转发 %s -> %s:%d，有效期 %d 分钟，用途: %s",
+		claims.Username, host.Name, cr.TargetHost, cr.TargetPort, cr.Duration, cr.Reason)
+
+	res.OkWithData(TunnelCreateResponse{
+		TunnelModel: tunnel,
+		Token:       tunnel.Token,
+		Connect:     "/api/tunnels/connect?token=" + tunnel.Token,
+	}, c)
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package tunnel_api
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/res"
+	"ccops/utils/jwts"
+	"ccops/utils/permission"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TunnelListRequest struct {
+	models.PageInfo
+	HostID uint `form:"hostId"` // 主机
+	Active bool `form:"active"` // 只看仍可使用的转发
+}
+
+// TunnelListView 端口转发列表，普通用户只能看到自己的
+func (TunnelApi) TunnelListView(c *gin.Context) {
+	_claims, _ := c.Get("claims")
+	claims := _claims.(*jwts.CustomClaims)
+
+	var cr TunnelListRequest
+	if err := c.ShouldBindQuery(&cr); err != nil {
+		res.FailWithCode(res.ArgumentError, c)
+		return
+	}
+	if cr.Page <= 0 {
+		cr.Page = 1
+	}
+	if cr.Limit <= 0 {
+		cr.Limit = 10
+	}
+
+	query := global.DB.Model(&models.TunnelModel{})
+	if !permission.IsAdmin(claims.UserID) {
+		query = query.Where("user_id = ?", claims.UserID)
+	}
+	if cr.HostID != 0 {
+		query = query.Where("host_id = ?", cr.HostID)
+	}
+	if cr.Active {
+		query = query.Where("closed_at IS NULL AND expires_at > ?", time.Now())
+	}
+	if cr.Key != "" {
+		query = query.Where("username LIKE ? OR host_name LIKE ? OR reason LIKE ?", "%"+cr.Key+"%", "%"+cr.Key+"%", "%"+cr.Key+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var list []models.TunnelModel
+	if err := query.Order("id DESC").
+		Offset((cr.Page - 1) * cr.Limit).Limit(cr.Limit).
+		Find(&list).Error; err != nil {
+		res.FailWithMessage("查询失败", c)
+		return
+	}
+	res.OkWithList(list, total, c)
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type AssignPermissionReq struct {
@@ -54,6 +55,10 @@ func (UserApi) AssignPermission(c *gin.Context) {
 		for _, hostId := range cr.Permissions.HostIds {
 			newMap[hostId] = true
 		}
+		// 有终端权限的主机同时具有查看权限
+		for _, hostId := range cr.Permissions.TerminalHostIds {
+			newMap[hostId] = true
+		}
 
 		// 找出需要删除的权限
 		var toDelete []uint
@@ -109,6 +114,9 @@ func (UserApi) AssignPermission(c *gin.Context) {
 		for _, labelId := range cr.Permissions.LabelIds {
 			newLabelMap[labelId] = true
 		}
+		for _, labelId := range cr.Permissions.TerminalLabelIds {
+			newLabelMap[labelId] = true
+		}
 
 		// 找出需要删除的标签
 		var toDeleteLabels []uint
@@ -148,6 +156,13 @@ func (UserApi) AssignPermission(c *gin.Context) {
 			}
 		}
 
+		// 更新终端权限
+		if err := updateTerminalPermission(tx, uint(userId), cr.Permissions); err != nil {
+			tx.Rollback()
+			res.FailWithMessage("更新终端权限失败", c)
+			return
+		}
+
 		// 更新用户角色
 		if err := tx.Model(&models.UserModel{}).
 			Where("id = ?", userId).
@@ -165,3 +180,28 @@ func (UserApi) AssignPermission(c *gin.Context) {
 	}
 	res.FailWithMessage("未知用户类型", c)
 }
+
+// updateTerminalPermission 按请求重新设置主机和标签上的终端权限
+func updateTerminalPermission(tx *gorm.DB, userId uint, permissions models.UserPermission) error {
+	if err := tx.Model(&models.HostPermission{}).Where("user_id = ?", userId).Update("terminal", false).Error; err != nil {
+		return err
+	}
+	if len(permissions.TerminalHostIds) > 0 {
+		if err := tx.Model(&models.HostPermission{}).
+			Where("user_id = ? AND host_id IN ?", userId, permissions.TerminalHostIds).
+			Update("terminal", true).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&models.UserLabels{}).Where("user_id = ?", userId).Update("terminal", false).Error; err != nil {
+		return err
+	}
+	if len(permissions.TerminalLabelIds) > 0 {
+		if err := tx.Model(&models.UserLabels{}).
+			Where("user_id = ? AND label_id IN ?", userId, permissions.TerminalLabelIds).
+			Update("terminal", true).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
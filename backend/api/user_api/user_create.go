@@ -100,13 +100,15 @@ func (UserApi) UserCreate(c *gin.Context) {
 		return
 	}
 
-	// 分配主机权限
-	if len(req.Permissions.HostIds) > 0 {
+	// 分配主机权限，有终端权限的主机同时具有查看权限
+	hostIds, terminalHosts := mergeTerminalIds(req.Permissions.HostIds, req.Permissions.TerminalHostIds)
+	if len(hostIds) > 0 {
 		var toAdd []models.HostPermission
-		for _, hostId := range req.Permissions.HostIds {
+		for _, hostId := range hostIds {
 			toAdd = append(toAdd, models.HostPermission{
-				UserId: newUser.ID,
-				HostId: hostId,
+				UserId:   newUser.ID,
+				HostId:   hostId,
+				Terminal: terminalHosts[hostId],
 			})
 		}
 		if err := tx.Create(&toAdd).Error; err != nil {
@@ -117,12 +119,14 @@ func (UserApi) UserCreate(c *gin.Context) {
 	}
 
 	// 分配标签权限
-	if len(req.Permissions.LabelIds) > 0 {
+	labelIds, terminalLabels := mergeTerminalIds(req.Permissions.LabelIds, req.Permissions.TerminalLabelIds)
+	if len(labelIds) > 0 {
 		var toAddLabels []models.UserLabels
-		for _, labelId := range req.Permissions.LabelIds {
+		for _, labelId := range labelIds {
 			toAddLabels = append(toAddLabels, models.UserLabels{
-				UserID:  newUser.ID,
-				LabelID: labelId,
+				UserID:   newUser.ID,
+				LabelID:  labelId,
+				Terminal: terminalLabels[labelId],
 			})
 		}
 		if err := tx.Create(&toAddLabels).Error; err != nil {
@@ -152,3 +156,12 @@ func (UserApi) UserCreate(c *gin.Context) {
 
 	res.Ok(user, "用户创建成功", c)
 }
+
+// mergeTerminalIds 合并查看权限和终端权限的ID，返回去重后的ID和有终端权限的ID集合
+func mergeTerminalIds(ids []uint, terminalIds []uint) ([]uint, map[uint]bool) {
+	terminal := make(map[uint]bool)
+	for _, id := range terminalIds {
+		terminal[id] = true
+	}
+	return models.RemoveDuplicatesUint(append(append([]uint{}, ids...), terminalIds...)), terminal
+}
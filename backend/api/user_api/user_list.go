@@ -54,11 +54,12 @@ func (UserApi) UserList(c *gin.Context) {
 
 	// 批量查询用户-主机关联
 	var hostPermissions []struct {
-		UserID uint
-		HostID uint `gorm:"column:id"`
+		UserID   uint
+		HostID   uint `gorm:"column:id"`
+		Terminal bool
 	}
 	global.DB.Table("host_permissions").
-		Select("host_permissions.user_id, host_models.id").
+		Select("host_permissions.user_id, host_models.id, host_permissions.terminal").
 		Joins("LEFT JOIN host_models ON host_models.id = host_permissions.host_id").
 		Where("host_permissions.user_id IN ?", userIds).
 		Scan(&hostPermissions)
@@ -73,11 +74,12 @@ func (UserApi) UserList(c *gin.Context) {
 
 	// 批量查询用户-标签关联
 	var labelPermissions []struct {
-		UserID  uint
-		LabelID uint `gorm:"column:id"`
+		UserID   uint
+		LabelID  uint `gorm:"column:id"`
+		Terminal bool
 	}
 	global.DB.Table("user_labels").
-		Select("user_labels.user_id, label_models.id").
+		Select("user_labels.user_id, label_models.id, user_labels.terminal").
 		Joins("LEFT JOIN label_models ON label_models.id = user_labels.label_id").
 		Where("user_labels.user_id IN ?", userIds).
 		Scan(&labelPermissions)
@@ -86,19 +88,27 @@ func (UserApi) UserList(c *gin.Context) {
 	userPermissionsMap := make(map[uint]*models.UserPermission)
 	for _, user := range users {
 		userPermissionsMap[user.ID] = &models.UserPermission{
-			HostIds:  make([]uint, 0),
-			LabelIds: make([]uint, 0),
+			HostIds:          make([]uint, 0),
+			LabelIds:         make([]uint, 0),
+			TerminalHostIds:  make([]uint, 0),
+			TerminalLabelIds: make([]uint, 0),
 		}
 	}
 
 	// 填充主机权限
 	for _, hp := range hostPermissions {
 		userPermissionsMap[hp.UserID].HostIds = append(userPermissionsMap[hp.UserID].HostIds, hp.HostID)
+		if hp.Terminal {
+			userPermissionsMap[hp.UserID].TerminalHostIds = append(userPermissionsMap[hp.UserID].TerminalHostIds, hp.HostID)
+		}
 	}
 
 	// 填充标签权限
 	for _, lp := range labelPermissions {
 		userPermissionsMap[lp.UserID].LabelIds = append(userPermissionsMap[lp.UserID].LabelIds, lp.LabelID)
+		if lp.Terminal {
+			userPermissionsMap[lp.UserID].TerminalLabelIds = append(userPermissionsMap[lp.UserID].TerminalLabelIds, lp.LabelID)
+		}
 	}
 
 	// 更新用户权限
@@ -106,8 +116,10 @@ func (UserApi) UserList(c *gin.Context) {
 		if users[i].Role == "系统管理员" {
 			// 系统管理员拥有所有主机和标签权限
 			users[i].Permissions = models.UserPermission{
-				HostIds:  allHostIds,
-				LabelIds: allLabelIds,
+				HostIds:          allHostIds,
+				LabelIds:         allLabelIds,
+				TerminalHostIds:  allHostIds,
+				TerminalLabelIds: allLabelIds,
 			}
 		} else {
 			// 普通用户使用查询到的权限
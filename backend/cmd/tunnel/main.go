@@ -0,0 +1,134 @@
+// 端口转发本地助手：在本地监听端口，每个 TCP 连接通过 WebSocket 桥接到平台上申请的端口转发
+//
+//	go run ./cmd/tunnel -server https://ops.example.com -token <令牌> -listen 127.0.0.1:13306
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func main() {
+	server := flag.String("server", "http://127.0.0.1:8080", "平台地址")
+	token := flag.String("token", "", "申请端口转发时返回的令牌")
+	listen := flag.String("listen", "127.0.0.1:0", "本地监听地址")
+	insecure := flag.Bool("insecure", false, "允许使用 ws:// 连接非本机的平台地址")
+	flag.Parse()
+
+	if *token == "" {
+		log.Fatal("缺少 -token")
+	}
+	endpoint, err := connectURL(*server, *token)
+	if err != nil {
+		log.Fatalf("平台地址错误: %v", err)
+	}
+	if endpoint.Scheme == "ws" && !*insecure && !isLoopback(endpoint.Hostname()) {
+		log.Fatal("令牌会以明文传输，请使用 https 平台地址，或指定 -insecure")
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatalf("监听失败: %v", err)
+	}
+	log.Printf("正在监听 %s，连接该地址即可访问转发的目标端口", listener.Addr())
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Fatalf("接受连接失败: %v", err)
+		}
+		go bridge(conn, endpoint.String())
+	}
+}
+
+// connectURL 把平台地址转换为 WebSocket 连接地址
+func connectURL(server, token string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimRight(server, "/"))
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "https", "wss":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	u.Path += "/api/tunnels/connect"
+	u.RawQuery = url.Values{"token": {token}}.Encode()
+	return u, nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// bridge 在 TCP 连接和 WebSocket 之间双向复制数据
+func bridge(conn net.Conn, endpoint string) {
+	defer conn.Close()
+
+	dialer := websocket.Dialer{HandshakeTimeout: 15 * time.Second}
+	ws, resp, err := dialer.Dial(endpoint, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			log.Printf("连接平台失败: %s %s", resp.Status, body)
+			return
+		}
+		log.Printf("连接平台失败: %v", err)
+		return
+	}
+	defer ws.Close()
+	log.Printf("%s 已连接", conn.RemoteAddr())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			messageType, reader, err := ws.NextReader()
+			if err != nil {
+				if ce, ok := err.(*websocket.CloseError); ok && ce.Code != websocket.CloseNormalClosure {
+					log.Printf("平台关闭了连接: %s", ce.Text)
+				}
+				conn.Close()
+				return
+			}
+			if messageType != websocket.BinaryMessage {
+				continue
+			}
+			if _, err := io.Copy(conn, reader); err != nil {
+				return
+			}
+		}
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			if werr := ws.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(5*time.Second))
+	// 等待平台确认关闭，超时后直接断开
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	<-done
+	log.Printf("%s 已断开", conn.RemoteAddr())
+}
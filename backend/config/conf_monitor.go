@@ -0,0 +1,45 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Monitor struct {
+	DataDir         string `yaml:"data_dir"`         // 时序数据目录，为空时只在内存中保留 24 小时
+	Retention       string `yaml:"retention"`        // 原始数据保留时间，支持 d（天）、w（周）、h（小时），如 30d、4w
+	RollupRetention string `yaml:"rollup_retention"` // 降采样数据（1m/5m/1h）保留时间，格式同上
+	BlockDuration   int    `yaml:"block_duration"`   // 每个数据块覆盖的时间（小时）
+	OutOfOrder      int    `yaml:"out_of_order"`     // 数据块落盘前等待迟到数据的时间（分钟）
+}
+
+// RetentionDuration 解析原始数据保留时间，格式错误或未配置时为 30 天
+func (m Monitor) RetentionDuration() time.Duration {
+	return parseRetention(m.Retention, 30*24*time.Hour)
+}
+
+// RollupRetentionDuration 解析降采样数据保留时间，格式错误或未配置时为 1 年
+func (m Monitor) RollupRetentionDuration() time.Duration {
+	return parseRetention(m.RollupRetention, 365*24*time.Hour)
+}
+
+func parseRetention(s string, defaultRetention time.Duration) time.Duration {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return defaultRetention
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return defaultRetention
+	}
+	switch s[len(s)-1] {
+	case 'h':
+		return time.Duration(n) * time.Hour
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour
+	}
+	return defaultRetention
+}
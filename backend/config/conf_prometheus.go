@@ -0,0 +1,17 @@
+package config
+
+type Prometheus struct {
+	Enabled             bool                `yaml:"enabled"`               // 开启 /metrics、远程写入接收和查询接口
+	Token               string              `yaml:"token"`                 // 访问令牌，Bearer 令牌或 Basic 认证的密码，开启时必须配置
+	StaleSeconds        int                 `yaml:"stale_seconds"`         // /metrics 不输出超过该时间未上报的主机（秒）
+	RemoteWriteInterval int                 `yaml:"remote_write_interval"` // 向远程写入目标推送的间隔（秒）
+	RemoteWrite         []RemoteWriteTarget `yaml:"remote_write"`          // 远程写入目标，为空时不推送
+}
+
+// RemoteWriteTarget Prometheus 远程写入目标
+type RemoteWriteTarget struct {
+	URL         string `yaml:"url"`
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+	BearerToken string `yaml:"bearer_token"`
+}
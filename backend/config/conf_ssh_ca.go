@@ -0,0 +1,8 @@
+package config
+
+type SSHCA struct {
+	Enabled    bool `yaml:"enabled"`     // 使用平台 CA 签发的短期证书登录主机
+	SessionTTL int  `yaml:"session_ttl"` // 终端、文件管理、端口转发证书有效期（分钟），只在建立连接时校验
+	TaskTTL    int  `yaml:"task_ttl"`    // 任务证书有效期（分钟），需覆盖任务的执行时间
+	StaticKey  bool `yaml:"static_key"`  // 继续使用旧的静态密钥，关闭后 agent 会从 authorized_keys 中移除
+}
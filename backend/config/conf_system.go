@@ -3,9 +3,14 @@ package config
 import "fmt"
 
 type System struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
-	Env  string `yaml:"env"`
+	Host           string   `yaml:"host"`
+	Port           int      `yaml:"port"`
+	Env            string   `yaml:"env"`
+	AllowedOrigins []string `yaml:"allowed_origins"` // WebSocket 允许的来源，为空时只允许同源，"*" 允许所有来源
+	TrustedProxies []string `yaml:"trusted_proxies"` // 信任的反向代理地址，只有来自这些地址的 X-Forwarded-For、X-Real-IP 才会被采用
+	AgentToken     string   `yaml:"agent_token"`     // agent 认证令牌，agent 以 -token 参数配置；为空时不下发用户证书主体，agent 上报的主机密钥也不会被直接信任
+
+	HostKeyNotificationId uint64 `yaml:"host_key_notification_id"` // 出现未信任的主机密钥时发送告警的通知ID，0 表示只生成告警记录
 }
 
 func (s System) Addr() string {
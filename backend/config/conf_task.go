@@ -0,0 +1,7 @@
+package config
+
+type Task struct {
+	RetentionDays int    `yaml:"retention_days"` // 任务保留天数，0 表示不清理
+	RetentionMode string `yaml:"retention_mode"` // 过期任务处理方式：archive 归档后删除，delete 直接删除
+	ArchiveDir    string `yaml:"archive_dir"`    // 归档目录
+}
@@ -0,0 +1,12 @@
+package config
+
+type Terminal struct {
+	RecordingDir           string `yaml:"recording_dir"`            // 终端录像目录，为空时不录像
+	RecordingMaxSize       int64  `yaml:"recording_max_size"`       // 单个录像最大大小（MB），超出后停止录制，0 表示不限制
+	RecordingRetentionDays int    `yaml:"recording_retention_days"` // 录像保留天数，0 表示不清理
+	IdleTimeout            int    `yaml:"idle_timeout"`             // 无输入自动断开的时间（分钟），0 表示不断开
+	KeepaliveInterval      int    `yaml:"keepalive_interval"`       // SSH 保活间隔（秒），0 表示不发送
+	ReconnectGrace         int    `yaml:"reconnect_grace"`          // 浏览器断开后保留 SSH 会话等待重连的时间（秒）
+	SftpMaxUpload          int64  `yaml:"sftp_max_upload"`          // 文件管理单个文件上传上限（MB），0 表示不限制
+	SftpMaxDownload        int64  `yaml:"sftp_max_download"`        // 文件管理单个文件下载上限（MB），0 表示不限制
+}
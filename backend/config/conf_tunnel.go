@@ -0,0 +1,7 @@
+package config
+
+type Tunnel struct {
+	DefaultDuration int   `yaml:"default_duration"` // 默认有效期（分钟）
+	MaxDuration     int   `yaml:"max_duration"`     // 最长有效期（分钟）
+	AllowedPorts    []int `yaml:"allowed_ports"`    // 允许转发的目标端口，为空时不限制
+}
@@ -1,8 +1,14 @@
 package config
 
 type Config struct {
-	Mysql  Mysql  `yaml:"mysql"`
-	Logger Logger `yaml:"logger"`
-	System System `yaml:"system"`
-	Jwt    Jwt    `yaml:"jwt"`
+	Mysql      Mysql      `yaml:"mysql"`
+	Logger     Logger     `yaml:"logger"`
+	System     System     `yaml:"system"`
+	Jwt        Jwt        `yaml:"jwt"`
+	Task       Task       `yaml:"task"`
+	Terminal   Terminal   `yaml:"terminal"`
+	Tunnel     Tunnel     `yaml:"tunnel"`
+	SSHCA      SSHCA      `yaml:"ssh_ca"`
+	Monitor    Monitor    `yaml:"monitor"`
+	Prometheus Prometheus `yaml:"prometheus"`
 }
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"ccops/global"
+	"ccops/models"
+)
+
+// InitCommandRules 初始化默认的终端高危命令规则，已有规则时不处理
+func InitCommandRules() {
+	var count int64
+	global.DB.Model(&models.CommandRuleModel{}).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	rules := []models.CommandRuleModel{
+		{
+			Name:        "删除根目录",
+			Pattern:     `\brm\s+(-\S+\s+)*/\*?(\s|$)`,
+			Action:      models.CommandActionDeny,
+			Enabled:     true,
+			Description: "rm -rf / 及 rm -rf /*",
+		},
+		{
+			Name:        "Fork 炸弹",
+			Pattern:     `:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`,
+			Action:      models.CommandActionDeny,
+			Enabled:     true,
+			Description: ":(){ :|:& };:",
+		},
+		{
+			Name:        "格式化磁盘",
+			Pattern:     `\bmkfs(\.\w+)?\b`,
+			Action:      models.CommandActionConfirm,
+			Enabled:     true,
+			Description: "mkfs / mkfs.ext4 等",
+		},
+		{
+			Name:        "覆写块设备",
+			Pattern:     `\bdd\b.*\bof=/dev/`,
+			Action:      models.CommandActionConfirm,
+			Enabled:     true,
+			Description: "dd 写入块设备",
+		},
+		{
+			Name:        "关机重启",
+			Pattern:     `\b(shutdown|reboot|halt|poweroff)\b|\binit\s+[06]\b`,
+			Action:      models.CommandActionConfirm,
+			Enabled:     true,
+			Description: "shutdown / reboot / halt / poweroff / init 0 / init 6",
+		},
+	}
+	if err := global.DB.Create(&rules).Error; err != nil {
+		global.Log.Errorf("初始化终端命令规则失败: %v", err)
+	}
+}
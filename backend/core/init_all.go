@@ -5,10 +5,14 @@ func InitAll() error {
 	InitAIConfiguration()
 	InitSystemConfiguration()
 	InitUser()
+	InitCommandRules()
 	err := InitKeysConfiguration()
 	if err != nil {
 		return err
 	}
+	if err := InitSSHCA(); err != nil {
+		return err
+	}
 	return nil
 
 }
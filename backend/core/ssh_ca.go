@@ -0,0 +1,56 @@
+package core
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// InitSSHCA 初始化签发 SSH 证书的 CA 密钥，数据库中已存在时直接使用
+func InitSSHCA() error {
+	if global.Config.SSHCA.Enabled && global.Config.System.AgentToken == "" {
+		global.Log.Warnln("未配置 agent_token，agent 不会收到用户证书主体，用户证书无法登录主机")
+	}
+	var count int64
+	global.DB.Model(&models.Configuration{}).
+		Where("type = ? AND field_name IN ?", models.ConfigurationTypeKey, []string{"CAPrivateKey", "CAPublicKey"}).
+		Count(&count)
+	if count == 2 {
+		return nil
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return errors.New("生成 CA 密钥失败")
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "ccops-ca@cc.ops")
+	if err != nil {
+		return errors.New("编码 CA 私钥失败")
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return errors.New("编码 CA 公钥失败")
+	}
+
+	global.DB.Where("type = ? AND field_name IN ?", models.ConfigurationTypeKey, []string{"CAPrivateKey", "CAPublicKey"}).
+		Delete(&models.Configuration{})
+	global.DB.Create(&models.Configuration{
+		Type:             models.ConfigurationTypeKey,
+		FieldName:        "CAPublicKey",
+		FieldValue:       string(ssh.MarshalAuthorizedKey(sshPub)),
+		FieldDescription: "SSH CA 公钥",
+	})
+	global.DB.Create(&models.Configuration{
+		Type:             models.ConfigurationTypeKey,
+		FieldName:        "CAPrivateKey",
+		FieldValue:       string(pem.EncodeToMemory(block)),
+		FieldDescription: "SSH CA 私钥",
+	})
+	global.Log.Info("成功生成 SSH CA 密钥并保存到数据库")
+	return nil
+}
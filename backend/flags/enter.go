@@ -8,6 +8,8 @@ type Option struct {
 	DB   bool
 	Dump bool   // 导出数据库
 	Load string // 导入数据库文件
+	List bool   // 输出 ansible 动态 inventory
+	Host string // 输出 ansible 单个主机变量
 }
 
 // Parse 解析命令行参数
@@ -17,6 +19,8 @@ func Parse() (option *Option) {
 	flag.BoolVar(&option.DB, "db", false, "初始化数据库") //只要执行-db 默认转为true
 	flag.BoolVar(&option.Dump, "dump", false, "导出sql数据库")
 	flag.StringVar(&option.Load, "load", "", "导入sql数据库")
+	flag.BoolVar(&option.List, "list", false, "输出ansible动态inventory")
+	flag.StringVar(&option.Host, "host", "", "输出ansible单个主机变量")
 	flag.Parse()
 	return option
 }
@@ -35,5 +39,9 @@ func (option Option) Run() bool {
 		Load(option.Load)
 		return true
 	}
+	if option.List || option.Host != "" {
+		Inventory(option)
+		return true
+	}
 	return false
 }
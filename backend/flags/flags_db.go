@@ -35,6 +35,16 @@ func DB() {
 			&models.SystemUserModel{},
 			&models.HostPermission{},
 			&models.UserLabels{},
+			&models.JumpHostModel{},
+			&models.TaskOutputChunkModel{},
+			&models.TerminalSessionModel{},
+			&models.TerminalParticipantModel{},
+			&models.FileOperationModel{},
+			&models.HostKeyModel{},
+			&models.TunnelModel{},
+			&models.SSHCertificateModel{},
+			&models.CommandRuleModel{},
+			&models.AgentConfigModel{},
 			&alert.AlertRecord{},
 			&alert.AlertRule{},
 			&alert.AlertRuleTarget{},
@@ -0,0 +1,34 @@
+package flags
+
+import (
+	"ccops/global"
+	"ccops/service/inventory_ser"
+	"encoding/json"
+	"os"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Inventory 按 ansible 动态 inventory 脚本的约定输出 JSON，stdout 上只能有 JSON 内容
+func Inventory(option Option) {
+	global.Log.SetOutput(os.Stderr)
+	global.DB = global.DB.Session(&gorm.Session{Logger: logger.Discard})
+
+	var data any
+	var err error
+	if option.Host != "" {
+		data, err = inventory_ser.Host(nil, option.Host)
+	} else {
+		data, err = inventory_ser.Build(nil)
+	}
+	if err != nil {
+		global.Log.Fatalln(err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		global.Log.Fatalln(err)
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"ccops/models/monitor"
 	"ccops/router"
 	"ccops/service/alert"
+	"ccops/service/cron_ser"
+	"ccops/service/metrics_ser"
 	utils "ccops/utils"
 	"fmt"
+	"time"
 )
 
 func main() {
@@ -30,12 +33,28 @@ func main() {
 	if err != nil {
 		fmt.Println(err)
 	}
-	// 添加 TimeSeriesDB 实例
-	global.TimeSeriesDB = monitor.NewTimeSeriesDB()
+	// 添加 TimeSeriesDB 实例，配置了数据目录时持久化到磁盘
+	tsdb, err := monitor.Open(monitor.Options{
+		Dir:             global.Config.Monitor.DataDir,
+		Retention:       global.Config.Monitor.RetentionDuration(),
+		RollupRetention: global.Config.Monitor.RollupRetentionDuration(),
+		BlockDuration:   time.Duration(global.Config.Monitor.BlockDuration) * time.Hour,
+		OutOfOrder:      time.Duration(global.Config.Monitor.OutOfOrder) * time.Minute,
+	})
+	if err != nil {
+		global.Log.Fatalf("打开时序数据库失败: %v", err)
+	}
+	global.TimeSeriesDB = tsdb
+	// 向配置的 Prometheus 远程写入目标推送指标
+	metrics_ser.StartRemoteWrite()
 
 	// 启动告警定时任务
 	alert.StartCronTasks()
 
+	// 启动任务保留策略定时任务
+	cron_ser.StartTaskRetention()
+	cron_ser.StartRecordingRetention()
+
 	// 初始化路由
 	router := router.InitRouter()
 
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"ccops/global"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrometheusAuth Prometheus 兼容接口的校验：未开启时返回 404，校验 Bearer 令牌或 Basic 认证的密码；
+// 开启后必须配置令牌，否则拒绝所有请求，避免远程写入和查询接口对外开放
+func PrometheusAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf := global.Config.Prometheus
+		if !conf.Enabled {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"status": "error", "errorType": "not_found", "error": "prometheus 接口未开启"})
+			return
+		}
+		if conf.Token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "error", "errorType": "forbidden", "error": "prometheus 接口未配置访问令牌"})
+			return
+		}
+		token := extractToken(c)
+		if token == "" {
+			_, token, _ = c.Request.BasicAuth()
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(conf.Token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "error", "errorType": "unauthorized", "error": "令牌错误"})
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package models
+
+import "gorm.io/datatypes"
+
+// agent 日志级别
+const (
+	AgentLogDebug = "debug"
+	AgentLogInfo  = "info"
+	AgentLogWarn  = "warn"
+	AgentLogError = "error"
+)
+
+// AgentConfigSpec 下发给 agent 的配置，覆盖 agent 本地配置文件中的同名项；
+// 为 null 的项保留 agent 本地的配置，间隔为 0 时同样保留
+type AgentConfigSpec struct {
+	CollectInterval   int    `json:"collectInterval"`   // 系统指标采集间隔（秒）
+	ReportInterval    int    `json:"reportInterval"`    // 主机信息上报间隔（秒）
+	PublicKeyInterval int    `json:"publicKeyInterval"` // 公钥和 SSH CA 检查间隔（秒）
+	LogLevel          string `json:"logLevel"`          // 日志级别 debug/info/warn/error，为空时保留
+
+	Collectors struct {
+		CPU     *bool `json:"cpu"`
+		Memory  *bool `json:"memory"`
+		Disk    *bool `json:"disk"`
+		Network *bool `json:"network"`
+		Process *bool `json:"process"`
+	} `json:"collectors"` // 启用的采集项
+
+	Disk struct {
+		IgnoredFSTypes        []string `json:"ignoredFsTypes"`        // 不采集的文件系统类型
+		IgnoredDevicePrefixes []string `json:"ignoredDevicePrefixes"` // 不采集IO的块设备名前缀
+	} `json:"disk"`
+
+	Network struct {
+		IgnoreLoopback    *bool    `json:"ignoreLoopback"`    // 忽略回环网卡
+		IgnoreDown        *bool    `json:"ignoreDown"`        // 忽略未启用的网卡
+		IgnoredInterfaces []string `json:"ignoredInterfaces"` // 不采集的网卡名前缀，如 veth、docker
+	} `json:"network"`
+
+	Process *AgentProcessConfig `json:"process"` // 进程采集和进程监控列表
+}
+
+// AgentProcessConfig 进程采集配置，与 agent 配置文件的 process 项一致，是否启用由 Collectors.Process 决定
+type AgentProcessConfig struct {
+	TopN  int                 `json:"topN"`  // 分别按CPU和内存取前 N 个进程上报
+	Watch []AgentProcessWatch `json:"watch"` // 进程监控列表
+}
+
+// AgentProcessWatch 进程监控项，Process 和 Cmdline 都配置时需同时满足
+type AgentProcessWatch struct {
+	Name    string `json:"name"`    // 监控项名称，告警规则按此名称匹配
+	Process string `json:"process"` // 进程名，精确匹配
+	Cmdline string `json:"cmdline"` // 命令行正则表达式
+}
+
+// AgentConfigModel agent 配置。一台主机匹配多个配置时，指定主机的优先于指定标签的，
+// 指定标签的优先于对所有主机生效的，同一层级中 Priority 大的优先
+type AgentConfigModel struct {
+	MODEL
+	Name        string                              `gorm:"size:128;comment:配置名称" json:"name"`         // 配置名称
+	HostIds     datatypes.JSONSlice[uint]           `gorm:"type:json;comment:生效的主机" json:"hostIds"`    // 生效的主机
+	LabelIds    datatypes.JSONSlice[uint]           `gorm:"type:json;comment:生效的主机标签" json:"labelIds"` // 生效的主机标签，与主机都为空时对所有主机生效
+	Priority    int                                 `gorm:"default:0;comment:优先级" json:"priority"`     // 同一层级中大的优先
+	Config      datatypes.JSONType[AgentConfigSpec] `gorm:"type:json;comment:配置内容" json:"config"`      // 配置内容
+	Enabled     bool                                `gorm:"default:true;comment:是否启用" json:"enabled"`  // 是否启用
+	Description string                              `gorm:"size:512;comment:描述" json:"description"`    // 描述
+}
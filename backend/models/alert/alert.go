@@ -20,6 +20,7 @@ type AlertRule struct {
 	Duration       int       `json:"duration"`                       // 持续时间(秒)
 	Operator       string    `json:"operator" gorm:"size:2"`         // 运算符(>, <, >=, <=, ==)
 	Threshold      float64   `json:"threshold"`                      // 阈值
+	Target         string    `json:"target" gorm:"size:255"`         // 指标对象，进程类规则为进程监控列表中的名称，分区和 inode 规则为挂载点，单网卡规则为网卡名，自定义指标规则为序列名
 	RecoverNotify  bool      `json:"recoverNotify"`                  // 是否发送恢复通知
 	NotificationId uint64    `json:"notificationId"`                 // 通知ID
 }
@@ -56,29 +57,49 @@ const (
 	RuleTypeCPULoad5  = "load5"  // 5分钟负载
 	RuleTypeCPULoad15 = "load15" // 15分钟负载
 
+	RuleTypeCPUIowait       = "cpu_iowait"       // IO等待占比
+	RuleTypeCPUSteal        = "cpu_steal"        // 被虚拟化宿主占用的占比
+	RuleTypeContextSwitches = "context_switches" // 每秒上下文切换次数
+
 	// 内存指标
 	RuleTypeMemoryUsage     = "memory"       // 内存使用率
 	RuleTypeMemoryAvailable = "memory_avail" // 可用内存
 	RuleTypeMemoryFree      = "memory_free"  // 空闲内存
+	RuleTypeSwapUsage       = "swap_usage"   // 交换分区使用率
 
 	// 磁盘指标
 	RuleTypeDiskUsage   = "disk_usage"  // 磁盘使用率
 	RuleTypeDiskFree    = "disk_free"   // 磁盘剩余空间
 	RuleTypeDiskReadIO  = "disk_read"   // 磁盘读取速率
 	RuleTypeDiskWriteIO = "disk_write"  // 磁盘写入速率
-	RuleTypeDiskVolume  = "disk_volume" // 分区使用率
+	RuleTypeDiskVolume  = "disk_volume" // 分区使用率，Target 为挂载点，为空时取所有分区中最高的
+	RuleTypeInodeUsage  = "inode_usage" // inode 使用率，Target 为空时取所有分区中最高的
 
 	// 网络指标
-	RuleTypeNetInSpeed      = "network_in"     // 总网络入站速度
-	RuleTypeNetOutSpeed     = "network_out"    // 总网络出站速度
-	RuleTypeNetCardInSpeed  = "netcard_in"     // 单网卡入站速度
-	RuleTypeNetCardOutSpeed = "netcard_out"    // 单网卡出站速度
-	RuleTypeNetCardStatus   = "netcard_status" // 网卡状态
+	RuleTypeNetInSpeed      = "network_in"      // 总网络入站速度
+	RuleTypeNetOutSpeed     = "network_out"     // 总网络出站速度
+	RuleTypeNetCardInSpeed  = "netcard_in"      // 单网卡入站速度，Target 为网卡名，为空时取所有网卡中最高的
+	RuleTypeNetCardOutSpeed = "netcard_out"     // 单网卡出站速度，Target 为网卡名，为空时取所有网卡中最高的
+	RuleTypeNetCardStatus   = "netcard_status"  // 网卡状态
+	RuleTypeTCPEstablished  = "tcp_established" // ESTABLISHED 连接数
+	RuleTypeTCPTimeWait     = "tcp_time_wait"   // TIME_WAIT 连接数
+	RuleTypeTCPCloseWait    = "tcp_close_wait"  // CLOSE_WAIT 连接数
 
 	// 状态指标
-	RuleTypeOnline  = "online"  // 在线状态
-	RuleTypeSSL     = "ssl"     // SSL证书过期
-	RuleTypeProcess = "process" // 进程状态
+	RuleTypeOnline    = "online"     // 在线状态
+	RuleTypeSSL       = "ssl"        // SSL证书过期
+	RuleTypeProcess   = "process"    // 进程状态（运行中的进程数，小于 1 即进程不存在）
+	RuleTypeOpenFiles = "open_files" // 系统文件描述符使用率
+	RuleTypeUptime    = "uptime"     // 运行时间（秒），小于阈值即刚重启过
+
+	// 进程指标，Target 为 agent 进程监控列表中的名称
+	RuleTypeProcessCPU    = "process_cpu"    // 进程CPU使用率（百分比，多核可超过 100）
+	RuleTypeProcessMemory = "process_memory" // 进程常驻内存(MB)
+	RuleTypeProcessFDs    = "process_fds"    // 进程打开的文件描述符数
+
+	// 自定义指标，Target 为序列名，如 queue_depth{queue="orders"}；
+	// 不带标签时匹配同名的所有序列，取最大值
+	RuleTypeCustom = "custom"
 )
 
 // 运算符常量
@@ -104,6 +125,9 @@ const (
 	AlertStatusResolved = 2 // 已恢复状态
 )
 
+// SystemAlertRuleID 不来自告警规则的系统告警（如主机密钥变化）使用的规则ID
+const SystemAlertRuleID = 0
+
 // 目标类型常量
 const (
 	TargetTypeHost  = 0 // 主机
@@ -114,15 +138,19 @@ const (
 func ValidateRule(rule *AlertRule) error {
 	validTypes := map[string]bool{
 		// CPU相关指标
-		RuleTypeCPUUsage:  true,
-		RuleTypeCPULoad1:  true,
-		RuleTypeCPULoad5:  true,
-		RuleTypeCPULoad15: true,
+		RuleTypeCPUUsage:        true,
+		RuleTypeCPULoad1:        true,
+		RuleTypeCPULoad5:        true,
+		RuleTypeCPULoad15:       true,
+		RuleTypeCPUIowait:       true,
+		RuleTypeCPUSteal:        true,
+		RuleTypeContextSwitches: true,
 
 		// 内存相关指标
 		RuleTypeMemoryUsage:     true,
 		RuleTypeMemoryAvailable: true,
 		RuleTypeMemoryFree:      true,
+		RuleTypeSwapUsage:       true,
 
 		// 磁盘相关指标
 		RuleTypeDiskUsage:   true,
@@ -130,6 +158,7 @@ func ValidateRule(rule *AlertRule) error {
 		RuleTypeDiskReadIO:  true,
 		RuleTypeDiskWriteIO: true,
 		RuleTypeDiskVolume:  true,
+		RuleTypeInodeUsage:  true,
 
 		// 网络相关指标
 		RuleTypeNetInSpeed:      true,
@@ -137,11 +166,24 @@ func ValidateRule(rule *AlertRule) error {
 		RuleTypeNetCardInSpeed:  true,
 		RuleTypeNetCardOutSpeed: true,
 		RuleTypeNetCardStatus:   true,
+		RuleTypeTCPEstablished:  true,
+		RuleTypeTCPTimeWait:     true,
+		RuleTypeTCPCloseWait:    true,
 
 		// 状态指标
-		RuleTypeOnline:  true,
-		RuleTypeSSL:     true,
-		RuleTypeProcess: true,
+		RuleTypeOnline:    true,
+		RuleTypeSSL:       true,
+		RuleTypeProcess:   true,
+		RuleTypeOpenFiles: true,
+		RuleTypeUptime:    true,
+
+		// 进程指标
+		RuleTypeProcessCPU:    true,
+		RuleTypeProcessMemory: true,
+		RuleTypeProcessFDs:    true,
+
+		// 自定义指标
+		RuleTypeCustom: true,
 	}
 
 	validPriority := map[string]bool{
@@ -174,7 +216,8 @@ func ValidateRule(rule *AlertRule) error {
 
 	// 对特定类型的规则进行阈值范围验证
 	switch rule.Type {
-	case RuleTypeCPUUsage, RuleTypeMemoryUsage, RuleTypeDiskUsage, RuleTypeDiskVolume:
+	case RuleTypeCPUUsage, RuleTypeMemoryUsage, RuleTypeDiskUsage, RuleTypeDiskVolume,
+		RuleTypeCPUIowait, RuleTypeCPUSteal, RuleTypeSwapUsage, RuleTypeInodeUsage, RuleTypeOpenFiles:
 		if rule.Threshold < 0 || rule.Threshold > 100 {
 			return fmt.Errorf("percentage threshold must be between 0 and 100")
 		}
@@ -192,6 +235,21 @@ func ValidateRule(rule *AlertRule) error {
 		if rule.Threshold < 0 {
 			return fmt.Errorf("rate threshold cannot be negative")
 		}
+	case RuleTypeContextSwitches, RuleTypeTCPEstablished, RuleTypeTCPTimeWait, RuleTypeTCPCloseWait, RuleTypeUptime:
+		if rule.Threshold < 0 {
+			return fmt.Errorf("count threshold cannot be negative")
+		}
+	case RuleTypeProcess, RuleTypeProcessCPU, RuleTypeProcessMemory, RuleTypeProcessFDs:
+		if rule.Target == "" {
+			return fmt.Errorf("process rule requires a target process name")
+		}
+		if rule.Threshold < 0 {
+			return fmt.Errorf("process threshold cannot be negative")
+		}
+	case RuleTypeCustom:
+		if rule.Target == "" {
+			return fmt.Errorf("custom rule requires a target metric name")
+		}
 	}
 
 	return nil
@@ -0,0 +1,23 @@
+package models
+
+import "gorm.io/datatypes"
+
+// 命令规则动作
+const (
+	CommandActionDeny    = "deny"    // 拦截命令
+	CommandActionConfirm = "confirm" // 需要二次确认
+	CommandActionAlert   = "alert"   // 放行并发送告警
+)
+
+// CommandRuleModel Web 终端命令过滤规则
+type CommandRuleModel struct {
+	MODEL
+	Name           string                      `gorm:"size:128;comment:规则名称" json:"name"`                   // 规则名称
+	Pattern        string                      `gorm:"size:512;comment:匹配命令的正则表达式" json:"pattern"`          // 匹配整行命令的正则表达式
+	Action         string                      `gorm:"size:16;comment:动作 deny/confirm/alert" json:"action"` // 命中后的动作
+	LabelIds       datatypes.JSONSlice[uint]   `gorm:"type:json;comment:生效的主机标签" json:"labelIds"`           // 生效的主机标签，为空时对所有主机生效
+	Roles          datatypes.JSONSlice[string] `gorm:"type:json;comment:生效的用户角色" json:"roles"`              // 生效的用户角色，为空时对所有用户生效
+	NotificationId uint64                      `gorm:"default:0;comment:通知配置ID" json:"notificationId"`      // 命中后发送通知，0 表示不通知
+	Enabled        bool                        `gorm:"default:true;comment:是否启用" json:"enabled"`            // 是否启用
+	Description    string                      `gorm:"size:512;comment:描述" json:"description"`              // 描述
+}
@@ -0,0 +1,28 @@
+package models
+
+// 文件管理操作类型
+const (
+	FileOpList     = "list"
+	FileOpStat     = "stat"
+	FileOpDownload = "download"
+	FileOpUpload   = "upload"
+	FileOpRename   = "rename"
+	FileOpRemove   = "remove"
+	FileOpChmod    = "chmod"
+)
+
+// FileOperationModel 主机文件管理（SFTP）操作审计
+type FileOperationModel struct {
+	MODEL
+	UserID    uint   `gorm:"index;comment:用户ID" json:"userId"`      // 操作用户
+	Username  string `gorm:"size:36;comment:用户名" json:"username"`   // 用户名
+	HostID    uint   `gorm:"index;comment:主机ID" json:"hostId"`      // 目标主机
+	HostName  string `gorm:"size:36;comment:主机名称" json:"hostName"`  // 主机名称
+	ClientIP  string `gorm:"size:64;comment:客户端IP" json:"clientIp"` // 客户端IP
+	Operation string `gorm:"size:16;comment:操作类型" json:"operation"` // list / stat / download / upload / rename / remove / chmod
+	Path      string `gorm:"size:1024;comment:文件路径" json:"path"`    // 操作的路径
+	Target    string `gorm:"size:1024;comment:目标" json:"target"`    // 重命名的新路径或 chmod 的权限
+	Size      int64  `gorm:"comment:传输大小" json:"size"`              // 上传或下载的字节数
+	Success   bool   `gorm:"comment:是否成功" json:"success"`           // 是否成功
+	Error     string `gorm:"size:512;comment:错误信息" json:"error"`    // 失败原因
+}
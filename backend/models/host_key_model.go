@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// 主机密钥状态
+const (
+	HostKeyTrusted = "trusted" // 已信任，连接时用于校验
+	HostKeyPending = "pending" // 与已信任的密钥不一致、新类型或未认证 agent 上报的密钥，等待管理员确认
+)
+
+// 主机密钥来源
+const (
+	HostKeySourceAgent = "agent" // agent 上报
+	HostKeySourceSSH   = "ssh"   // 首次连接时记录
+	HostKeySourceAdmin = "admin" // 管理员确认
+)
+
+// HostKeyModel 主机 SSH 主机密钥，首次见到时信任（TOFU），之后每次连接校验
+type HostKeyModel struct {
+	MODEL
+	HostID      uint      `gorm:"index;comment:主机ID" json:"hostId"`       // 主机，跳板机的密钥为 0
+	JumpHostID  uint      `gorm:"index;comment:跳板机ID" json:"jumpHostId"`  // 跳板机
+	Algorithm   string    `gorm:"size:64;comment:密钥类型" json:"algorithm"`  // 如 ssh-ed25519
+	PublicKey   string    `gorm:"type:text;comment:公钥" json:"publicKey"`  // authorized_keys 格式
+	Fingerprint string    `gorm:"size:128;comment:指纹" json:"fingerprint"` // SHA256 指纹
+	Source      string    `gorm:"size:16;comment:来源" json:"source"`       // agent / ssh / admin
+	Status      string    `gorm:"size:16;index;comment:状态" json:"status"` // trusted / pending
+	LastSeen    time.Time `gorm:"comment:最后一次见到的时间" json:"lastSeen"`      // 最后一次见到的时间
+}
@@ -63,4 +63,6 @@ type HostModel struct {
 	City     string       `gorm:"size:64;comment:城市" json:"city"`       // 公网ip
 	Org      string       `gorm:"size:64;comment:组织" json:"org"`        // 组织
 
+	JumpHostID uint `gorm:"default:0;comment:跳板机ID" json:"jumpHostId"` // 跳板机ID，0 表示直连或继承标签
+
 }
@@ -4,4 +4,6 @@ type HostPermission struct {
 	MODEL
 	HostId uint `json:"hostId" gorm:"index"` // 为HostId添加索引
 	UserId uint `json:"userId" gorm:"index"` // 为UserId添加索引
+
+	Terminal bool `json:"terminal" gorm:"default:0;comment:是否允许打开终端"` // 查看权限之外单独授予的终端权限
 }
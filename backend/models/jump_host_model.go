@@ -0,0 +1,13 @@
+package models
+
+// JumpHostModel 跳板机（堡垒机）定义，可挂载到主机或标签上
+type JumpHostModel struct {
+	MODEL
+	Name        string `gorm:"size:64;comment:跳板机名称" json:"name"`             // 跳板机名称
+	Address     string `gorm:"size:128;comment:跳板机地址" json:"address"`         // 跳板机地址
+	Port        int    `gorm:"default:22;comment:SSH端口" json:"port"`          // SSH端口
+	User        string `gorm:"size:64;default:root;comment:登录用户" json:"user"` // 登录用户
+	PrivateKey  string `gorm:"type:text;comment:私钥，为空时使用平台密钥" json:"-"`       // 私钥，为空时使用平台密钥
+	Description string `gorm:"size:255;comment:描述" json:"description"`        // 描述
+	HasKey      bool   `gorm:"-" json:"hasKey"`                               // 是否配置了独立私钥
+}
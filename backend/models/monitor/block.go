@@ -0,0 +1,435 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 数据块：一个时间窗口内所有主机的数据，文件名 <窗口起始时间>-<序号>.blk。
+// 原始数据点保存在 blocks 目录，降采样数据保存在 rollups/<粒度> 目录，格式相同。
+// 同一窗口在落盘后又收到迟到数据时会产生多个序号，后台合并为一个。
+//
+//	magic | 主机1 的 gzip 数据 | 主机2 的 gzip 数据 | ... | JSON 索引 | 8 字节索引偏移 | magic
+//
+// 每台主机的数据独立压缩，查询单台主机时只需解压对应部分。
+
+const (
+	blockDir   = "blocks"
+	blockExt   = ".blk"
+	blockMagic = "CCTSDB01"
+)
+
+// blockHostIndex 一台主机在数据块中的位置
+type blockHostIndex struct {
+	Offset  int64 `json:"offset"`
+	Length  int64 `json:"length"`
+	Count   int   `json:"count"`
+	MinTime int64 `json:"minTime"`
+	MaxTime int64 `json:"maxTime"`
+}
+
+// blockIndex 数据块索引
+type blockIndex struct {
+	Start int64                      `json:"start"` // 窗口起始时间
+	End   int64                      `json:"end"`   // 窗口结束时间（不含）
+	Hosts map[uint64]*blockHostIndex `json:"hosts"`
+}
+
+// blockMeta 已打开的数据块，索引常驻内存
+type blockMeta struct {
+	path  string
+	start int64
+	seq   int
+	index *blockIndex
+}
+
+// blockSet 一个目录下的所有数据块，按窗口起始时间、序号升序
+type blockSet struct {
+	dir  string
+	secs int64 // 每个窗口覆盖的秒数
+
+	mu     sync.RWMutex
+	blocks []*blockMeta
+}
+
+func newBlockSet(dir string, secs int64) *blockSet {
+	return &blockSet{dir: dir, secs: secs}
+}
+
+// load 加载目录下的数据块，删除崩溃时未写完的临时文件，损坏的数据块改名跳过
+func (s *blockSet) load() error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("创建时序数据目录失败: %w", err)
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(s.dir, entry.Name())
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			os.Remove(path)
+			continue
+		}
+		start, seq, ok := parseBlockName(entry.Name())
+		if !ok {
+			continue
+		}
+		index, err := readBlockIndex(path)
+		if err != nil {
+			fmt.Printf("[时序数据库] 数据块 %s 损坏，已跳过: %v\n", path, err)
+			os.Rename(path, path+".corrupt")
+			continue
+		}
+		s.blocks = append(s.blocks, &blockMeta{path: path, start: start, seq: seq, index: index})
+	}
+	s.sortLocked()
+	return nil
+}
+
+func (s *blockSet) sortLocked() {
+	sort.Slice(s.blocks, func(i, j int) bool {
+		if s.blocks[i].start != s.blocks[j].start {
+			return s.blocks[i].start < s.blocks[j].start
+		}
+		return s.blocks[i].seq < s.blocks[j].seq
+	})
+}
+
+func (s *blockSet) windowStart(ts int64) int64 {
+	return ts - ((ts%s.secs)+s.secs)%s.secs
+}
+
+// nextPath 窗口下一个数据块的路径
+func (s *blockSet) nextPath(start int64) (string, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	seq := 0
+	for _, block := range s.blocks {
+		if block.start == start && block.seq >= seq {
+			seq = block.seq + 1
+		}
+	}
+	return filepath.Join(s.dir, fmt.Sprintf("%d-%d%s", start, seq, blockExt)), seq
+}
+
+func (s *blockSet) add(block *blockMeta) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocks = append(s.blocks, block)
+	s.sortLocked()
+}
+
+// replace 用合并后的数据块替换原来的多个数据块
+func (s *blockSet) replace(old []*blockMeta, block *blockMeta) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := make(map[*blockMeta]bool, len(old))
+	for _, b := range old {
+		removed[b] = true
+	}
+	kept := s.blocks[:0]
+	for _, b := range s.blocks {
+		if removed[b] {
+			os.Remove(b.path)
+			continue
+		}
+		kept = append(kept, b)
+	}
+	s.blocks = append(kept, block)
+	s.sortLocked()
+}
+
+// groups 按窗口分组，用于合并
+func (s *blockSet) groups() map[int64][]*blockMeta {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	groups := make(map[int64][]*blockMeta)
+	for _, block := range s.blocks {
+		groups[block.start] = append(groups[block.start], block)
+	}
+	return groups
+}
+
+// dropBefore 删除整个窗口都早于 cutoff 的数据块
+func (s *blockSet) dropBefore(cutoff int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.blocks[:0]
+	for _, block := range s.blocks {
+		if block.start+s.secs <= cutoff {
+			if err := os.Remove(block.path); err != nil && !os.IsNotExist(err) {
+				fmt.Printf("[时序数据库] 删除过期数据块 %s 失败: %v\n", block.path, err)
+			}
+			continue
+		}
+		kept = append(kept, block)
+	}
+	s.blocks = kept
+}
+
+// window 窗口的所有数据块，按序号升序
+func (s *blockSet) window(start int64) []*blockMeta {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var blocks []*blockMeta
+	for _, block := range s.blocks {
+		if block.start == start {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// count 数据块数量
+func (s *blockSet) count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.blocks)
+}
+
+// readRange 读取一台主机在时间范围内的数据，按数据块顺序回调，同一时间后读到的数据更新
+func readRange[T any](s *blockSet, hostID uint64, start, end int64, timeOf func(T) int64, fn func(T)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, block := range s.blocks {
+		if !block.overlaps(hostID, start, end) {
+			continue
+		}
+		items, err := readBlockHost[T](block, hostID)
+		if err != nil {
+			fmt.Printf("[时序数据库] 读取数据块 %s 失败: %v\n", block.path, err)
+			continue
+		}
+		for _, item := range items {
+			if t := timeOf(item); t >= start && t <= end {
+				fn(item)
+			}
+		}
+	}
+}
+
+// writeNext 把一个窗口的数据写成该窗口的下一个数据块，写入后由调用方 add 才对查询可见
+func writeNext[T any](s *blockSet, start int64, items map[uint64][]T, timeOf func(T) int64) (*blockMeta, error) {
+	path, seq := s.nextPath(start)
+	index, err := writeBlock(path, start, start+s.secs, items, timeOf)
+	if err != nil {
+		return nil, err
+	}
+	return &blockMeta{path: path, start: start, seq: seq, index: index}, nil
+}
+
+// compactSet 合并同一窗口的多个数据块，merge 合并两个按时间升序的列表。
+// 只合并在 sealedBefore 之前结束的窗口：降采样数据块覆盖的时间长，每次落盘都会给当前窗口新增一个数据块，
+// 窗口结束前合并会反复重写整个窗口
+func compactSet[T any](s *blockSet, sealedBefore int64, timeOf func(T) int64, merge func(older, newer []T) []T) {
+	for start, group := range s.groups() {
+		if len(group) < 2 || start+s.secs > sealedBefore {
+			continue
+		}
+		items := make(map[uint64][]T)
+		failed := false
+		for _, block := range group {
+			for hostID := range block.index.Hosts {
+				list, err := readBlockHost[T](block, hostID)
+				if err != nil {
+					fmt.Printf("[时序数据库] 读取数据块 %s 失败: %v\n", block.path, err)
+					failed = true
+					break
+				}
+				items[hostID] = merge(items[hostID], list)
+			}
+			if failed {
+				break
+			}
+		}
+		if failed {
+			continue
+		}
+
+		path, seq := s.nextPath(start)
+		index, err := writeBlock(path, start, start+s.secs, items, timeOf)
+		if err != nil {
+			fmt.Printf("[时序数据库] 合并窗口 %d 的数据块失败: %v\n", start, err)
+			continue
+		}
+		s.replace(group, &blockMeta{path: path, start: start, seq: seq, index: index})
+	}
+}
+
+// parseBlockName 从文件名解析窗口起始时间和序号
+func parseBlockName(name string) (int64, int, bool) {
+	if !strings.HasSuffix(name, blockExt) {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(name, blockExt), "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	start, err1 := strconv.ParseInt(parts[0], 10, 64)
+	seq, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return start, seq, true
+}
+
+// writeBlock 把一个窗口的数据写成数据块，先写临时文件，fsync 后再改名，保证数据块要么完整要么不存在
+func writeBlock[T any](path string, start, end int64, items map[uint64][]T, timeOf func(T) int64) (*blockIndex, error) {
+	index := &blockIndex{Start: start, End: end, Hosts: map[uint64]*blockHostIndex{}}
+
+	hostIDs := make([]uint64, 0, len(items))
+	for hostID, list := range items {
+		if len(list) > 0 {
+			hostIDs = append(hostIDs, hostID)
+		}
+	}
+	sort.Slice(hostIDs, func(i, j int) bool { return hostIDs[i] < hostIDs[j] })
+
+	var buf bytes.Buffer
+	buf.WriteString(blockMagic)
+	for _, hostID := range hostIDs {
+		list := items[hostID]
+		offset := int64(buf.Len())
+		zw := gzip.NewWriter(&buf)
+		enc := json.NewEncoder(zw)
+		for _, item := range list {
+			if err := enc.Encode(item); err != nil {
+				return nil, err
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		index.Hosts[hostID] = &blockHostIndex{
+			Offset:  offset,
+			Length:  int64(buf.Len()) - offset,
+			Count:   len(list),
+			MinTime: timeOf(list[0]),
+			MaxTime: timeOf(list[len(list)-1]),
+		}
+	}
+	indexOffset := int64(buf.Len())
+	if err := json.NewEncoder(&buf).Encode(index); err != nil {
+		return nil, err
+	}
+	var trailer [8]byte
+	binary.LittleEndian.PutUint64(trailer[:], uint64(indexOffset))
+	buf.Write(trailer[:])
+	buf.WriteString(blockMagic)
+
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return nil, err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return nil, err
+	}
+	file.Close()
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	syncDir(filepath.Dir(path))
+	return index, nil
+}
+
+// readBlockIndex 读取数据块末尾的索引，文件不完整时返回错误
+func readBlockIndex(path string) (*blockIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	trailerSize := int64(8 + len(blockMagic))
+	if info.Size() < int64(len(blockMagic))+trailerSize {
+		return nil, errors.New("数据块不完整")
+	}
+	trailer := make([]byte, trailerSize)
+	if _, err := file.ReadAt(trailer, info.Size()-trailerSize); err != nil {
+		return nil, err
+	}
+	if string(trailer[8:]) != blockMagic {
+		return nil, errors.New("数据块格式错误")
+	}
+	indexOffset := int64(binary.LittleEndian.Uint64(trailer[:8]))
+	if indexOffset < int64(len(blockMagic)) || indexOffset > info.Size()-trailerSize {
+		return nil, errors.New("数据块索引偏移错误")
+	}
+	var index blockIndex
+	section := io.NewSectionReader(file, indexOffset, info.Size()-trailerSize-indexOffset)
+	if err := json.NewDecoder(section).Decode(&index); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+// readBlockHost 解压数据块中一台主机的数据，按时间升序
+func readBlockHost[T any](b *blockMeta, hostID uint64) ([]T, error) {
+	entry, ok := b.index.Hosts[hostID]
+	if !ok {
+		return nil, nil
+	}
+	file, err := os.Open(b.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(bufio.NewReader(io.NewSectionReader(file, entry.Offset, entry.Length)))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	items := make([]T, 0, entry.Count)
+	dec := json.NewDecoder(zr)
+	for {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// overlaps 数据块中该主机的数据是否与查询范围相交
+func (b *blockMeta) overlaps(hostID uint64, start, end int64) bool {
+	entry, ok := b.index.Hosts[hostID]
+	return ok && entry.MaxTime >= start && entry.MinTime <= end
+}
+
+// syncDir 改名后 fsync 目录，保证目录项落盘
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
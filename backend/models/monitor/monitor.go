@@ -2,7 +2,10 @@ package monitor
 
 import (
 	"fmt"
+	"math"
+	"sort"
 	"sync"
+	"time"
 )
 
 // MetricPoint 单个指标数据点
@@ -16,6 +19,13 @@ type MetricPoint struct {
 		Load1m       float64 `json:"load1m"`       // 1分钟负载
 		Load5m       float64 `json:"load5m"`       // 5分钟负载
 		Load15m      float64 `json:"load15m"`      // 15分钟负载
+
+		UserPercent     float64 `json:"userPercent"`     // 用户态（百分比）
+		SystemPercent   float64 `json:"systemPercent"`   // 内核态（百分比）
+		IowaitPercent   float64 `json:"iowaitPercent"`   // 等待IO（百分比）
+		StealPercent    float64 `json:"stealPercent"`    // 被虚拟化宿主占用（百分比）
+		IdlePercent     float64 `json:"idlePercent"`     // 空闲（百分比）
+		ContextSwitches float64 `json:"contextSwitches"` // 每秒上下文切换次数
 	} `json:"cpu"`
 
 	// 内存信息
@@ -25,16 +35,22 @@ type MetricPoint struct {
 		FreeBytes      uint64  `json:"freeBytes"`      // 空闲内存(字节)
 		AvailableBytes uint64  `json:"availableBytes"` // 可用内存(字节)
 		UsagePercent   float64 `json:"usagePercent"`   // 使用率(百分比)
+
+		SwapTotalBytes   uint64  `json:"swapTotalBytes"`   // 交换分区总量(字节)
+		SwapUsedBytes    uint64  `json:"swapUsedBytes"`    // 交换分区已用(字节)
+		SwapFreeBytes    uint64  `json:"swapFreeBytes"`    // 交换分区剩余(字节)
+		SwapUsagePercent float64 `json:"swapUsagePercent"` // 交换分区使用率(百分比)
 	} `json:"memory"`
 
 	// 磁盘信息
 	Disk struct {
-		AvailableBytes float64     `json:"availableBytes"` // 可用空间(GB)
-		TotalBytes     float64     `json:"totalBytes"`     // 总空间(GB)
-		UsagePercent   string      `json:"usagePercent"`   // 使用率(百分比)
-		ReadRate       uint64      `json:"readRate"`       // 读取速率(B/s)
-		WriteRate      uint64      `json:"writeRate"`      // 写入速率(B/s)
-		Volumes        []DiskUsage `json:"volumes"`        // 磁盘详情列表
+		AvailableBytes float64       `json:"availableBytes"`               // 可用空间(GB)
+		TotalBytes     float64       `json:"totalBytes"`                   // 总空间(GB)
+		UsagePercent   string        `json:"usagePercent" metric:"number"` // 使用率(百分比)
+		ReadRate       uint64        `json:"readRate"`                     // 读取速率(B/s)
+		WriteRate      uint64        `json:"writeRate"`                    // 写入速率(B/s)
+		Volumes        []DiskUsage   `json:"volumes"`                      // 磁盘详情列表
+		Devices        []DiskIOStats `json:"devices"`                      // 块设备IO列表
 	} `json:"disk"`
 
 	// 网络信息
@@ -42,29 +58,109 @@ type MetricPoint struct {
 		RecvRate   float64          `json:"recvRate"`   // 总接收速率(B/s)
 		SendRate   float64          `json:"sendRate"`   // 总发送速率(B/s)
 		Interfaces []InterfaceStats `json:"interfaces"` // 网卡列表
+		TCP        TCPStates        `json:"tcp"`        // TCP连接状态
 	} `json:"network"`
+
+	// 系统信息
+	System struct {
+		BootTime         uint64  `json:"bootTime"`         // 启动时间（Unix时间戳，秒）
+		UptimeSeconds    uint64  `json:"uptimeSeconds"`    // 运行时间（秒）
+		OpenFiles        uint64  `json:"openFiles"`        // 已分配的文件描述符数
+		MaxFiles         uint64  `json:"maxFiles"`         // 文件描述符上限
+		OpenFilesPercent float64 `json:"openFilesPercent"` // 文件描述符使用率（百分比）
+	} `json:"system"`
+
+	// 进程信息
+	Processes struct {
+		Total int                 `json:"total"`             // 进程总数
+		Top   []ProcessStats      `json:"top" metric:"skip"` // CPU、内存占用最高的进程，进程号变化频繁，不参与汇总
+		Watch []ProcessWatchStats `json:"watch"`             // 进程监控列表
+	} `json:"processes"`
+
+	// 自定义指标，key 为 Prometheus 风格的序列名，如 node_load1、http_requests_total{code="200"}
+	Custom map[string]float64 `json:"custom,omitempty"`
+	// 只有推送的自定义指标、没有 agent 上报数据的数据点，展开时只包含自定义指标
+	Partial bool `json:"partial,omitempty"`
 }
 
 // DiskUsage 单个磁盘使用情况
 type DiskUsage struct {
-	MountPoint   string  `json:"mountPoint"`   // 挂载点路径
-	DeviceName   string  `json:"deviceName"`   // 设备名称
-	TotalBytes   uint64  `json:"totalBytes"`   // 总空间(字节)
-	UsedBytes    uint64  `json:"usedBytes"`    // 已用空间(字节)
-	FreeBytes    uint64  `json:"freeBytes"`    // 剩余空间(字节)
-	UsagePercent float64 `json:"usagePercent"` // 使用率(百分比)
-	FSType       string  `json:"fsType"`       // 文件系统类型
+	MountPoint   string  `json:"mountPoint" metric:"key"` // 挂载点路径
+	DeviceName   string  `json:"deviceName"`              // 设备名称
+	TotalBytes   uint64  `json:"totalBytes"`              // 总空间(字节)
+	UsedBytes    uint64  `json:"usedBytes"`               // 已用空间(字节)
+	FreeBytes    uint64  `json:"freeBytes"`               // 剩余空间(字节)
+	UsagePercent float64 `json:"usagePercent"`            // 使用率(百分比)
+	FSType       string  `json:"fsType"`                  // 文件系统类型
+
+	InodesTotal        uint64  `json:"inodesTotal"`        // inode 总数
+	InodesUsed         uint64  `json:"inodesUsed"`         // 已用 inode 数
+	InodesFree         uint64  `json:"inodesFree"`         // 剩余 inode 数
+	InodesUsagePercent float64 `json:"inodesUsagePercent"` // inode 使用率(百分比)
+}
+
+// DiskIOStats 单个块设备的IO统计
+type DiskIOStats struct {
+	Name        string  `json:"name" metric:"key"` // 设备名称，如 sda、nvme0n1
+	ReadBytes   uint64  `json:"readBytes"`         // 累计读取字节数
+	WriteBytes  uint64  `json:"writeBytes"`        // 累计写入字节数
+	ReadRate    float64 `json:"readRate"`          // 读取速率（字节/秒）
+	WriteRate   float64 `json:"writeRate"`         // 写入速率（字节/秒）
+	ReadIOPS    float64 `json:"readIops"`          // 每秒读次数
+	WriteIOPS   float64 `json:"writeIops"`         // 每秒写次数
+	UtilPercent float64 `json:"utilPercent"`       // 设备繁忙时间占比（百分比）
+	Partition   bool    `json:"partition"`         // 是否为分区
 }
 
 // InterfaceStats 网卡统计信息
 type InterfaceStats struct {
-	Name           string  `json:"name"`           // 网卡名称
-	MacAddress     string  `json:"macAddress"`     // MAC地址
-	IPv4Address    string  `json:"ipv4Address"`    // IPv4地址
-	TotalRecvBytes uint64  `json:"totalRecvBytes"` // 总接收字节数
-	TotalSentBytes uint64  `json:"totalSentBytes"` // 总发送字节数
-	RecvRate       float64 `json:"recvRate"`       // 接收速率（字节/秒）
-	SendRate       float64 `json:"sendRate"`       // 发送速率（字节/秒）
+	Name           string  `json:"name" metric:"key"` // 网卡名称
+	MacAddress     string  `json:"macAddress"`        // MAC地址
+	IPv4Address    string  `json:"ipv4Address"`       // IPv4地址
+	TotalRecvBytes uint64  `json:"totalRecvBytes"`    // 总接收字节数
+	TotalSentBytes uint64  `json:"totalSentBytes"`    // 总发送字节数
+	RecvRate       float64 `json:"recvRate"`          // 接收速率（字节/秒）
+	SendRate       float64 `json:"sendRate"`          // 发送速率（字节/秒）
+	Virtual        bool    `json:"virtual"`           // 是否为虚拟网卡
+}
+
+// ProcessStats 单个进程的资源占用
+type ProcessStats struct {
+	PID           int32   `json:"pid"`           // 进程号
+	Name          string  `json:"name"`          // 进程名
+	Exe           string  `json:"exe"`           // 可执行文件路径，不上报命令行参数
+	Username      string  `json:"username"`      // 运行用户
+	CPUPercent    float64 `json:"cpuPercent"`    // CPU使用率（百分比，多核可超过 100）
+	RSSBytes      uint64  `json:"rssBytes"`      // 常驻内存(字节)
+	MemoryPercent float64 `json:"memoryPercent"` // 内存使用率（百分比）
+	NumFDs        int32   `json:"numFds"`        // 打开的文件描述符数
+	NumThreads    int32   `json:"numThreads"`    // 线程数
+}
+
+// ProcessWatchStats 进程监控列表中一项匹配到的所有进程的汇总
+type ProcessWatchStats struct {
+	Name       string  `json:"name" metric:"key"` // 监控项名称
+	Count      int     `json:"count"`             // 运行中的进程数
+	CPUPercent float64 `json:"cpuPercent"`        // CPU使用率之和（百分比）
+	RSSBytes   uint64  `json:"rssBytes"`          // 常驻内存之和(字节)
+	NumFDs     int32   `json:"numFds"`            // 文件描述符数之和
+	NumThreads int32   `json:"numThreads"`        // 线程数之和
+}
+
+// TCPStates 各状态的TCP连接数（IPv4 和 IPv6 合计）
+type TCPStates struct {
+	Total       int `json:"total"`       // 连接总数
+	Established int `json:"established"` // ESTABLISHED
+	SynSent     int `json:"synSent"`     // SYN_SENT
+	SynRecv     int `json:"synRecv"`     // SYN_RECV
+	FinWait1    int `json:"finWait1"`    // FIN_WAIT1
+	FinWait2    int `json:"finWait2"`    // FIN_WAIT2
+	TimeWait    int `json:"timeWait"`    // TIME_WAIT
+	Close       int `json:"close"`       // CLOSE
+	CloseWait   int `json:"closeWait"`   // CLOSE_WAIT
+	LastAck     int `json:"lastAck"`     // LAST_ACK
+	Listen      int `json:"listen"`      // LISTEN
+	Closing     int `json:"closing"`     // CLOSING
 }
 
 // NetworkStatus 网络监控数据结构
@@ -81,35 +177,97 @@ type NetworkStatus struct {
 	BytesSentRate float64 `json:"bytesSentRate"` // 发送速率（字节/秒）
 }
 
-// HostTimeData 单个主机的时序数据
+// Options 时序库配置
+type Options struct {
+	Dir             string        // 数据目录，为空时只保存在内存中
+	Retention       time.Duration // 原始数据保留时间
+	RollupRetention time.Duration // 降采样数据保留时间，不短于原始数据
+	BlockDuration   time.Duration // 每个数据块覆盖的时间
+	OutOfOrder      time.Duration // 窗口结束后继续等待迟到数据的时间，之后落盘为数据块
+}
 
-type HostTimeData struct {
-	points [17280]*MetricPoint // 固定大小的数组，存储24小时的数据（5秒一个点）
-	head   int                 // 当前写入位置
-	count  int                 // 当前存储的数据点数量
+// window 尚未落盘的一个时间窗口，数据点按主机保存并按时间升序排列
+type window struct {
+	start  int64
+	points map[uint64][]*MetricPoint
+	wals   []*walSegment
 }
 
-// TimeSeriesDB 内存时序库
+// TimeSeriesDB 时序库：最近的窗口保存在内存中并写入预写日志，窗口结束后压缩落盘为数据块
 
 type TimeSeriesDB struct {
 	sync.RWMutex
-	hostPoints map[uint64]*HostTimeData // key为hostID，value为该主机的数据点数组
-}
+	opts      Options
+	blockSecs int64
 
-// 对象池，用于减少内存分配
+	windows  map[int64]*window       // 内存中的窗口，key 为窗口起始时间
+	flushing []*window               // 正在落盘的窗口，落盘完成前仍可查询
+	latest   map[uint64]*MetricPoint // 每台主机的最新数据点
 
-var pointPool = sync.Pool{
-	New: func() interface{} {
-		return &MetricPoint{}
-	},
+	raw     *blockSet   // 已落盘的原始数据块
+	rollups []*blockSet // 各粒度的降采样数据块，与 RollupTiers 对应
+
+	stop chan struct{}
+	done chan struct{}
 }
 
-// NewTimeSeriesDB 创建新的时序数据库实例
+// NewTimeSeriesDB 创建只保存在内存中的时序库，保留 24 小时数据
 
 func NewTimeSeriesDB() *TimeSeriesDB {
-	return &TimeSeriesDB{
-		hostPoints: make(map[uint64]*HostTimeData),
+	db, _ := Open(Options{})
+	return db
+}
+
+// Open 打开时序库，数据目录不为空时加载已有数据块并重放预写日志
+
+func Open(opts Options) (*TimeSeriesDB, error) {
+	if opts.BlockDuration < time.Minute {
+		opts.BlockDuration = 2 * time.Hour
+	}
+	// 降采样的每个间隔都需落在一个窗口内，窗口按最粗的间隔向上取整
+	if maxStep := time.Duration(RollupTiers[len(RollupTiers)-1].Step) * time.Second; opts.BlockDuration%maxStep != 0 {
+		opts.BlockDuration = (opts.BlockDuration/maxStep + 1) * maxStep
+	}
+	// 只保存在内存中时保留 24 小时
+	if opts.Retention <= 0 || opts.Dir == "" {
+		opts.Retention = 24 * time.Hour
+	}
+	if opts.RollupRetention < opts.Retention {
+		opts.RollupRetention = opts.Retention
 	}
+	if opts.OutOfOrder < 0 {
+		opts.OutOfOrder = 0
+	}
+	db := &TimeSeriesDB{
+		opts:      opts,
+		blockSecs: int64(opts.BlockDuration / time.Second),
+		windows:   make(map[int64]*window),
+		latest:    make(map[uint64]*MetricPoint),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if opts.Dir != "" {
+		if err := db.recover(); err != nil {
+			return nil, err
+		}
+	}
+	go db.run()
+	return db, nil
+}
+
+// Close 停止后台任务并关闭预写日志，内存中的窗口下次启动时由日志恢复
+
+func (db *TimeSeriesDB) Close() error {
+	close(db.stop)
+	<-db.done
+	db.Lock()
+	defer db.Unlock()
+	for _, w := range db.windows {
+		for _, wal := range w.wals {
+			wal.close()
+		}
+	}
+	return nil
 }
 
 // alignTimestamp 时间戳对齐到5秒
@@ -118,69 +276,145 @@ func alignTimestamp(ts int64) int64 {
 	return ts - (ts % 5)
 }
 
-// Insert 插入数据点
+func (db *TimeSeriesDB) windowStart(ts int64) int64 {
+	return ts - ((ts%db.blockSecs)+db.blockSecs)%db.blockSecs
+}
+
+// Insert 插入数据点，同一主机同一时间的数据点会被覆盖
 
 func (db *TimeSeriesDB) Insert(point *MetricPoint) {
+	// 复制一份，调用方可能继续修改传入的数据
+	newPoint := *point
+	newPoint.CollectedAt = alignTimestamp(newPoint.CollectedAt)
+	point.CollectedAt = newPoint.CollectedAt
+
+	if newPoint.CollectedAt < time.Now().Add(-db.opts.Retention).Unix() {
+		fmt.Printf("[时序数据库] 丢弃超出保留时间的数据: 主机ID=%d, 时间戳=%d\n", newPoint.HostID, newPoint.CollectedAt)
+		return
+	}
+
 	db.Lock()
 	defer db.Unlock()
+	db.insertLocked(&newPoint, true)
+}
 
-	// 对齐时间戳
-	point.CollectedAt = alignTimestamp(point.CollectedAt)
-
-	// 获取或创建主机的时间序列数据
-	hostData, exists := db.hostPoints[point.HostID]
+// insertLocked 写入内存窗口，logged 为 true 时同时写入预写日志
+func (db *TimeSeriesDB) insertLocked(point *MetricPoint, logged bool) {
+	start := db.windowStart(point.CollectedAt)
+	w, exists := db.windows[start]
 	if !exists {
-		hostData = &HostTimeData{
-			points: [17280]*MetricPoint{},
-			head:   0,
-			count:  0,
+		w = &window{start: start, points: make(map[uint64][]*MetricPoint)}
+		db.windows[start] = w
+	}
+	if logged && db.opts.Dir != "" {
+		if err := db.appendWal(w, point); err != nil {
+			fmt.Printf("[时序数据库] 写入预写日志失败: %v\n", err)
 		}
-		db.hostPoints[point.HostID] = hostData
+	}
 
+	list := w.points[point.HostID]
+	i := sort.Search(len(list), func(i int) bool { return list[i].CollectedAt >= point.CollectedAt })
+	if i < len(list) && list[i].CollectedAt == point.CollectedAt {
+		// 保留之前推送的自定义指标，agent 上报的数据不会覆盖它们
+		point.Custom = mergeCustom(list[i].Custom, point.Custom)
+		list[i] = point
+	} else {
+		list = append(list, nil)
+		copy(list[i+1:], list[i:])
+		list[i] = point
 	}
+	w.points[point.HostID] = list
 
-	// 从对象池获取新对象并复制数据
-	newPoint := pointPool.Get().(*MetricPoint)
-	*newPoint = *point
+	// 只有自定义指标的数据点不作为最新数据，避免最新数据中 agent 上报的字段变为 0
+	if point.Partial {
+		return
+	}
+	if latest := db.latest[point.HostID]; latest == nil || point.CollectedAt >= latest.CollectedAt {
+		db.latest[point.HostID] = point
+	}
+}
+
+// InsertCustom 写入推送的自定义指标，合并到同一时间的数据点中；该时间还没有数据点时新建只含自定义指标的数据点。
+// 已落盘的窗口不再接受推送，返回 false
+func (db *TimeSeriesDB) InsertCustom(hostID uint64, ts int64, values map[string]float64) bool {
+	ts = alignTimestamp(ts)
+	db.Lock()
+	defer db.Unlock()
 
-	// 如果当前位置有旧数据，放回对象池
-	if hostData.points[hostData.head] != nil {
-		pointPool.Put(hostData.points[hostData.head])
+	start := db.windowStart(ts)
+	w, ok := db.windows[start]
+	if !ok && start+db.blockSecs <= time.Now().Add(-db.opts.OutOfOrder).Unix() {
+		return false
+	}
+	point := &MetricPoint{HostID: hostID, CollectedAt: ts, Partial: true}
+	if ok {
+		list := w.points[hostID]
+		i := sort.Search(len(list), func(i int) bool { return list[i].CollectedAt >= ts })
+		if i < len(list) && list[i].CollectedAt == ts {
+			copied := *list[i]
+			point = &copied
+		}
 	}
+	point.Custom = mergeCustom(point.Custom, values)
+	db.insertLocked(point, true)
+	return true
+}
 
-	// 存入新数据
-	hostData.points[hostData.head] = newPoint
-	hostData.head = (hostData.head + 1) % 17280
-	if hostData.count < 17280 {
-		hostData.count++
+// mergeCustom 合并自定义指标，newer 中的值优先，返回新的 map
+func mergeCustom(older, newer map[string]float64) map[string]float64 {
+	if len(older) == 0 {
+		return newer
+	}
+	merged := make(map[string]float64, len(older)+len(newer))
+	for k, v := range older {
+		merged[k] = v
 	}
+	for k, v := range newer {
+		merged[k] = v
+	}
+	return merged
+}
 
+// appendWal 写入窗口当前的日志段，没有时新建
+func (db *TimeSeriesDB) appendWal(w *window, point *MetricPoint) error {
+	if len(w.wals) == 0 {
+		wal, err := openWalSegment(walPath(db.opts.Dir, w.start, time.Now().UnixNano()))
+		if err != nil {
+			return err
+		}
+		w.wals = append(w.wals, wal)
+	}
+	return w.wals[len(w.wals)-1].append(point)
 }
 
-// Query 查询指定主机在指定时间范围的数据
+// Query 查询指定主机在指定时间范围的数据，按时间倒序。
+// 只有推送或远程写入数据的主机没有最新数据，同样可以查询
 
 func (db *TimeSeriesDB) Query(hostID uint64, start, end int64) []*MetricPoint {
-	db.RLock()
-	defer db.RUnlock()
-
-	hostData, exists := db.hostPoints[hostID]
-	if !exists {
-		fmt.Printf("[时序数据库] 查询失败: 主机ID=%d 不存在\n", hostID)
-		return nil
+	// 先取内存中的数据再读数据块：窗口在两次读取之间落盘时会读到两份，而不会两边都读不到。
+	// 重复时以内存中的为准，迟到数据覆盖的数据点可能还没合并进数据块
+	head := db.headPoints(hostID, start, end)
+	merged := make(map[int64]*MetricPoint)
+	if db.raw != nil {
+		readRange(db.raw, hostID, start, end, pointTime, func(point *MetricPoint) {
+			if older, ok := merged[point.CollectedAt]; ok {
+				point = mergePoint(older, point)
+			}
+			merged[point.CollectedAt] = point
+		})
 	}
-
-	result := make([]*MetricPoint, 0)
-	for i := 0; i < hostData.count; i++ {
-		idx := (hostData.head - 1 - i + 17280) % 17280
-		point := hostData.points[idx]
-		if point == nil {
-			continue
-		}
-		if point.CollectedAt >= start && point.CollectedAt <= end {
-			result = append(result, point)
+	for _, point := range head {
+		if older, ok := merged[point.CollectedAt]; ok {
+			point = mergePoint(older, point)
 		}
+		merged[point.CollectedAt] = point
 	}
 
+	result := make([]*MetricPoint, 0, len(merged))
+	for _, point := range merged {
+		result = append(result, point)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CollectedAt > result[j].CollectedAt })
 	return result
 }
 
@@ -190,17 +424,9 @@ func (db *TimeSeriesDB) GetLatest(hostID uint64) *MetricPoint {
 	db.RLock()
 	defer db.RUnlock()
 
-	hostData, exists := db.hostPoints[hostID]
-	if !exists || hostData.count == 0 {
+	point := db.latest[hostID]
+	if point == nil {
 		fmt.Printf("[时序数据库] 获取最新数据失败: 主机ID=%d 不存在或无数据\n", hostID)
-		return nil
-	}
-
-	lastIdx := (hostData.head - 1 + 17280) % 17280
-	point := hostData.points[lastIdx]
-	if point != nil {
-		fmt.Printf("[时序数据库] 获取最新数据: 主机ID=%d, 时间戳=%d\n",
-			hostID, point.CollectedAt)
 	}
 	return point
 }
@@ -211,49 +437,31 @@ func (db *TimeSeriesDB) GetAllLatest() map[uint64]*MetricPoint {
 	db.RLock()
 	defer db.RUnlock()
 
-	result := make(map[uint64]*MetricPoint)
-	for hostID, hostData := range db.hostPoints {
-		if hostData.count > 0 {
-			lastIdx := (hostData.head - 1 + 17280) % 17280
-			if point := hostData.points[lastIdx]; point != nil {
-				result[hostID] = point
-			}
-		}
+	result := make(map[uint64]*MetricPoint, len(db.latest))
+	for hostID, point := range db.latest {
+		result[hostID] = point
 	}
 	return result
 }
 
-// GetAllData 获取指定主机的所有存储的数据点
+// GetAllData 获取指定主机最近 24 小时的数据点，按时间倒序
 
 func (db *TimeSeriesDB) GetAllData(hostID uint64) []*MetricPoint {
-	db.RLock()
-	defer db.RUnlock()
-
-	hostData, exists := db.hostPoints[hostID]
-	if !exists {
-		fmt.Printf("[时序数据库] 获取所有数据失败: 主机ID=%d 不存在\n", hostID)
-		return nil
-	}
-
-	result := make([]*MetricPoint, 0, hostData.count)
-	for i := 0; i < hostData.count; i++ {
-		idx := (hostData.head - 1 - i + 17280) % 17280
-		if point := hostData.points[idx]; point != nil {
-			result = append(result, point)
-		}
-	}
-
-	return result
+	return db.Query(hostID, time.Now().Add(-24*time.Hour).Unix(), math.MaxInt64)
 }
 
-// GetAllHostData 获取所有主机的所有数据点
+// GetAllHostData 获取所有主机最近 24 小时的数据点
 
 func (db *TimeSeriesDB) GetAllHostData() map[uint64][]*MetricPoint {
 	db.RLock()
-	defer db.RUnlock()
+	hostIDs := make([]uint64, 0, len(db.latest))
+	for hostID := range db.latest {
+		hostIDs = append(hostIDs, hostID)
+	}
+	db.RUnlock()
 
 	result := make(map[uint64][]*MetricPoint)
-	for hostID := range db.hostPoints {
+	for _, hostID := range hostIDs {
 		result[hostID] = db.GetAllData(hostID)
 	}
 
@@ -0,0 +1,307 @@
+package monitor
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 降采样：窗口落盘时按 1m、5m、1h 三种粒度汇总每个数值字段的 min/max/avg/last，
+// 长时间范围的查询读取汇总数据，不再逐个扫描 5 秒一个的原始数据点。
+
+// Tier 降采样粒度
+type Tier struct {
+	Name      string // 目录名，也用于查询结果
+	Step      int64  // 汇总间隔（秒）
+	Partition int64  // 每个数据块覆盖的时间（秒）
+}
+
+// RawTier 原始数据，5 秒一个点
+var RawTier = Tier{Name: "raw", Step: 5}
+
+// RollupTiers 降采样粒度，从细到粗
+var RollupTiers = []Tier{
+	{Name: "1m", Step: 60, Partition: 24 * 3600},
+	{Name: "5m", Step: 300, Partition: 7 * 24 * 3600},
+	{Name: "1h", Step: 3600, Partition: 30 * 24 * 3600},
+}
+
+const rollupDir = "rollups"
+
+// Agg 一个字段在汇总间隔内的统计值
+type Agg struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Sum    float64 `json:"sum"`
+	Count  int64   `json:"count"`
+	Last   float64 `json:"last"`
+	LastAt int64   `json:"lastAt"` // last 对应的采集时间
+}
+
+// Avg 平均值
+func (a Agg) Avg() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+	return a.Sum / float64(a.Count)
+}
+
+func newAgg(value float64, at int64) Agg {
+	return Agg{Min: value, Max: value, Sum: value, Count: 1, Last: value, LastAt: at}
+}
+
+// merge 合并两个统计值，满足交换律和结合律，可以按任意顺序合并
+func (a Agg) merge(b Agg) Agg {
+	if a.Count == 0 {
+		return b
+	}
+	if b.Count == 0 {
+		return a
+	}
+	result := Agg{
+		Min:   math.Min(a.Min, b.Min),
+		Max:   math.Max(a.Max, b.Max),
+		Sum:   a.Sum + b.Sum,
+		Count: a.Count + b.Count,
+	}
+	if b.LastAt >= a.LastAt {
+		result.Last, result.LastAt = b.Last, b.LastAt
+	} else {
+		result.Last, result.LastAt = a.Last, a.LastAt
+	}
+	return result
+}
+
+// RollupPoint 一台主机在一个汇总间隔内的数据，Fields 的 key 为 Flatten 生成的指标路径
+type RollupPoint struct {
+	Time   int64          `json:"time"` // 间隔起始时间
+	HostID uint64         `json:"hostId"`
+	Fields map[string]Agg `json:"fields"`
+}
+
+func rollupTime(p *RollupPoint) int64 { return p.Time }
+
+func (p *RollupPoint) add(fields map[string]float64, at int64) {
+	for name, value := range fields {
+		p.Fields[name] = p.Fields[name].merge(newAgg(value, at))
+	}
+}
+
+func (p *RollupPoint) mergeFrom(other *RollupPoint) {
+	for name, agg := range other.Fields {
+		p.Fields[name] = p.Fields[name].merge(agg)
+	}
+}
+
+// Flatten 把数据点展开为 指标路径 -> 数值，路径由 json 字段名组成，跳过 metric:"skip" 标记的字段，
+// 列表中的元素用 metric:"key" 标记的字段区分，如 disk.volumes[/data].usagePercent、network.interfaces[eth0].recvRate，
+// 自定义指标为 custom.<序列名>
+func Flatten(point *MetricPoint) map[string]float64 {
+	fields := make(map[string]float64)
+	v := reflect.ValueOf(point).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		// 时间、主机ID 和标记不是指标
+		if name == "" || name == "collectedAt" || name == "hostId" || name == "partial" {
+			continue
+		}
+		if point.Partial && name != "custom" {
+			continue
+		}
+		flattenValue(fields, name, v.Field(i), t.Field(i).Tag.Get("metric"))
+	}
+	return fields
+}
+
+func flattenValue(fields map[string]float64, path string, v reflect.Value, tag string) {
+	// metric:"skip" 标记的字段不是指标
+	if tag == "skip" {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		fields[path] = v.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fields[path] = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fields[path] = float64(v.Uint())
+	case reflect.String:
+		// 只有标记为数值的字符串字段参与汇总
+		if tag == "number" {
+			if f, err := strconv.ParseFloat(strings.TrimSuffix(v.String(), "%"), 64); err == nil {
+				fields[path] = f
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name := jsonName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			flattenValue(fields, path+"."+name, v.Field(i), t.Field(i).Tag.Get("metric"))
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			if elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+			key := elementKey(elem)
+			if key == "" {
+				key = strconv.Itoa(i)
+			}
+			flattenValue(fields, path+"["+key+"]", elem, "")
+		}
+	case reflect.Map:
+		// 自定义指标等 map[string]数值 字段
+		iter := v.MapRange()
+		for iter.Next() {
+			if iter.Key().Kind() == reflect.String {
+				flattenValue(fields, path+"."+iter.Key().String(), iter.Value(), tag)
+			}
+		}
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// elementKey 列表元素中 metric:"key" 标记的字段值
+func elementKey(v reflect.Value) string {
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("metric") == "key" {
+			return v.Field(i).String()
+		}
+	}
+	return ""
+}
+
+// buildRollups 把按时间升序的原始数据点汇总为指定间隔的数据
+func buildRollups(hostID uint64, points []*MetricPoint, step int64) []*RollupPoint {
+	var result []*RollupPoint
+	for _, point := range points {
+		bucket := point.CollectedAt - point.CollectedAt%step
+		if len(result) == 0 || result[len(result)-1].Time != bucket {
+			result = append(result, &RollupPoint{Time: bucket, HostID: hostID, Fields: map[string]Agg{}})
+		}
+		result[len(result)-1].add(Flatten(point), point.CollectedAt)
+	}
+	return result
+}
+
+// mergeRollups 合并两个按时间升序的列表，同一间隔以 newer 为准：
+// 迟到数据落盘时按合并后的原始数据重新汇总整个间隔，新数据块中的间隔已包含旧的数据
+func mergeRollups(older, newer []*RollupPoint) []*RollupPoint {
+	result := make([]*RollupPoint, 0, len(older)+len(newer))
+	i, j := 0, 0
+	for i < len(older) && j < len(newer) {
+		switch {
+		case older[i].Time < newer[j].Time:
+			result = append(result, older[i])
+			i++
+		case older[i].Time > newer[j].Time:
+			result = append(result, newer[j])
+			j++
+		default:
+			result = append(result, newer[j])
+			i++
+			j++
+		}
+	}
+	result = append(result, older[i:]...)
+	return append(result, newer[j:]...)
+}
+
+// RangeResult 范围查询结果
+type RangeResult struct {
+	Tier   string         `json:"tier"`   // 使用的数据粒度
+	Step   int64          `json:"step"`   // 结果中相邻两点的间隔（秒）
+	Points []*RollupPoint `json:"points"` // 按时间升序
+}
+
+// QueryRange 查询主机在时间范围内的数据，结果不超过 maxPoints 个点。
+// 选择间隔不超过所需间隔的最粗粒度，再合并到所需间隔；原始数据超出保留时间时改用降采样数据。
+func (db *TimeSeriesDB) QueryRange(hostID uint64, start, end int64, maxPoints int) *RangeResult {
+	if maxPoints <= 0 {
+		maxPoints = 1000
+	}
+	if end < start {
+		start, end = end, start
+	}
+	need := (end - start + int64(maxPoints) - 1) / int64(maxPoints)
+
+	tier, tierIndex := RawTier, -1
+	if db.opts.Dir != "" {
+		rawFrom := time.Now().Add(-db.opts.Retention).Unix()
+		for i, t := range RollupTiers {
+			if t.Step <= need || (start < rawFrom && i == 0) {
+				tier, tierIndex = t, i
+			}
+		}
+	}
+	step := tier.Step
+	if need > step {
+		step = (need + tier.Step - 1) / tier.Step * tier.Step
+	}
+
+	buckets := make(map[int64]*RollupPoint)
+	addRollup := func(p *RollupPoint) {
+		bucket := p.Time - p.Time%step
+		target, ok := buckets[bucket]
+		if !ok {
+			target = &RollupPoint{Time: bucket, HostID: hostID, Fields: map[string]Agg{}}
+			buckets[bucket] = target
+		}
+		target.mergeFrom(p)
+	}
+
+	if tierIndex < 0 {
+		// 原始数据直接汇总，Query 按时间倒序返回
+		points := db.Query(hostID, start, end)
+		sort.Slice(points, func(i, j int) bool { return points[i].CollectedAt < points[j].CollectedAt })
+		for _, p := range buildRollups(hostID, points, step) {
+			addRollup(p)
+		}
+	} else {
+		// 内存中尚未落盘的原始数据，加上已落盘窗口的降采样数据。
+		// 窗口恰好在两次读取之间落盘时会统计两次，min/max/avg/last 不受影响
+		for _, p := range buildRollups(hostID, db.headPoints(hostID, start, end), tier.Step) {
+			addRollup(p)
+		}
+		// 合并前同一间隔可能出现在多个数据块中，以后写入的为准
+		flushed := make(map[int64]*RollupPoint)
+		readRange(db.rollups[tierIndex], hostID, start, end, rollupTime, func(p *RollupPoint) {
+			flushed[p.Time] = p
+		})
+		for _, p := range flushed {
+			addRollup(p)
+		}
+	}
+
+	result := &RangeResult{Tier: tier.Name, Step: step, Points: make([]*RollupPoint, 0, len(buckets))}
+	for _, p := range buckets {
+		result.Points = append(result.Points, p)
+	}
+	sort.Slice(result.Points, func(i, j int) bool { return result.Points[i].Time < result.Points[j].Time })
+	return result
+}
@@ -0,0 +1,391 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	walSyncInterval  = time.Second // 预写日志 fsync 间隔，崩溃时最多丢失这段时间的数据
+	maintainInterval = time.Minute // 落盘、合并和过期清理的检查间隔
+	backfillMarker   = ".backfilled"
+)
+
+func pointTime(p *MetricPoint) int64 { return p.CollectedAt }
+
+// recover 启动时加载数据块索引并重放预写日志
+func (db *TimeSeriesDB) recover() error {
+	if err := os.MkdirAll(filepath.Join(db.opts.Dir, walDir), 0755); err != nil {
+		return fmt.Errorf("创建时序数据目录失败: %w", err)
+	}
+	db.raw = newBlockSet(filepath.Join(db.opts.Dir, blockDir), db.blockSecs)
+	if err := db.raw.load(); err != nil {
+		return err
+	}
+	db.rollups = make([]*blockSet, len(RollupTiers))
+	for i, tier := range RollupTiers {
+		db.rollups[i] = newBlockSet(filepath.Join(db.opts.Dir, rollupDir, tier.Name), tier.Partition)
+		if err := db.rollups[i].load(); err != nil {
+			return err
+		}
+	}
+	if err := db.backfillRollups(); err != nil {
+		return err
+	}
+
+	segments, err := listWalSegments(db.opts.Dir)
+	if err != nil {
+		return err
+	}
+	var replayed int
+	for start, paths := range segments {
+		for _, path := range paths {
+			err := replayWal(path, func(point *MetricPoint) {
+				db.insertLocked(point, false)
+				replayed++
+			})
+			if err != nil {
+				return fmt.Errorf("重放预写日志 %s 失败: %w", path, err)
+			}
+			wal, err := openWalSegment(path)
+			if err != nil {
+				return err
+			}
+			w, ok := db.windows[start]
+			if !ok {
+				// 空的日志段
+				w = &window{start: start, points: make(map[uint64][]*MetricPoint)}
+				db.windows[start] = w
+			}
+			w.wals = append(w.wals, wal)
+		}
+	}
+
+	db.loadLatestFromBlocks()
+	fmt.Printf("[时序数据库] 加载 %d 个数据块，重放 %d 条预写日志\n", db.raw.count(), replayed)
+	return nil
+}
+
+// backfillRollups 升级前落盘的原始数据块没有降采样数据，第一次启动时补齐
+func (db *TimeSeriesDB) backfillRollups() error {
+	marker := filepath.Join(db.opts.Dir, rollupDir, backfillMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	}
+	for start := range db.raw.groups() {
+		points, err := db.flushedWindow(start, nil)
+		if err != nil {
+			fmt.Printf("[时序数据库] 读取窗口 %d 的数据块失败: %v\n", start, err)
+			continue
+		}
+		metas, err := db.writeRollups(points)
+		if err != nil {
+			return fmt.Errorf("生成降采样数据失败: %w", err)
+		}
+		for i, list := range metas {
+			for _, meta := range list {
+				db.rollups[i].add(meta)
+			}
+		}
+	}
+	for _, set := range db.rollups {
+		compactSet(set, math.MaxInt64, rollupTime, mergeRollups)
+	}
+	return os.WriteFile(marker, nil, 0644)
+}
+
+// loadLatestFromBlocks 内存窗口中没有数据的主机，从最新的数据块中取最新数据点；
+// 与写入时一样，只有自定义指标的数据点不作为最新数据
+func (db *TimeSeriesDB) loadLatestFromBlocks() {
+	for i := len(db.raw.blocks) - 1; i >= 0; i-- {
+		block := db.raw.blocks[i]
+		for hostID := range block.index.Hosts {
+			if _, ok := db.latest[hostID]; ok {
+				continue
+			}
+			points, err := readBlockHost[*MetricPoint](block, hostID)
+			if err != nil {
+				continue
+			}
+			for j := len(points) - 1; j >= 0; j-- {
+				if !points[j].Partial {
+					db.latest[hostID] = points[j]
+					break
+				}
+			}
+		}
+	}
+}
+
+// run 后台任务：定时 fsync 预写日志，落盘已结束的窗口，合并数据块并清理过期数据
+func (db *TimeSeriesDB) run() {
+	defer close(db.done)
+	syncTicker := time.NewTicker(walSyncInterval)
+	defer syncTicker.Stop()
+	maintainTicker := time.NewTicker(maintainInterval)
+	defer maintainTicker.Stop()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-syncTicker.C:
+			db.syncWal()
+		case <-maintainTicker.C:
+			db.maintain(time.Now())
+		}
+	}
+}
+
+func (db *TimeSeriesDB) syncWal() {
+	db.Lock()
+	defer db.Unlock()
+	for _, w := range db.windows {
+		for _, wal := range w.wals {
+			if err := wal.sync(); err != nil {
+				fmt.Printf("[时序数据库] 同步预写日志 %s 失败: %v\n", wal.path, err)
+			}
+		}
+	}
+}
+
+// maintain 执行一次落盘、合并和过期清理
+func (db *TimeSeriesDB) maintain(now time.Time) {
+	if db.opts.Dir == "" {
+		db.dropExpiredWindows(now)
+		return
+	}
+	db.flushWindows(now)
+	compactSet(db.raw, math.MaxInt64, pointTime, mergePoints)
+	// 降采样数据块只在覆盖的时间内不再有窗口落盘后合并
+	sealedBefore := now.Add(-db.opts.OutOfOrder).Unix()
+	for _, set := range db.rollups {
+		compactSet(set, sealedBefore, rollupTime, mergeRollups)
+	}
+	db.raw.dropBefore(now.Add(-db.opts.Retention).Unix())
+	for _, set := range db.rollups {
+		set.dropBefore(now.Add(-db.opts.RollupRetention).Unix())
+	}
+}
+
+// dropExpiredWindows 内存模式下删除超出保留时间的窗口
+func (db *TimeSeriesDB) dropExpiredWindows(now time.Time) {
+	cutoff := now.Add(-db.opts.Retention).Unix()
+	db.Lock()
+	defer db.Unlock()
+	for start := range db.windows {
+		if start+db.blockSecs <= cutoff {
+			delete(db.windows, start)
+		}
+	}
+}
+
+// flushWindows 把已结束且超过迟到等待时间的窗口写成数据块，然后删除对应的预写日志
+func (db *TimeSeriesDB) flushWindows(now time.Time) {
+	deadline := now.Add(-db.opts.OutOfOrder).Unix()
+
+	db.Lock()
+	var ready []*window
+	for start, w := range db.windows {
+		if start+db.blockSecs <= deadline {
+			ready = append(ready, w)
+			delete(db.windows, start)
+		}
+	}
+	db.flushing = append(db.flushing, ready...)
+	db.Unlock()
+
+	for _, w := range ready {
+		if err := db.flushWindow(w); err != nil {
+			// 落盘失败时放回内存，预写日志仍在，下次重试
+			fmt.Printf("[时序数据库] 窗口 %d 落盘失败: %v\n", w.start, err)
+			db.Lock()
+			db.removeFlushing(w)
+			if existing, ok := db.windows[w.start]; ok {
+				for _, list := range w.points {
+					for _, point := range list {
+						if !containsPoint(existing.points[point.HostID], point.CollectedAt) {
+							db.insertLocked(point, false)
+						}
+					}
+				}
+				existing.wals = append(w.wals, existing.wals...)
+			} else {
+				db.windows[w.start] = w
+			}
+			db.Unlock()
+		}
+	}
+}
+
+// flushWindow 写入原始数据块和各粒度的降采样数据块，全部写完后一起对查询可见，再删除预写日志。
+// 窗口已经落盘过时（迟到数据），降采样数据按合并后的原始数据重新汇总，覆盖已落盘的间隔
+func (db *TimeSeriesDB) flushWindow(w *window) error {
+	rollupPoints := w.points
+	if len(db.raw.window(w.start)) > 0 {
+		merged, err := db.flushedWindow(w.start, w.points)
+		if err != nil {
+			return err
+		}
+		rollupPoints = merged
+	}
+
+	rawMeta, err := writeNext(db.raw, w.start, w.points, pointTime)
+	if err != nil {
+		return err
+	}
+	rollupMetas, err := db.writeRollups(rollupPoints)
+	if err != nil {
+		os.Remove(rawMeta.path)
+		return err
+	}
+
+	db.Lock()
+	db.raw.add(rawMeta)
+	for i, list := range rollupMetas {
+		for _, meta := range list {
+			db.rollups[i].add(meta)
+		}
+	}
+	db.removeFlushing(w)
+	db.Unlock()
+
+	for _, wal := range w.wals {
+		if err := wal.remove(); err != nil {
+			fmt.Printf("[时序数据库] 删除预写日志 %s 失败: %v\n", wal.path, err)
+		}
+	}
+	return nil
+}
+
+// flushedWindow 读取已落盘窗口的原始数据并与 points 合并，同一时间 points 优先；
+// points 为 nil 时读取窗口中所有主机的数据，否则只读取 points 中的主机
+func (db *TimeSeriesDB) flushedWindow(start int64, points map[uint64][]*MetricPoint) (map[uint64][]*MetricPoint, error) {
+	blocks := db.raw.window(start)
+	merged := make(map[uint64][]*MetricPoint)
+	for _, block := range blocks {
+		for hostID := range block.index.Hosts {
+			if points != nil && points[hostID] == nil {
+				continue
+			}
+			list, err := readBlockHost[*MetricPoint](block, hostID)
+			if err != nil {
+				return nil, err
+			}
+			merged[hostID] = mergePoints(merged[hostID], list)
+		}
+	}
+	for hostID, list := range points {
+		merged[hostID] = mergePoints(merged[hostID], list)
+	}
+	return merged, nil
+}
+
+// writeRollups 按各粒度汇总并写入数据块，返回值按 RollupTiers 的顺序分组
+func (db *TimeSeriesDB) writeRollups(points map[uint64][]*MetricPoint) ([][]*blockMeta, error) {
+	metas := make([][]*blockMeta, len(RollupTiers))
+	removeWritten := func() {
+		for _, list := range metas {
+			for _, meta := range list {
+				os.Remove(meta.path)
+			}
+		}
+	}
+	for i, tier := range RollupTiers {
+		set := db.rollups[i]
+		// 原始窗口可能跨越降采样数据块的边界，按数据块分组
+		parts := make(map[int64]map[uint64][]*RollupPoint)
+		for hostID, list := range points {
+			for _, p := range buildRollups(hostID, list, tier.Step) {
+				start := set.windowStart(p.Time)
+				if parts[start] == nil {
+					parts[start] = make(map[uint64][]*RollupPoint)
+				}
+				parts[start][hostID] = append(parts[start][hostID], p)
+			}
+		}
+		for start, items := range parts {
+			meta, err := writeNext(set, start, items, rollupTime)
+			if err != nil {
+				removeWritten()
+				return nil, err
+			}
+			metas[i] = append(metas[i], meta)
+		}
+	}
+	return metas, nil
+}
+
+func (db *TimeSeriesDB) removeFlushing(w *window) {
+	for i, f := range db.flushing {
+		if f == w {
+			db.flushing = append(db.flushing[:i], db.flushing[i+1:]...)
+			return
+		}
+	}
+}
+
+// headPoints 内存中尚未落盘的数据点（包括正在落盘的窗口），按时间升序
+func (db *TimeSeriesDB) headPoints(hostID uint64, start, end int64) []*MetricPoint {
+	db.RLock()
+	defer db.RUnlock()
+	var points []*MetricPoint
+	collect := func(w *window) {
+		for _, point := range w.points[hostID] {
+			if point.CollectedAt >= start && point.CollectedAt <= end {
+				points = append(points, point)
+			}
+		}
+	}
+	for _, w := range db.flushing {
+		collect(w)
+	}
+	for _, w := range db.windows {
+		collect(w)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].CollectedAt < points[j].CollectedAt })
+	return points
+}
+
+func containsPoint(list []*MetricPoint, ts int64) bool {
+	i := sort.Search(len(list), func(i int) bool { return list[i].CollectedAt >= ts })
+	return i < len(list) && list[i].CollectedAt == ts
+}
+
+// mergePoints 合并两个按时间升序的列表，时间相同时取 newer 中的数据点，自定义指标合并
+func mergePoints(older, newer []*MetricPoint) []*MetricPoint {
+	result := make([]*MetricPoint, 0, len(older)+len(newer))
+	i, j := 0, 0
+	for i < len(older) && j < len(newer) {
+		switch {
+		case older[i].CollectedAt < newer[j].CollectedAt:
+			result = append(result, older[i])
+			i++
+		case older[i].CollectedAt > newer[j].CollectedAt:
+			result = append(result, newer[j])
+			j++
+		default:
+			result = append(result, mergePoint(older[i], newer[j]))
+			i++
+			j++
+		}
+	}
+	result = append(result, older[i:]...)
+	return append(result, newer[j:]...)
+}
+
+// mergePoint 同一时间的两个数据点：只有自定义指标的数据点不覆盖 agent 上报的数据
+func mergePoint(older, newer *MetricPoint) *MetricPoint {
+	if newer.Partial && !older.Partial {
+		merged := *older
+		merged.Custom = mergeCustom(older.Custom, newer.Custom)
+		return &merged
+	}
+	merged := *newer
+	merged.Custom = mergeCustom(older.Custom, newer.Custom)
+	return &merged
+}
@@ -0,0 +1,192 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T, dir string) *TimeSeriesDB {
+	db, err := Open(Options{Dir: dir, Retention: 7 * 24 * time.Hour, BlockDuration: time.Hour, OutOfOrder: 5 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func testPoint(hostID uint64, ts int64, cpu float64) *MetricPoint {
+	point := &MetricPoint{HostID: hostID, CollectedAt: ts}
+	point.CPU.UsagePercent = cpu
+	return point
+}
+
+func TestStorageRecoverFlushAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().Unix()
+	base := now - now%3600 - 3*3600 // 三个窗口之前
+
+	db := openTestDB(t, dir)
+	for i := int64(0); i < 10; i++ {
+		db.Insert(testPoint(1, base+i*60, float64(i)))
+	}
+	db.Insert(testPoint(2, now, 50))
+	db.Close()
+
+	// 重启后由预写日志恢复
+	db = openTestDB(t, dir)
+	if got := db.Query(1, base, base+3600); len(got) != 10 || got[0].CPU.UsagePercent != 9 {
+		t.Fatalf("恢复后查询结果错误: %d 条", len(got))
+	}
+	if latest := db.GetLatest(2); latest == nil || latest.CPU.UsagePercent != 50 {
+		t.Fatalf("恢复后最新数据错误: %+v", latest)
+	}
+
+	// 落盘后预写日志删除，数据从数据块读取
+	db.maintain(time.Now())
+	if blocks, _ := filepath.Glob(filepath.Join(dir, blockDir, "*"+blockExt)); len(blocks) != 1 {
+		t.Fatalf("应生成 1 个数据块，实际 %d 个", len(blocks))
+	}
+	if got := db.Query(1, base, base+3600); len(got) != 10 {
+		t.Fatalf("落盘后查询结果错误: %d 条", len(got))
+	}
+
+	// 迟到数据覆盖已落盘的数据点，合并后只剩一个数据块
+	db.Insert(testPoint(1, base, 99))
+	db.Insert(testPoint(1, base+30, 1))
+	db.maintain(time.Now())
+	if blocks, _ := filepath.Glob(filepath.Join(dir, blockDir, "*"+blockExt)); len(blocks) != 1 {
+		t.Fatalf("合并后应只有 1 个数据块，实际 %d 个", len(blocks))
+	}
+	got := db.Query(1, base, base+3600)
+	if len(got) != 11 || got[len(got)-1].CPU.UsagePercent != 99 {
+		t.Fatalf("迟到数据处理错误: %d 条", len(got))
+	}
+	// 降采样数据按合并后的原始数据重新汇总，被覆盖的数据点不重复统计
+	var bucket *RollupPoint
+	readRange(db.rollups[0], 1, base, base, rollupTime, func(p *RollupPoint) { bucket = p })
+	if bucket == nil {
+		t.Fatal("缺少 1m 降采样数据")
+	}
+	if cpu := bucket.Fields["cpu.usagePercent"]; cpu.Count != 2 || cpu.Min != 1 || cpu.Max != 99 {
+		t.Fatalf("迟到数据的降采样结果错误: %+v", cpu)
+	}
+	db.Close()
+
+	// 重启后从数据块恢复最新数据，超出保留时间的数据块被删除
+	db = openTestDB(t, dir)
+	defer db.Close()
+	if latest := db.GetLatest(1); latest == nil || latest.CollectedAt != base+540 {
+		t.Fatalf("从数据块恢复最新数据错误: %+v", latest)
+	}
+	db.maintain(time.Now().Add(8 * 24 * time.Hour))
+	if blocks, _ := filepath.Glob(filepath.Join(dir, blockDir, "*"+blockExt)); len(blocks) != 0 {
+		t.Fatalf("过期数据块未删除: %v", blocks)
+	}
+}
+
+func TestWalTruncatedRecord(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir)
+	now := time.Now().Unix()
+	db.Insert(testPoint(1, now-10, 1))
+	db.Insert(testPoint(1, now, 2))
+	db.Close()
+
+	// 模拟崩溃时最后一条记录没有写完
+	segments, _ := filepath.Glob(filepath.Join(dir, walDir, "*"+walExt))
+	if len(segments) == 0 {
+		t.Fatal("没有预写日志")
+	}
+	info, _ := os.Stat(segments[len(segments)-1])
+	os.Truncate(segments[len(segments)-1], info.Size()-3)
+
+	db = openTestDB(t, dir)
+	defer db.Close()
+	if got := db.Query(1, 0, now+10); len(got) != 1 {
+		t.Fatalf("应恢复 1 条完整记录，实际 %d 条", len(got))
+	}
+}
+
+func TestRollupQueryRange(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(Options{Dir: dir, Retention: 6 * time.Hour, RollupRetention: 30 * 24 * time.Hour, BlockDuration: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now().Unix()
+	// 按 2 小时对齐，两小时的数据在 7 天范围的查询中落入同一个间隔
+	base := now - now%7200 - 4*3600
+	// 两个小时的数据，CPU 使用率依次为 0..1439，第二个网卡只在前一半时间出现
+	for i := int64(0); i < 1440; i++ {
+		point := testPoint(1, base+i*5, float64(i))
+		point.Disk.UsagePercent = "50.5"
+		point.Network.Interfaces = []InterfaceStats{{Name: "eth0", RecvRate: 1}}
+		if i < 720 {
+			point.Network.Interfaces = append(point.Network.Interfaces, InterfaceStats{Name: "eth1", RecvRate: 2})
+		}
+		db.Insert(point)
+	}
+	db.maintain(time.Now())
+	if result := db.QueryRange(1, base, base+3600-1, 720); result.Tier != "raw" || len(result.Points) != 720 {
+		t.Fatalf("短范围应使用原始数据: tier=%s points=%d", result.Tier, len(result.Points))
+	}
+	// 原始数据块过期删除，只剩降采样数据
+	db.maintain(time.Now().Add(8 * time.Hour))
+
+	// 每 10 分钟一个点，使用 5m 粒度再合并
+	result := db.QueryRange(1, base, base+2*3600-1, 12)
+	if result.Tier != "5m" || result.Step != 600 || len(result.Points) != 12 {
+		t.Fatalf("粒度选择错误: tier=%s step=%d points=%d", result.Tier, result.Step, len(result.Points))
+	}
+	cpu := result.Points[0].Fields["cpu.usagePercent"]
+	if cpu.Min != 0 || cpu.Max != 119 || cpu.Last != 119 || cpu.Count != 120 || cpu.Avg() != 59.5 {
+		t.Fatalf("汇总结果错误: %+v", cpu)
+	}
+	if disk := result.Points[0].Fields["disk.usagePercent"]; disk.Avg() != 50.5 {
+		t.Fatalf("字符串数值字段汇总错误: %+v", disk)
+	}
+	if _, ok := result.Points[11].Fields["network.interfaces[eth1].recvRate"]; ok {
+		t.Fatal("eth1 不应出现在后一小时")
+	}
+	if eth0 := result.Points[11].Fields["network.interfaces[eth0].recvRate"]; eth0.Count != 120 {
+		t.Fatalf("eth0 汇总错误: %+v", eth0)
+	}
+
+	// 范围更长时选择更粗的粒度
+	result = db.QueryRange(1, base-7*24*3600, base+2*3600, 100)
+	if result.Tier != "1h" || result.Step != 7200 || len(result.Points) != 1 {
+		t.Fatalf("7 天范围应使用 1h 粒度: tier=%s step=%d points=%d", result.Tier, result.Step, len(result.Points))
+	}
+	if cpu := result.Points[0].Fields["cpu.usagePercent"]; cpu.Count != 1440 || cpu.Max != 1439 {
+		t.Fatalf("1h 粒度汇总错误: %+v", cpu)
+	}
+}
+
+func TestPartialPoints(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().Unix()
+	base := now - now%3600 - 3*3600
+
+	db := openTestDB(t, dir)
+	db.Insert(testPoint(1, base, 10))
+	if !db.InsertCustom(1, base+5, map[string]float64{"queue_depth": 3}) {
+		t.Fatal("未落盘的窗口应接受推送")
+	}
+	db.maintain(time.Now())
+	// 只推送自定义指标的主机没有最新数据，但可以查询
+	db.InsertCustom(2, now, map[string]float64{"queue_depth": 5})
+	if got := db.Query(2, now-60, now+60); len(got) != 1 || got[0].Custom["queue_depth"] != 5 {
+		t.Fatalf("只有自定义指标的主机查询错误: %d 条", len(got))
+	}
+	db.Close()
+
+	// 从数据块恢复最新数据时跳过只有自定义指标的数据点
+	db = openTestDB(t, dir)
+	defer db.Close()
+	if latest := db.GetLatest(1); latest == nil || latest.CollectedAt != base || latest.CPU.UsagePercent != 10 {
+		t.Fatalf("最新数据应为 agent 上报的数据点: %+v", latest)
+	}
+}
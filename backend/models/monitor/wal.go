@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 预写日志：每个时间窗口的段文件 wal/<窗口起始时间>-<创建时间>.wal，窗口落盘为数据块后删除。
+// 窗口正在落盘时收到的迟到数据写入新的段文件，所以同一窗口可能有多个段。
+// 记录格式：4 字节长度 + 4 字节 CRC32 + JSON 编码的数据点，启动时重放，遇到损坏的记录截断文件。
+
+const (
+	walDir        = "wal"
+	walExt        = ".wal"
+	walHeaderSize = 8
+	maxRecordSize = 16 << 20
+)
+
+// walSegment 一个窗口的日志段
+type walSegment struct {
+	path  string
+	file  *os.File
+	dirty bool // 有未 fsync 的写入
+}
+
+func walPath(dir string, windowStart, created int64) string {
+	return filepath.Join(dir, walDir, fmt.Sprintf("%d-%d%s", windowStart, created, walExt))
+}
+
+func openWalSegment(path string) (*walSegment, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &walSegment{path: path, file: file}, nil
+}
+
+// append 写入一条记录，fsync 由后台定时完成
+func (w *walSegment) append(point *MetricPoint) error {
+	payload, err := json.Marshal(point)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, walHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[walHeaderSize:], payload)
+	if _, err := w.file.Write(buf); err != nil {
+		return err
+	}
+	w.dirty = true
+	return nil
+}
+
+func (w *walSegment) sync() error {
+	if !w.dirty {
+		return nil
+	}
+	w.dirty = false
+	return w.file.Sync()
+}
+
+func (w *walSegment) close() error {
+	w.sync()
+	return w.file.Close()
+}
+
+// remove 窗口落盘后删除日志段
+func (w *walSegment) remove() error {
+	w.file.Close()
+	return os.Remove(w.path)
+}
+
+// listWalSegments 返回日志目录中所有段的路径，按窗口起始时间分组
+func listWalSegments(dir string) (map[int64][]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, walDir))
+	if err != nil {
+		return nil, err
+	}
+	segments := make(map[int64][]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, walExt) {
+			continue
+		}
+		start, err := strconv.ParseInt(strings.SplitN(strings.TrimSuffix(name, walExt), "-", 2)[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		segments[start] = append(segments[start], filepath.Join(dir, walDir, name))
+	}
+	// 文件名中的创建时间位数相同，按名称排序即按创建顺序
+	for _, paths := range segments {
+		sort.Strings(paths)
+	}
+	return segments, nil
+}
+
+// replayWal 读取日志段中的所有记录，末尾不完整或校验失败的记录视为崩溃时未写完，截断丢弃
+func replayWal(path string, fn func(*MetricPoint)) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	header := make([]byte, walHeaderSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			break
+		}
+		size := binary.LittleEndian.Uint32(header[0:4])
+		if size > maxRecordSize {
+			break
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+			break
+		}
+		var point MetricPoint
+		if err := json.Unmarshal(payload, &point); err != nil {
+			break
+		}
+		fn(&point)
+		offset += walHeaderSize + int64(size)
+	}
+
+	fmt.Printf("[时序数据库] 预写日志 %s 在偏移 %d 处损坏，截断后续内容\n", path, offset)
+	if err := file.Truncate(offset); err != nil {
+		return err
+	}
+	return file.Sync()
+}
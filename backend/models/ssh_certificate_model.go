@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// 证书用途
+const (
+	CertPurposeTerminal = "terminal" // Web 终端、文件管理、端口转发
+	CertPurposeTask     = "task"     // ansible 任务
+	CertPurposePlatform = "platform" // 平台自身的连接，如获取主机密钥
+)
+
+// SSHCertificateModel 平台 CA 签发的 SSH 证书记录
+type SSHCertificateModel struct {
+	MODEL
+	Serial      uint64    `gorm:"index;comment:证书序列号" json:"serial"`       // 证书序列号
+	KeyID       string    `gorm:"size:128;comment:证书ID" json:"keyId"`      // 写入 sshd 日志的证书标识
+	Principals  string    `gorm:"size:256;comment:证书主体" json:"principals"` // 逗号分隔
+	Purpose     string    `gorm:"size:16;comment:用途" json:"purpose"`       // terminal / task / platform
+	UserID      uint      `gorm:"index;comment:用户ID" json:"userId"`        // 申请的用户，平台自身为 0
+	Username    string    `gorm:"size:36;comment:用户名" json:"username"`     // 用户名
+	HostID      uint      `gorm:"index;comment:主机ID" json:"hostId"`        // 目标主机，任务证书为 0
+	TaskID      uint      `gorm:"index;comment:任务ID" json:"taskId"`        // 任务证书对应的任务
+	ValidAfter  time.Time `gorm:"comment:生效时间" json:"validAfter"`          // 生效时间
+	ValidBefore time.Time `gorm:"index;comment:失效时间" json:"validBefore"`   // 失效时间
+}
@@ -0,0 +1,11 @@
+package models
+
+// TaskOutputChunkModel 任务输出分块存储，每块为若干行 gzip 压缩后的内容，按行号顺序排列
+type TaskOutputChunkModel struct {
+	MODEL
+	TaskID     uint   `gorm:"not null;index:idx_task_chunk,priority:1;comment:任务ID" json:"taskId"`     // 关联任务表
+	ChunkIndex int    `gorm:"not null;index:idx_task_chunk,priority:2;comment:分块序号" json:"chunkIndex"` // 分块序号，从0开始
+	StartLine  int    `gorm:"not null;comment:起始行号" json:"startLine"`                                  // 本块第一行的行号，从0开始
+	LineCount  int    `gorm:"not null;comment:行数" json:"lineCount"`                                    // 本块包含的行数
+	Data       []byte `gorm:"type:mediumblob;comment:gzip压缩的输出" json:"-"`                              // gzip 压缩后的输出，行之间以 \n 分隔
+}
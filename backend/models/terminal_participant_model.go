@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// 加入终端会话的方式
+const (
+	TerminalModeOwner     = "owner"      // 会话创建者
+	TerminalModeReadOnly  = "read_only"  // 只读观察
+	TerminalModeReadWrite = "read_write" // 共同操作
+)
+
+// TerminalParticipantModel 终端会话参与记录，每次挂载（包括创建者重连）一条
+type TerminalParticipantModel struct {
+	MODEL
+	SessionID uint       `gorm:"index;comment:终端会话ID" json:"sessionId"` // 对应 TerminalSessionModel
+	UserID    uint       `gorm:"index;comment:用户ID" json:"userId"`      // 加入的用户
+	Username  string     `gorm:"size:36;comment:用户名" json:"username"`   // 用户名
+	ClientIP  string     `gorm:"size:64;comment:客户端IP" json:"clientIp"` // 客户端IP
+	Mode      string     `gorm:"size:16;comment:加入方式" json:"mode"`      // owner / read_only / read_write
+	JoinTime  time.Time  `gorm:"comment:加入时间" json:"joinTime"`          // 加入时间
+	LeaveTime *time.Time `gorm:"comment:离开时间" json:"leaveTime"`         // 离开时间，仍在会话中为空
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// TerminalSessionModel Web 终端会话审计记录
+type TerminalSessionModel struct {
+	MODEL
+	UserID      uint       `gorm:"index;comment:用户ID" json:"userId"`                // 打开终端的用户
+	Username    string     `gorm:"size:36;comment:用户名" json:"username"`             // 用户名，用户删除后仍可追溯
+	HostID      uint       `gorm:"index;comment:主机ID" json:"hostId"`                // 目标主机
+	HostName    string     `gorm:"size:36;comment:主机名称" json:"hostName"`            // 主机名称
+	HostIP      string     `gorm:"size:128;comment:主机地址" json:"hostIp"`             // 主机地址
+	ClientIP    string     `gorm:"size:64;comment:客户端IP" json:"clientIp"`           // 客户端IP
+	StartTime   time.Time  `gorm:"index;comment:开始时间" json:"startTime"`             // 开始时间
+	EndTime     *time.Time `gorm:"comment:结束时间" json:"endTime"`                     // 结束时间，会话进行中为空
+	ExitReason  string     `gorm:"size:256;comment:结束原因" json:"exitReason"`         // 结束原因
+	BroadcastID string     `gorm:"size:32;index;comment:广播终端ID" json:"broadcastId"` // 广播终端同一批次的会话ID相同
+
+	RecordingPath      string `gorm:"size:256;comment:录像文件路径" json:"-"`                    // asciicast v2 录像文件
+	RecordingSize      int64  `gorm:"comment:录像大小" json:"recordingSize"`                   // 录像大小（字节）
+	RecordingTruncated bool   `gorm:"default:0;comment:录像是否被截断" json:"recordingTruncated"` // 超出大小限制后停止录制
+}
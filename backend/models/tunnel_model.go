@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// TunnelModel 经由平台的 SSH 端口转发，同时作为审计记录
+type TunnelModel struct {
+	MODEL
+	UserID      uint       `gorm:"index;comment:用户ID" json:"userId"`          // 申请的用户
+	Username    string     `gorm:"size:36;comment:用户名" json:"username"`       // 用户名
+	HostID      uint       `gorm:"index;comment:主机ID" json:"hostId"`          // 建立 SSH 连接的主机
+	HostName    string     `gorm:"size:36;comment:主机名称" json:"hostName"`      // 主机名称
+	TargetHost  string     `gorm:"size:128;comment:目标地址" json:"targetHost"`   // 从主机上看到的目标地址，默认 127.0.0.1
+	TargetPort  int        `gorm:"comment:目标端口" json:"targetPort"`            // 目标端口
+	Token       string     `gorm:"size:64;uniqueIndex;comment:连接令牌" json:"-"` // 本地助手连接时使用
+	Reason      string     `gorm:"size:256;comment:用途" json:"reason"`         // 申请用途
+	ClientIP    string     `gorm:"size:64;comment:申请时的客户端IP" json:"clientIp"` // 申请时的客户端IP
+	ExpiresAt   time.Time  `gorm:"index;comment:过期时间" json:"expiresAt"`       // 过期时间
+	ClosedAt    *time.Time `gorm:"comment:关闭时间" json:"closedAt"`              // 提前关闭的时间
+	Connections int        `gorm:"comment:连接次数" json:"connections"`           // 累计连接次数
+	BytesIn     int64      `gorm:"comment:上行字节数" json:"bytesIn"`              // 本地发往目标的字节数
+	BytesOut    int64      `gorm:"comment:下行字节数" json:"bytesOut"`             // 目标发回本地的字节数
+	LastUsedAt  *time.Time `gorm:"comment:最后使用时间" json:"lastUsedAt"`          // 最后一次连接的时间
+}
+
+// Active 是否仍可连接
+func (t TunnelModel) Active() bool {
+	return t.ClosedAt == nil && time.Now().Before(t.ExpiresAt)
+}
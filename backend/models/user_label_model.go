@@ -8,4 +8,6 @@ type UserLabels struct {
 
 	LabelID uint       `gorm:"not null;comment:标签ID" json:"label_id"`
 	Label   LabelModel `gorm:"foreignKey:LabelID" json:"label"`
+
+	Terminal bool `gorm:"default:0;comment:是否允许打开终端" json:"terminal"` // 标签下主机的终端权限
 }
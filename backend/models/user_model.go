@@ -18,4 +18,7 @@ type UserModel struct {
 type UserPermission struct {
 	HostIds  []uint `json:"hostIds"`
 	LabelIds []uint `json:"labelIds"` // 新增标签ID列表
+
+	TerminalHostIds  []uint `json:"terminalHostIds"`  // 允许打开终端的主机，同时具有查看权限
+	TerminalLabelIds []uint `json:"terminalLabelIds"` // 允许打开终端的标签，同时具有查看权限
 }
@@ -0,0 +1,18 @@
+package router
+
+import (
+	"ccops/api"
+	"ccops/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (router RouterGroup) AgentConfigRouter(agentConfigRouterGroup *gin.RouterGroup) {
+	app := api.ApiGroupApp.AgentConfigApi
+	agentConfigRouterGroup.Use(middleware.JwtUser())
+	agentConfigRouterGroup.GET("", app.AgentConfigListView)
+	agentConfigRouterGroup.POST("", app.AgentConfigCreateView)
+	agentConfigRouterGroup.PUT("/:id", app.AgentConfigUpdateView)
+	agentConfigRouterGroup.DELETE("/:id", app.AgentConfigRemoveView)
+	agentConfigRouterGroup.GET("hosts/:id", app.AgentConfigHostView)
+}
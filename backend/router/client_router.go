@@ -10,5 +10,8 @@ func (router RouterGroup) ClientRouter(clientRouterGroup *gin.RouterGroup) {
 	app := api.ApiGroupApp.ClientApi
 	clientRouterGroup.POST("receive", app.ClientInfoReceive)
 	clientRouterGroup.GET("public_key", app.GetPublicKey)
+	clientRouterGroup.GET("agent_config", app.GetAgentConfig)
 	clientRouterGroup.POST("metrics", app.ClientMetricsReceive)
+	clientRouterGroup.POST("metrics/batch", app.ClientMetricsBatchReceive)
+	clientRouterGroup.POST("custom_metrics", app.ClientCustomMetricsReceive)
 }
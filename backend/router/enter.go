@@ -16,6 +16,10 @@ type RouterGroup struct {
 func InitRouter() *gin.Engine {
 	gin.SetMode(global.Config.System.Env)
 	router := gin.Default()
+	// 只采用信任的反向代理转发的客户端地址，未配置时使用连接的来源地址
+	if err := router.SetTrustedProxies(global.Config.System.TrustedProxies); err != nil {
+		global.Log.Fatalf("信任的代理地址配置错误: %v", err)
+	}
 
 	router.Use(cors.New(cors.Config{
 		AllowAllOrigins: true,                                     // 开放所有请求源
@@ -43,6 +47,14 @@ func InitRouter() *gin.Engine {
 	configurationRouterGroup := apiRouterGroup.Group("configurations")
 	ruleRouterGroup := apiRouterGroup.Group("alert_rules")
 	notificationRouterGroup := apiRouterGroup.Group("notifications")
+	jumpHostRouterGroup := apiRouterGroup.Group("jump_hosts")
+	inventoryRouterGroup := apiRouterGroup.Group("inventory")
+	terminalRouterGroup := apiRouterGroup.Group("terminals")
+	sftpRouterGroup := apiRouterGroup.Group("sftp")
+	tunnelRouterGroup := apiRouterGroup.Group("tunnels")
+	metricsRouterGroup := apiRouterGroup.Group("metrics")
+	prometheusRouterGroup := apiRouterGroup.Group("v1")
+	agentConfigRouterGroup := apiRouterGroup.Group("agent_configs")
 	routerGroupApp := RouterGroup{apiRouterGroup}
 
 	// 使用不同的路由组
@@ -58,6 +70,14 @@ func InitRouter() *gin.Engine {
 	routerGroupApp.AuthRouter(authRouterGroup)
 	routerGroupApp.RulesRouter(ruleRouterGroup)
 	routerGroupApp.NotificationRouter(notificationRouterGroup)
+	routerGroupApp.JumpHostRouter(jumpHostRouterGroup)
+	routerGroupApp.InventoryRouter(inventoryRouterGroup)
+	routerGroupApp.TerminalRouter(terminalRouterGroup)
+	routerGroupApp.SftpRouter(sftpRouterGroup)
+	routerGroupApp.TunnelRouter(tunnelRouterGroup)
+	routerGroupApp.MetricsRouter(metricsRouterGroup)
+	routerGroupApp.PrometheusRouter(prometheusRouterGroup, &router.RouterGroup)
+	routerGroupApp.AgentConfigRouter(agentConfigRouterGroup)
 
 	return router
 }
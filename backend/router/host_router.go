@@ -19,6 +19,10 @@ func (router RouterGroup) HostRouter(hostRouterGroup *gin.RouterGroup) {
 	hostRouterGroup.POST("refresh", app.HostFlushInfoView)
 	hostRouterGroup.POST("rename", app.HostRename)
 	hostRouterGroup.POST("assign_labels", app.AssignLabelsToHost)
+	hostRouterGroup.GET("/:id/host_keys", app.HostKeyListView)
+	hostRouterGroup.POST("/:id/host_key/accept", app.HostKeyAcceptView)
+
+	hostRouterGroup.GET("monitor/latest", app.GetLatestMonitorData)
 
 	hostRouterGroup.GET("me", app.PermissionHosts)
 	hostRouterGroup.GET("search", app.HostSearch)
@@ -0,0 +1,14 @@
+package router
+
+import (
+	"ccops/api"
+	"ccops/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (router RouterGroup) InventoryRouter(inventoryRouterGroup *gin.RouterGroup) {
+	app := api.ApiGroupApp.InventoryApi
+	inventoryRouterGroup.Use(middleware.JwtUser())
+	inventoryRouterGroup.GET("", app.InventoryView)
+}
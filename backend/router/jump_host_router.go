@@ -0,0 +1,20 @@
+package router
+
+import (
+	"ccops/api"
+	"ccops/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (router RouterGroup) JumpHostRouter(jumpHostRouterGroup *gin.RouterGroup) {
+	app := api.ApiGroupApp.JumpHostApi
+	jumpHostRouterGroup.Use(middleware.JwtUser())
+	jumpHostRouterGroup.POST("", app.JumpHostCreateView)
+	jumpHostRouterGroup.GET("", app.JumpHostListView)
+	jumpHostRouterGroup.PUT("/:id", app.JumpHostUpdateView)
+	jumpHostRouterGroup.DELETE("/:id", app.JumpHostRemoveView)
+	jumpHostRouterGroup.POST("assign", app.JumpHostAssignView)
+	jumpHostRouterGroup.GET("/:id/host_keys", app.JumpHostKeyListView)
+	jumpHostRouterGroup.POST("/:id/host_key/accept", app.JumpHostKeyAcceptView)
+}
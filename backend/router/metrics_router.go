@@ -0,0 +1,15 @@
+package router
+
+import (
+	"ccops/api"
+	"ccops/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (router RouterGroup) MetricsRouter(metricsRouterGroup *gin.RouterGroup) {
+	app := api.ApiGroupApp.MetricsApi
+	metricsRouterGroup.Use(middleware.JwtUser())
+	metricsRouterGroup.POST("query", app.MetricsQueryView)
+	metricsRouterGroup.GET("names", app.MetricsNamesView)
+}
@@ -0,0 +1,27 @@
+package router
+
+import (
+	"ccops/api"
+	"ccops/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrometheusRouter Prometheus 兼容接口：根路径下的 /metrics，以及 /api/v1 下的远程写入和查询接口
+func (router RouterGroup) PrometheusRouter(prometheusRouterGroup *gin.RouterGroup, rootRouterGroup *gin.RouterGroup) {
+	app := api.ApiGroupApp.PrometheusApi
+	rootRouterGroup.GET("metrics", middleware.PrometheusAuth(), app.MetricsView)
+
+	prometheusRouterGroup.Use(middleware.PrometheusAuth())
+	prometheusRouterGroup.POST("write", app.RemoteWriteView)
+	prometheusRouterGroup.GET("query", app.QueryView)
+	prometheusRouterGroup.POST("query", app.QueryView)
+	prometheusRouterGroup.GET("query_range", app.QueryRangeView)
+	prometheusRouterGroup.POST("query_range", app.QueryRangeView)
+	prometheusRouterGroup.GET("labels", app.LabelsView)
+	prometheusRouterGroup.POST("labels", app.LabelsView)
+	prometheusRouterGroup.GET("label/:name/values", app.LabelValuesView)
+	prometheusRouterGroup.GET("series", app.SeriesView)
+	prometheusRouterGroup.POST("series", app.SeriesView)
+	prometheusRouterGroup.GET("metadata", app.MetadataView)
+}
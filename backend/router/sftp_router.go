@@ -0,0 +1,21 @@
+package router
+
+import (
+	"ccops/api"
+	"ccops/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (router RouterGroup) SftpRouter(sftpRouterGroup *gin.RouterGroup) {
+	app := api.ApiGroupApp.SftpApi
+	sftpRouterGroup.Use(middleware.JwtUser())
+	sftpRouterGroup.GET("operations", app.SftpOperationListView)
+	sftpRouterGroup.GET("/:id/list", app.SftpListView)
+	sftpRouterGroup.GET("/:id/stat", app.SftpStatView)
+	sftpRouterGroup.GET("/:id/download", app.SftpDownloadView)
+	sftpRouterGroup.POST("/:id/upload", app.SftpUploadView)
+	sftpRouterGroup.POST("/:id/rename", app.SftpRenameView)
+	sftpRouterGroup.POST("/:id/chmod", app.SftpChmodView)
+	sftpRouterGroup.DELETE("/:id", app.SftpRemoveView)
+}
@@ -15,4 +15,6 @@ func (router RouterGroup) TaskRouter(taskRouterGroup *gin.RouterGroup) {
 	taskRouterGroup.GET("/:id", app.TaskInfoView)
 	taskRouterGroup.DELETE("/:id", app.TaskRemove)
 	taskRouterGroup.GET("/:id/message", app.WebSocketHandler)
+	taskRouterGroup.GET("/:id/logs", app.TaskLogView)
+	taskRouterGroup.GET("/:id/events", app.TaskEventView)
 }
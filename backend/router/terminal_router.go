@@ -0,0 +1,22 @@
+package router
+
+import (
+	"ccops/api"
+	"ccops/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (router RouterGroup) TerminalRouter(terminalRouterGroup *gin.RouterGroup) {
+	app := api.ApiGroupApp.TerminalApi
+	terminalRouterGroup.Use(middleware.JwtUser())
+	terminalRouterGroup.GET("sessions", app.TerminalSessionListView)
+	terminalRouterGroup.GET("sessions/:id/recording", app.TerminalRecordingView)
+	terminalRouterGroup.GET("sessions/:id/participants", app.TerminalParticipantListView)
+	terminalRouterGroup.GET("live", app.TerminalLiveListView)
+	terminalRouterGroup.GET("broadcast", app.TerminalBroadcastView)
+	terminalRouterGroup.GET("command_rules", app.CommandRuleListView)
+	terminalRouterGroup.POST("command_rules", app.CommandRuleCreateView)
+	terminalRouterGroup.PUT("command_rules/:id", app.CommandRuleUpdateView)
+	terminalRouterGroup.DELETE("command_rules/:id", app.CommandRuleRemoveView)
+}
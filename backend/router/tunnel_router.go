@@ -0,0 +1,18 @@
+package router
+
+import (
+	"ccops/api"
+	"ccops/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (router RouterGroup) TunnelRouter(tunnelRouterGroup *gin.RouterGroup) {
+	app := api.ApiGroupApp.TunnelApi
+	// 本地助手凭令牌连接，不经过登录校验
+	tunnelRouterGroup.GET("connect", app.TunnelConnectView)
+	tunnelRouterGroup.Use(middleware.JwtUser())
+	tunnelRouterGroup.POST("", app.TunnelCreateView)
+	tunnelRouterGroup.GET("", app.TunnelListView)
+	tunnelRouterGroup.DELETE("/:id", app.TunnelCloseView)
+}
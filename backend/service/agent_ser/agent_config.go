@@ -0,0 +1,127 @@
+package agent_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// 配置的间隔上限（秒）
+const maxAgentInterval = 24 * 60 * 60
+
+// ValidateAgentConfig 检查下发给 agent 的配置
+func ValidateAgentConfig(spec models.AgentConfigSpec) error {
+	intervals := map[string]int{
+		"采集间隔":     spec.CollectInterval,
+		"主机信息上报间隔": spec.ReportInterval,
+		"公钥检查间隔":   spec.PublicKeyInterval,
+	}
+	for name, seconds := range intervals {
+		if seconds < 0 || seconds > maxAgentInterval {
+			return fmt.Errorf("%s需在 0 到 %d 秒之间", name, maxAgentInterval)
+		}
+	}
+	switch spec.LogLevel {
+	case "", models.AgentLogDebug, models.AgentLogInfo, models.AgentLogWarn, models.AgentLogError:
+	default:
+		return fmt.Errorf("日志级别只能是 debug、info、warn 或 error")
+	}
+	if spec.Process != nil {
+		if spec.Process.TopN < 0 {
+			return fmt.Errorf("进程 TopN 不能为负数")
+		}
+		names := make(map[string]bool)
+		for _, watch := range spec.Process.Watch {
+			if watch.Name == "" {
+				return fmt.Errorf("进程监控项缺少名称")
+			}
+			if names[watch.Name] {
+				return fmt.Errorf("进程监控项 %s 重复", watch.Name)
+			}
+			names[watch.Name] = true
+			if watch.Process == "" && watch.Cmdline == "" {
+				return fmt.Errorf("进程监控项 %s 需要配置进程名或命令行", watch.Name)
+			}
+			if watch.Cmdline != "" {
+				if _, err := regexp.Compile(watch.Cmdline); err != nil {
+					return fmt.Errorf("进程监控项 %s 的命令行正则表达式错误: %v", watch.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveAgentConfig 对主机生效的配置，没有时返回 nil。
+// 指定主机的优先于指定标签的，指定标签的优先于对所有主机生效的，同一层级中 Priority 大的优先，再按 ID 小的优先
+func ResolveAgentConfig(hostID uint) *models.AgentConfigModel {
+	var configs []models.AgentConfigModel
+	global.DB.Where("enabled = ?", true).Order("id").Find(&configs)
+	if len(configs) == 0 {
+		return nil
+	}
+
+	var hostLabelIds []uint
+	global.DB.Model(&models.HostLabels{}).Where("host_model_id = ?", hostID).Pluck("label_model_id", &hostLabelIds)
+	hostLabels := make(map[uint]bool)
+	for _, id := range hostLabelIds {
+		hostLabels[id] = true
+	}
+
+	var best *models.AgentConfigModel
+	bestTier := -1
+	for i := range configs {
+		config := &configs[i]
+		tier := -1
+		switch {
+		case containsID(config.HostIds, hostID):
+			tier = 2
+		case containsAnyID(config.LabelIds, hostLabels):
+			tier = 1
+		case len(config.HostIds) == 0 && len(config.LabelIds) == 0:
+			tier = 0
+		}
+		if tier < 0 {
+			continue
+		}
+		if tier > bestTier || (tier == bestTier && config.Priority > best.Priority) {
+			best, bestTier = config, tier
+		}
+	}
+	return best
+}
+
+// ConfigVersion 配置内容的摘要，agent 据此判断配置是否变化；没有配置时为空
+func ConfigVersion(config *models.AgentConfigModel) string {
+	if config == nil {
+		return ""
+	}
+	data, _ := json.Marshal(struct {
+		ID   uint                   `json:"id"`
+		Spec models.AgentConfigSpec `json:"spec"`
+	}{config.ID, config.Config.Data()})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyID(ids []uint, set map[uint]bool) bool {
+	for _, v := range ids {
+		if set[v] {
+			return true
+		}
+	}
+	return false
+}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"strings"
 	"sync"
 	"time"
 
@@ -72,8 +74,18 @@ func (s *AlertService) getHostLabels(hostID uint64) ([]uint64, error) {
 	return labels, nil
 }
 
-// CheckMetrics 检查监控指标是否触发告警
+// CheckMetrics 检查 agent 上报的系统指标是否触发告警，自定义指标规则在推送时检查
 func (s *AlertService) CheckMetrics(metrics *monitor.MetricPoint) error {
+	return s.checkRules(metrics, false)
+}
+
+// CheckCustomMetrics 检查推送的自定义指标是否触发告警，metrics 中只有 Custom 有效
+func (s *AlertService) CheckCustomMetrics(metrics *monitor.MetricPoint) error {
+	return s.checkRules(metrics, true)
+}
+
+// checkRules 检查适用于主机的系统指标规则或自定义指标规则
+func (s *AlertService) checkRules(metrics *monitor.MetricPoint, custom bool) error {
 
 	// 获取主机所属的标签ID列表
 	hostLabels, err := s.getHostLabels(metrics.HostID)
@@ -90,9 +102,12 @@ func (s *AlertService) CheckMetrics(metrics *monitor.MetricPoint) error {
 
 	// 检查每个规则
 	for _, rule := range rules {
+		if (rule.Type == alert.RuleTypeCustom) != custom {
+			continue
+		}
 
 		// 获取指标值
-		value, err := s.getMetricValue(metrics, rule.Type)
+		value, err := s.getMetricValue(metrics, rule)
 		if err != nil {
 			log.Printf("获取指标值失败: %v", err)
 			continue
@@ -207,10 +222,10 @@ func (s *AlertService) isHostApplicable(tx *gorm.DB, hostID uint64, hostLabels [
 	return false, nil
 }
 
-// getMetricValue 获取指定类型的指标值
-func (s *AlertService) getMetricValue(metrics *monitor.MetricPoint, ruleType string) (float64, error) {
+// getMetricValue 获取规则对应的指标值
+func (s *AlertService) getMetricValue(metrics *monitor.MetricPoint, rule *alert.AlertRule) (float64, error) {
 	var value float64
-	switch ruleType {
+	switch rule.Type {
 	// CPU相关指标
 	case alert.RuleTypeCPUUsage:
 		value = metrics.CPU.UsagePercent
@@ -220,6 +235,12 @@ func (s *AlertService) getMetricValue(metrics *monitor.MetricPoint, ruleType str
 		value = metrics.CPU.Load5m
 	case alert.RuleTypeCPULoad15:
 		value = metrics.CPU.Load15m
+	case alert.RuleTypeCPUIowait:
+		value = metrics.CPU.IowaitPercent
+	case alert.RuleTypeCPUSteal:
+		value = metrics.CPU.StealPercent
+	case alert.RuleTypeContextSwitches:
+		value = metrics.CPU.ContextSwitches
 
 	// 内存相关指标
 	case alert.RuleTypeMemoryUsage:
@@ -228,6 +249,8 @@ func (s *AlertService) getMetricValue(metrics *monitor.MetricPoint, ruleType str
 		value = float64(metrics.Memory.AvailableBytes) / (1024 * 1024 * 1024) // 转换为GB
 	case alert.RuleTypeMemoryFree:
 		value = float64(metrics.Memory.FreeBytes) / (1024 * 1024 * 1024) // 转换为GB
+	case alert.RuleTypeSwapUsage:
+		value = metrics.Memory.SwapUsagePercent
 
 	// 磁盘相关指标
 	case alert.RuleTypeDiskUsage:
@@ -241,14 +264,17 @@ func (s *AlertService) getMetricValue(metrics *monitor.MetricPoint, ruleType str
 	case alert.RuleTypeDiskWriteIO:
 		value = float64(metrics.Disk.WriteRate) / (1024 * 1024) // 转换为MB/s
 	case alert.RuleTypeDiskVolume:
-		// 遍历所有分区，找到使用率最高的
-		var maxUsage float64
-		for _, volume := range metrics.Disk.Volumes {
-			if volume.UsagePercent > maxUsage {
-				maxUsage = volume.UsagePercent
-			}
+		v, err := volumeValue(metrics.Disk.Volumes, rule.Target, func(v monitor.DiskUsage) float64 { return v.UsagePercent })
+		if err != nil {
+			return 0, err
+		}
+		value = v
+	case alert.RuleTypeInodeUsage:
+		v, err := volumeValue(metrics.Disk.Volumes, rule.Target, func(v monitor.DiskUsage) float64 { return v.InodesUsagePercent })
+		if err != nil {
+			return 0, err
 		}
-		value = maxUsage
+		value = v
 
 	// 网络相关指标
 	case alert.RuleTypeNetInSpeed:
@@ -256,23 +282,17 @@ func (s *AlertService) getMetricValue(metrics *monitor.MetricPoint, ruleType str
 	case alert.RuleTypeNetOutSpeed:
 		value = metrics.Network.SendRate / (1024 * 1024) // 转换为MB/s
 	case alert.RuleTypeNetCardInSpeed:
-		// 遍历所有网卡，找到入站速率最高的
-		var maxRecvRate float64
-		for _, net := range metrics.Network.Interfaces {
-			if net.RecvRate > maxRecvRate {
-				maxRecvRate = net.RecvRate
-			}
+		v, err := interfaceValue(metrics.Network.Interfaces, rule.Target, func(i monitor.InterfaceStats) float64 { return i.RecvRate })
+		if err != nil {
+			return 0, err
 		}
-		value = maxRecvRate / (1024 * 1024) // 转换为MB/s
+		value = v / (1024 * 1024) // 转换为MB/s
 	case alert.RuleTypeNetCardOutSpeed:
-		// 遍历所有网卡，找到出站速率最高的
-		var maxSendRate float64
-		for _, net := range metrics.Network.Interfaces {
-			if net.SendRate > maxSendRate {
-				maxSendRate = net.SendRate
-			}
+		v, err := interfaceValue(metrics.Network.Interfaces, rule.Target, func(i monitor.InterfaceStats) float64 { return i.SendRate })
+		if err != nil {
+			return 0, err
 		}
-		value = maxSendRate / (1024 * 1024) // 转换为MB/s
+		value = v / (1024 * 1024) // 转换为MB/s
 	case alert.RuleTypeNetCardStatus:
 		// 检查是否有活跃的网卡
 		if len(metrics.Network.Interfaces) > 0 {
@@ -280,9 +300,92 @@ func (s *AlertService) getMetricValue(metrics *monitor.MetricPoint, ruleType str
 		} else {
 			value = 0 // 无网卡在线
 		}
+	case alert.RuleTypeTCPEstablished:
+		value = float64(metrics.Network.TCP.Established)
+	case alert.RuleTypeTCPTimeWait:
+		value = float64(metrics.Network.TCP.TimeWait)
+	case alert.RuleTypeTCPCloseWait:
+		value = float64(metrics.Network.TCP.CloseWait)
+
+	// 系统相关指标
+	case alert.RuleTypeOpenFiles:
+		value = metrics.System.OpenFilesPercent
+	case alert.RuleTypeUptime:
+		value = float64(metrics.System.UptimeSeconds)
+
+	// 进程相关指标，agent 对监控列表中的每一项都会上报（进程不存在时进程数为 0），
+	// 没有该项说明主机的监控列表中没有配置，不能当作进程不存在
+	case alert.RuleTypeProcess, alert.RuleTypeProcessCPU, alert.RuleTypeProcessMemory, alert.RuleTypeProcessFDs:
+		var watch *monitor.ProcessWatchStats
+		for i := range metrics.Processes.Watch {
+			if metrics.Processes.Watch[i].Name == rule.Target {
+				watch = &metrics.Processes.Watch[i]
+				break
+			}
+		}
+		if watch == nil {
+			return 0, fmt.Errorf("进程监控列表中没有 %s", rule.Target)
+		}
+		switch rule.Type {
+		case alert.RuleTypeProcess:
+			value = float64(watch.Count)
+		case alert.RuleTypeProcessCPU:
+			value = watch.CPUPercent
+		case alert.RuleTypeProcessMemory:
+			value = float64(watch.RSSBytes) / (1024 * 1024) // 转换为MB
+		case alert.RuleTypeProcessFDs:
+			value = float64(watch.NumFDs)
+		}
+
+	// 自定义指标，Target 不带标签时取同名序列中的最大值
+	case alert.RuleTypeCustom:
+		v, ok := metrics.Custom[rule.Target]
+		if !ok && !strings.Contains(rule.Target, "{") {
+			for key, custom := range metrics.Custom {
+				if strings.HasPrefix(key, rule.Target+"{") && (!ok || custom > v) {
+					v, ok = custom, true
+				}
+			}
+		}
+		if !ok {
+			return 0, fmt.Errorf("没有自定义指标 %s", rule.Target)
+		}
+		value = v
 
 	default:
-		return 0, fmt.Errorf("不支持的规则类型: %s", ruleType)
+		return 0, fmt.Errorf("不支持的规则类型: %s", rule.Type)
+	}
+	return value, nil
+}
+
+// volumeValue 指定挂载点时取该分区的值，否则取所有分区中最大的
+func volumeValue(volumes []monitor.DiskUsage, mountPoint string, get func(monitor.DiskUsage) float64) (float64, error) {
+	var value float64
+	for _, volume := range volumes {
+		if mountPoint == "" {
+			value = math.Max(value, get(volume))
+		} else if volume.MountPoint == mountPoint {
+			return get(volume), nil
+		}
+	}
+	if mountPoint != "" {
+		return 0, fmt.Errorf("没有挂载点 %s", mountPoint)
+	}
+	return value, nil
+}
+
+// interfaceValue 指定网卡时取该网卡的值，否则取所有网卡中最大的
+func interfaceValue(interfaces []monitor.InterfaceStats, name string, get func(monitor.InterfaceStats) float64) (float64, error) {
+	var value float64
+	for _, iface := range interfaces {
+		if name == "" {
+			value = math.Max(value, get(iface))
+		} else if iface.Name == name {
+			return get(iface), nil
+		}
+	}
+	if name != "" {
+		return 0, fmt.Errorf("没有网卡 %s", name)
 	}
 	return value, nil
 }
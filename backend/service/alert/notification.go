@@ -34,13 +34,6 @@ func WebhookNotification(alertId uint, hostId uint, value float64, notifyType No
 	log.Printf("开始处理告警通知: alertId=%d, hostId=%d, value=%.2f, notifyType=%d",
 		alertId, hostId, value, notifyType)
 
-	type notifyBody struct {
-		MsgType string `json:"msgtype"`
-		Text    struct {
-			Content string `json:"content"`
-		} `json:"text"`
-	}
-
 	// 使用事务处理所有数据库查询
 	err := global.DB.Transaction(func(tx *gorm.DB) error {
 		// 查询告警规则
@@ -142,14 +135,41 @@ func WebhookNotification(alertId uint, hostId uint, value float64, notifyType No
 		return fmt.Errorf("不支持的通知类型: %d", notifyType)
 	}
 
-	body := notifyBody{
-		MsgType: "text",
-		Text: struct {
+	if err := PostWebhook(notify.WebhookUrl, info); err != nil {
+		return err
+	}
+
+	var notifyTypeStr string
+	if notifyType == NotificationTypeAlert {
+		notifyTypeStr = "alert notification"
+	} else {
+		notifyTypeStr = "recovery notification"
+	}
+	log.Printf("Successfully sent %s to server.", notifyTypeStr)
+	return nil
+}
+
+// SendNotification 通过指定的通知配置发送文本消息，通知未启用时返回错误
+func SendNotification(notificationId uint64, content string) error {
+	var notify alert.Notification
+	if err := global.DB.Model(&alert.Notification{}).
+		Where("id = ? AND enabled = ?", notificationId, true).
+		First(&notify).Error; err != nil {
+		return fmt.Errorf("获取通知配置失败或通知未启用: %w", err)
+	}
+	return PostWebhook(notify.WebhookUrl, content)
+}
+
+// PostWebhook 以文本消息格式调用 Webhook
+func PostWebhook(webhookUrl string, content string) error {
+	type notifyBody struct {
+		MsgType string `json:"msgtype"`
+		Text    struct {
 			Content string `json:"content"`
-		}{
-			Content: info,
-		},
+		} `json:"text"`
 	}
+	body := notifyBody{MsgType: "text"}
+	body.Text.Content = content
 
 	jsonData, err := json.Marshal(body)
 	if err != nil {
@@ -157,11 +177,12 @@ func WebhookNotification(alertId uint, hostId uint, value float64, notifyType No
 	}
 
 	// 在发送请求前打印请求信息
-	log.Printf("准备发送通知请求到: %s", notify.WebhookUrl)
+	log.Printf("准备发送通知请求到: %s", webhookUrl)
 	log.Printf("请求内容: %s", string(jsonData))
 
 	// 发送请求
-	resp, err := http.Post(notify.WebhookUrl, "application/json", bytes.NewBuffer(jsonData))
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(webhookUrl, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		log.Printf("发送通知请求失败: %v", err)
 		return fmt.Errorf("send webhook notification failed: %w", err)
@@ -176,13 +197,5 @@ func WebhookNotification(alertId uint, hostId uint, value float64, notifyType No
 		log.Printf("通知请求返回非成功状态码: %s", resp.Status)
 		return fmt.Errorf("notify non-OK response: %s, body: %s", resp.Status, string(respBody))
 	}
-
-	var notifyTypeStr string
-	if notifyType == NotificationTypeAlert {
-		notifyTypeStr = "alert notification"
-	} else {
-		notifyTypeStr = "recovery notification"
-	}
-	log.Printf("Successfully sent %s to server.", notifyTypeStr)
 	return nil
 }
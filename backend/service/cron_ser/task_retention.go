@@ -0,0 +1,65 @@
+package cron_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/service/task_ser"
+	"time"
+)
+
+// StartTaskRetention 启动任务保留策略定时任务，未配置保留天数时不启动
+func StartTaskRetention() {
+	if global.Config.Task.RetentionDays <= 0 {
+		return
+	}
+	go func() {
+		CleanExpiredTasks()
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			CleanExpiredTasks()
+		}
+	}()
+}
+
+// CleanExpiredTasks 按配置归档或删除超过保留天数的任务，执行中的任务不处理
+func CleanExpiredTasks() {
+	conf := global.Config.Task
+	if conf.RetentionDays <= 0 {
+		return
+	}
+	deadline := time.Now().AddDate(0, 0, -conf.RetentionDays)
+
+	var taskIDs []uint
+	if err := global.DB.Model(&models.TaskModel{}).
+		Where("created_at < ? AND status NOT IN ?", deadline, []string{"created", "running"}).
+		Order("id").Pluck("id", &taskIDs).Error; err != nil {
+		global.Log.Errorf("查询过期任务失败: %v", err)
+		return
+	}
+	if len(taskIDs) == 0 {
+		return
+	}
+
+	archiveDir := conf.ArchiveDir
+	if archiveDir == "" {
+		archiveDir = "archive/tasks"
+	}
+
+	removed := 0
+	for _, taskID := range taskIDs {
+		if conf.RetentionMode != "delete" {
+			if _, err := task_ser.ArchiveTask(taskID, archiveDir); err != nil {
+				// 归档失败的任务保留，下次再试
+				global.Log.Errorf("归档任务 %d 失败: %v", taskID, err)
+				continue
+			}
+		}
+		if err := task_ser.RemoveTask(taskID); err != nil {
+			global.Log.Errorf("删除过期任务 %d 失败: %v", taskID, err)
+			continue
+		}
+		removed++
+	}
+	global.Log.Infof("已清理 %d 个超过 %d 天的任务", removed, conf.RetentionDays)
+}
@@ -0,0 +1,51 @@
+package cron_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"os"
+	"time"
+)
+
+// StartRecordingRetention 启动终端录像清理定时任务，未配置保留天数时不启动
+func StartRecordingRetention() {
+	if global.Config.Terminal.RecordingRetentionDays <= 0 {
+		return
+	}
+	go func() {
+		CleanExpiredRecordings()
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			CleanExpiredRecordings()
+		}
+	}()
+}
+
+// CleanExpiredRecordings 删除超过保留天数的终端录像，审计记录保留
+func CleanExpiredRecordings() {
+	days := global.Config.Terminal.RecordingRetentionDays
+	if days <= 0 {
+		return
+	}
+	deadline := time.Now().AddDate(0, 0, -days)
+
+	var sessions []models.TerminalSessionModel
+	if err := global.DB.Select("id", "recording_path").
+		Where("start_time < ? AND end_time IS NOT NULL AND recording_path <> ''", deadline).
+		Find(&sessions).Error; err != nil {
+		global.Log.Errorf("查询过期终端录像失败: %v", err)
+		return
+	}
+
+	for _, session := range sessions {
+		if err := os.Remove(session.RecordingPath); err != nil && !os.IsNotExist(err) {
+			global.Log.Errorf("删除终端录像 %s 失败: %v", session.RecordingPath, err)
+			continue
+		}
+		global.DB.Model(&session).Update("recording_path", "")
+	}
+	if len(sessions) > 0 {
+		global.Log.Infof("已清理 %d 个超过 %d 天的终端录像", len(sessions), days)
+	}
+}
@@ -0,0 +1,232 @@
+package inventory_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/service/ssh_ser"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Group ansible 动态 inventory 中的分组
+type Group struct {
+	Hosts    []string       `json:"hosts"`
+	Vars     map[string]any `json:"vars,omitempty"`
+	Children []string       `json:"children,omitempty"`
+}
+
+// Inventory ansible 动态 inventory 的 --list 输出，分组名为键，另带 _meta.hostvars
+type Inventory map[string]any
+
+var invalidGroupChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// GroupName 将标签名转换成合法的 ansible 分组名（只允许字母、数字和下划线，且不能以数字开头）
+func GroupName(labelName string) string {
+	name := invalidGroupChars.ReplaceAllString(strings.TrimSpace(labelName), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "label_" + name
+	}
+	return name
+}
+
+// Build 根据主机和标签生成动态 inventory，hostIds 为 nil 时导出全部主机
+func Build(hostIds []uint) (Inventory, error) {
+	hosts, err := loadHosts(hostIds)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := Inventory{}
+	hostvars := map[string]any{}
+	groups := map[string]*Group{}
+	labelGroups := map[uint]string{}
+	var ungrouped []string
+
+	names := hostnames(hosts)
+	jumpHosts := ssh_ser.LoadJumpHosts(hosts)
+	for _, host := range hosts {
+		name := names[host.ID]
+		hostvars[name] = HostVars(host, jumpHosts.Resolve(host))
+
+		if len(host.Label) == 0 {
+			ungrouped = append(ungrouped, name)
+			continue
+		}
+		for _, label := range host.Label {
+			groupName := labelGroupName(label, labelGroups, groups)
+			groups[groupName].Hosts = append(groups[groupName].Hosts, name)
+		}
+	}
+
+	children := []string{"ungrouped"}
+	for groupName, group := range groups {
+		sort.Strings(group.Hosts)
+		inventory[groupName] = group
+		children = append(children, groupName)
+	}
+	sort.Strings(children[1:])
+	sort.Strings(ungrouped)
+
+	inventory["all"] = Group{Hosts: []string{}, Children: children}
+	inventory["ungrouped"] = Group{Hosts: nonNil(ungrouped)}
+	inventory["_meta"] = map[string]any{"hostvars": hostvars}
+	return inventory, nil
+}
+
+// Host 返回 --host 的输出，即单个主机的变量；主机不存在时返回空对象
+func Host(hostIds []uint, name string) (map[string]any, error) {
+	hosts, err := loadHosts(hostIds)
+	if err != nil {
+		return nil, err
+	}
+	names := hostnames(hosts)
+	for _, host := range hosts {
+		if names[host.ID] == name {
+			return HostVars(host, ssh_ser.ResolveJumpHost(host)), nil
+		}
+	}
+	return map[string]any{}, nil
+}
+
+// hostnames 计算主机在 inventory 中的名称，主机名重复时追加主机ID区分
+func hostnames(hosts []models.HostModel) map[uint]string {
+	count := make(map[string]int)
+	for _, host := range hosts {
+		count[host.Name]++
+	}
+	names := make(map[uint]string, len(hosts))
+	for _, host := range hosts {
+		if count[host.Name] > 1 || host.Name == "" {
+			names[host.ID] = fmt.Sprintf("%s_%d", host.Name, host.ID)
+		} else {
+			names[host.ID] = host.Name
+		}
+	}
+	return names
+}
+
+// HostVars 生成主机变量，平台采集到的主机信息统一以 ccops_ 为前缀，jumpHost 为主机需要经过的跳板机
+func HostVars(host models.HostModel, jumpHost *models.JumpHostModel) map[string]any {
+	labels := make([]string, 0, len(host.Label))
+	for _, label := range host.Label {
+		labels = append(labels, label.Name)
+	}
+
+	vars := map[string]any{
+		"ansible_host": host.HostServerUrl,
+		"ansible_user": "root",
+
+		"ccops_id":                 host.ID,
+		"ccops_name":               host.Name,
+		"ccops_status":             host.Status,
+		"ccops_labels":             labels,
+		"ccops_operating_system":   host.OperatingSystem,
+		"ccops_platform":           host.Platform,
+		"ccops_platform_like":      host.PlatformLike,
+		"ccops_version":            host.Version,
+		"ccops_kernel_version":     host.KernelVersion,
+		"ccops_arch":               host.Arch,
+		"ccops_primary_ip":         host.PrimaryIp,
+		"ccops_primary_mac":        host.PrimaryMac,
+		"ccops_public_ip":          host.PublicIP,
+		"ccops_country":            host.Country,
+		"ccops_city":               host.City,
+		"ccops_org":                host.Org,
+		"ccops_cpu_brand":          host.CpuBrand,
+		"ccops_cpu_type":           host.CpuType,
+		"ccops_cpu_logical_cores":  host.CpuLogicalCores,
+		"ccops_cpu_physical_cores": host.CpuPhysicalCores,
+		"ccops_cpu_sockets":        host.CpuSockets,
+		"ccops_physical_memory":    host.PhysicalMemory,
+		"ccops_hardware_vendor":    host.HardwareVendor,
+		"ccops_hardware_model":     host.HardwareModel,
+		"ccops_hardware_serial":    host.HardwareSerial,
+		"ccops_uuid":               host.UUID,
+		"ccops_agent":              host.Agent,
+		"ccops_start_time":         host.StartTime,
+		"ccops_fetch_time":         host.FetchTime,
+	}
+
+	// 经过跳板机的主机使用 ProxyJump，由执行方自己的密钥完成认证
+	if args := ssh_ser.ProxyJumpArgs(jumpHost); args != "" {
+		vars["ansible_ssh_common_args"] = args
+	}
+	return vars
+}
+
+// INI 将 inventory 导出为静态 ini 格式，主机变量只保留连接相关的部分
+func INI(inventory Inventory) string {
+	hostvars := inventory["_meta"].(map[string]any)["hostvars"].(map[string]any)
+
+	var groupNames []string
+	for name := range inventory {
+		if name == "_meta" || name == "all" {
+			continue
+		}
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+
+	var b strings.Builder
+	for _, groupName := range groupNames {
+		var hosts []string
+		switch group := inventory[groupName].(type) {
+		case *Group:
+			hosts = group.Hosts
+		case Group:
+			hosts = group.Hosts
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "[%s]\n", groupName)
+		for _, name := range hosts {
+			vars := hostvars[name].(map[string]any)
+			line := fmt.Sprintf("%s ansible_host=%v ansible_user=%v", name, vars["ansible_host"], vars["ansible_user"])
+			if args, ok := vars["ansible_ssh_common_args"]; ok {
+				line += fmt.Sprintf(" ansible_ssh_common_args='%v'", args)
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func loadHosts(hostIds []uint) ([]models.HostModel, error) {
+	var hosts []models.HostModel
+	query := global.DB.Preload("Label").Order("id")
+	if hostIds != nil {
+		if len(hostIds) == 0 {
+			return hosts, nil
+		}
+		query = query.Where("id IN ?", hostIds)
+	}
+	if err := query.Find(&hosts).Error; err != nil {
+		return nil, fmt.Errorf("获取主机信息失败: %w", err)
+	}
+	return hosts, nil
+}
+
+// labelGroupName 获取标签对应的分组，不同标签转换后重名时追加标签ID
+func labelGroupName(label models.LabelModel, labelGroups map[uint]string, groups map[string]*Group) string {
+	if name, ok := labelGroups[label.ID]; ok {
+		return name
+	}
+	name := GroupName(label.Name)
+	if _, exists := groups[name]; exists || name == "all" || name == "ungrouped" {
+		name = fmt.Sprintf("%s_%d", name, label.ID)
+	}
+	labelGroups[label.ID] = name
+	groups[name] = &Group{Hosts: []string{}, Vars: map[string]any{"ccops_label_id": label.ID, "ccops_label_name": label.Name}}
+	return name
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+package metrics_ser
+
+import (
+	"ccops/global"
+	"ccops/models/monitor"
+	"ccops/service/alert"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// maxCustomPerPush 一次推送的自定义指标数上限
+const maxCustomPerPush = 5000
+
+var (
+	metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRe  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// CustomMetric 推送的一个自定义指标
+type CustomMetric struct {
+	Name  string            `json:"name"`  // 指标名，如 queue_depth
+	Tags  map[string]string `json:"tags"`  // 标签，如 {"queue": "orders"}
+	Value float64           `json:"value"` // 数值
+}
+
+// CustomSeriesKey 检查指标名和标签，返回标签排序后的序列名。
+// 指标名不能使用 ccops_ 前缀，标签不能使用 host、host_id 等主机维度的标签名
+func CustomSeriesKey(name string, tags map[string]string) (string, error) {
+	if !metricNameRe.MatchString(name) {
+		return "", fmt.Errorf("指标名 %q 不合法", name)
+	}
+	if strings.HasPrefix(name, metricPrefix) {
+		return "", fmt.Errorf("指标名 %s 不能使用 %s 前缀", name, metricPrefix)
+	}
+	labels := make(map[string]string, len(tags))
+	for k, v := range tags {
+		if !labelNameRe.MatchString(k) || strings.HasPrefix(k, "__") {
+			return "", fmt.Errorf("指标 %s 的标签名 %q 不合法", name, k)
+		}
+		if hostLabelNames[k] {
+			return "", fmt.Errorf("指标 %s 的标签名 %s 已被主机标签占用", name, k)
+		}
+		if v != "" {
+			labels[k] = v
+		}
+	}
+	return FormatSeriesKey(name, labels), nil
+}
+
+// NormalizeSeriesKey 把告警规则等处填写的序列名整理为与保存时相同的形式
+func NormalizeSeriesKey(key string) (string, error) {
+	name, labels, err := ParseSeriesKey(key)
+	if err != nil {
+		return "", err
+	}
+	return CustomSeriesKey(name, labels)
+}
+
+// PushCustomMetrics 把主机推送的自定义指标写入时序库并检查自定义指标告警规则。
+// 指标名、标签或数值不合法的指标被丢弃，全部不合法时返回第一个错误
+func PushCustomMetrics(hostID uint64, ts int64, metrics []CustomMetric) (WriteResult, error) {
+	var result WriteResult
+	if len(metrics) == 0 {
+		return result, fmt.Errorf("没有指标数据")
+	}
+	if len(metrics) > maxCustomPerPush {
+		return result, fmt.Errorf("一次最多推送 %d 个指标", maxCustomPerPush)
+	}
+	values := make(map[string]float64, len(metrics))
+	var firstErr error
+	for _, metric := range metrics {
+		key, err := CustomSeriesKey(metric.Name, metric.Tags)
+		if err == nil && (math.IsNaN(metric.Value) || math.IsInf(metric.Value, 0)) {
+			err = fmt.Errorf("指标 %s 的数值不合法", key)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			result.Dropped++
+			continue
+		}
+		values[key] = metric.Value
+	}
+	if len(values) == 0 {
+		return result, firstErr
+	}
+	if !global.TimeSeriesDB.InsertCustom(hostID, ts, values) {
+		return result, fmt.Errorf("采集时间过早，数据已落盘")
+	}
+	result.Accepted = len(values)
+	checkCustomAlerts(hostID, ts, values)
+	return result, nil
+}
+
+// checkCustomAlerts 用推送的自定义指标检查告警规则
+func checkCustomAlerts(hostID uint64, ts int64, values map[string]float64) {
+	point := &monitor.MetricPoint{HostID: hostID, CollectedAt: ts, Custom: values}
+	if err := alert.GetAlertService().CheckCustomMetrics(point); err != nil {
+		log.Printf("自定义指标告警检查失败: %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package metrics_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/monitor"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Prometheus 兼容：指标路径转换为 Prometheus 序列，如
+// cpu.usagePercent -> ccops_cpu_usage_percent{host="web1",host_id="3",label="web,prod"}
+// disk.volumes[/data].usagePercent -> ccops_disk_volumes_usage_percent{mountpoint="/data",...}
+// 自定义指标 custom.<序列名> 按序列名原样输出。
+
+const (
+	metricPrefix = "ccops_"
+	customPrefix = "custom."
+)
+
+// listLabels 列表元素的 key 对应的标签名，未列出的列表使用 key
+var listLabels = map[string]string{
+	"disk.volumes":       "mountpoint",
+	"disk.devices":       "device",
+	"processes.watch":    "process",
+	"network.interfaces": "interface",
+}
+
+// hostLabelNames 主机维度的标签，远程写入接收时不计入自定义指标的序列名
+var hostLabelNames = map[string]bool{"host": true, "host_id": true, "label": true, "instance": true, "job": true}
+
+// promHost 主机的 Prometheus 标签
+type promHost struct {
+	id     uint64
+	name   string
+	ip     string
+	labels string // 标签名，按名称排序后用逗号连接
+}
+
+func (h promHost) labelSet() map[string]string {
+	return map[string]string{"host": h.name, "host_id": strconv.FormatUint(h.id, 10), "label": h.labels}
+}
+
+// loadPromHosts 所有主机及其标签
+func loadPromHosts() (map[uint64]promHost, error) {
+	var hosts []models.HostModel
+	if err := global.DB.Select("id", "name", "primary_ip").Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		HostModelID uint
+		Name        string
+	}
+	global.DB.Table("host_labels").
+		Select("host_labels.host_model_id, label_models.name").
+		Joins("JOIN label_models ON label_models.id = host_labels.label_model_id").
+		Scan(&rows)
+	labelNames := map[uint][]string{}
+	for _, row := range rows {
+		labelNames[row.HostModelID] = append(labelNames[row.HostModelID], row.Name)
+	}
+
+	result := make(map[uint64]promHost, len(hosts))
+	for _, host := range hosts {
+		names := labelNames[host.ID]
+		sort.Strings(names)
+		result[uint64(host.ID)] = promHost{id: uint64(host.ID), name: host.Name, ip: host.PrimaryIp, labels: strings.Join(names, ",")}
+	}
+	return result, nil
+}
+
+// seriesOf 指标路径对应的序列名和标签
+func seriesOf(path string) (string, map[string]string) {
+	if strings.HasPrefix(path, customPrefix) {
+		name, labels, err := ParseSeriesKey(strings.TrimPrefix(path, customPrefix))
+		if err == nil {
+			return name, labels
+		}
+		return sanitizeName(strings.TrimPrefix(path, customPrefix)), map[string]string{}
+	}
+
+	labels := map[string]string{}
+	var plain strings.Builder
+	for {
+		open := strings.IndexByte(path, '[')
+		if open < 0 {
+			plain.WriteString(path)
+			break
+		}
+		closing := strings.IndexByte(path[open:], ']')
+		if closing < 0 {
+			plain.WriteString(path)
+			break
+		}
+		plain.WriteString(path[:open])
+		labelName, ok := listLabels[plain.String()]
+		if !ok {
+			labelName = "key"
+		}
+		labels[labelName] = path[open+1 : open+closing]
+		path = path[open+closing+1:]
+	}
+	return metricPrefix + snakeCase(plain.String()), labels
+}
+
+// snakeCase cpu.usagePercent -> cpu_usage_percent
+func snakeCase(path string) string {
+	var b strings.Builder
+	prevLower := false
+	for _, r := range path {
+		switch {
+		case unicode.IsUpper(r):
+			if prevLower {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			prevLower = false
+		case r == '.':
+			b.WriteByte('_')
+			prevLower = false
+		default:
+			b.WriteRune(r)
+			prevLower = unicode.IsLower(r) || unicode.IsDigit(r)
+		}
+	}
+	return sanitizeName(b.String())
+}
+
+// sanitizeName 替换序列名中不允许的字符
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r == '_' || r == ':' || (r < unicode.MaxASCII && (unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)))) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// ParseSeriesKey 解析 name{a="1",b="2"} 形式的序列名
+func ParseSeriesKey(key string) (string, map[string]string, error) {
+	key = strings.TrimSpace(key)
+	labels := map[string]string{}
+	open := strings.IndexByte(key, '{')
+	if open < 0 {
+		if key == "" {
+			return "", nil, errors.New("序列名为空")
+		}
+		return key, labels, nil
+	}
+	if !strings.HasSuffix(key, "}") {
+		return "", nil, errors.New("序列名格式错误")
+	}
+	name := strings.TrimSpace(key[:open])
+	p := &promParser{input: key[open:]}
+	if err := p.next(); err != nil {
+		return "", nil, err
+	}
+	matchers, err := p.parseMatchers()
+	if err != nil {
+		return "", nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return "", nil, errors.New("序列名格式错误")
+	}
+	for _, m := range matchers {
+		if m.op != "=" {
+			return "", nil, errors.New("序列名的标签只能用 =")
+		}
+		labels[m.name] = m.value
+	}
+	if name == "" {
+		return "", nil, errors.New("序列名为空")
+	}
+	return name, labels, nil
+}
+
+// labelEscaper 标签值的转义，与 Prometheus 文本格式一致
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// FormatSeriesKey 生成标签按名称排序的序列名，相同的序列总是得到相同的 key
+func FormatSeriesKey(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i, k := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", k, labelEscaper.Replace(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// promSample 一个序列的一个值，Labels 包含 __name__
+type promSample struct {
+	Labels map[string]string
+	Value  float64
+	Time   int64
+}
+
+// latestSamples 各主机最新数据点展开后的序列，since 之前上报的主机不包含在内
+func latestSamples(hosts map[uint64]promHost, since int64) []promSample {
+	var result []promSample
+	for hostID, point := range global.TimeSeriesDB.GetAllLatest() {
+		host, ok := hosts[hostID]
+		if !ok || point.CollectedAt < since {
+			continue
+		}
+		for path, value := range monitor.Flatten(point) {
+			name, labels := seriesOf(path)
+			for k, v := range host.labelSet() {
+				labels[k] = v
+			}
+			labels["__name__"] = name
+			result = append(result, promSample{Labels: labels, Value: value, Time: point.CollectedAt})
+		}
+	}
+	return result
+}
+
+// WriteExposition 以 Prometheus 文本格式输出各主机的最新数据
+func WriteExposition(w io.Writer) error {
+	hosts, err := loadPromHosts()
+	if err != nil {
+		return err
+	}
+	stale := global.Config.Prometheus.StaleSeconds
+	if stale <= 0 {
+		stale = 300
+	}
+	samples := latestSamples(hosts, time.Now().Unix()-int64(stale))
+
+	byName := map[string][]string{}
+	for _, s := range samples {
+		name := s.Labels["__name__"]
+		delete(s.Labels, "__name__")
+		byName[name] = append(byName[name], FormatSeriesKey(name, s.Labels)+" "+formatValue(s.Value))
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		typ := "untyped"
+		if strings.HasPrefix(name, metricPrefix) {
+			typ = "gauge"
+		}
+		lines := byName[name]
+		sort.Strings(lines)
+		if _, err := fmt.Fprintf(w, "# TYPE %s %s\n%s\n", name, typ, strings.Join(lines, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatValue Prometheus 的数值格式，特殊值为 NaN、+Inf、-Inf
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,812 @@
+package metrics_ser
+
+import (
+	"ccops/global"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PromQL 子集，供 Grafana 把 ccops 作为 Prometheus 数据源使用：
+// 数字、序列选择器 name{a="x",b!="y",c=~"re",d!~"re"}、+ - * / 运算，
+// 以及 sum/avg/min/max/count 聚合，可带 by (标签...)。不支持范围向量和函数。
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp // { } ( ) [ ] , = != =~ !~ + - * /
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+type promParser struct {
+	input string
+	pos   int
+	tok   token
+}
+
+// next 读取下一个 token
+func (p *promParser) next() error {
+	for p.pos < len(p.input) && strings.ContainsRune(" \t\r\n", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	if p.pos >= len(p.input) {
+		p.tok = token{kind: tokEOF}
+		return nil
+	}
+	start := p.pos
+	c := p.input[p.pos]
+	switch {
+	case isIdentChar(c, true):
+		for p.pos < len(p.input) && isIdentChar(p.input[p.pos], false) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, value: p.input[start:p.pos]}
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.input) && (isIdentChar(p.input[p.pos], false) || p.input[p.pos] == '.' ||
+			((p.input[p.pos] == '+' || p.input[p.pos] == '-') && (p.input[p.pos-1] == 'e' || p.input[p.pos-1] == 'E'))) {
+			p.pos++
+		}
+		p.tok = token{kind: tokNumber, value: p.input[start:p.pos]}
+	case c == '"' || c == '\'' || c == '`':
+		value, err := p.readString(c)
+		if err != nil {
+			return err
+		}
+		p.tok = token{kind: tokString, value: value}
+	case strings.HasPrefix(p.input[p.pos:], "!=") || strings.HasPrefix(p.input[p.pos:], "=~") || strings.HasPrefix(p.input[p.pos:], "!~"):
+		p.pos += 2
+		p.tok = token{kind: tokOp, value: p.input[start:p.pos]}
+	case strings.ContainsRune("{}()[],=+-*/", rune(c)):
+		p.pos++
+		p.tok = token{kind: tokOp, value: string(c)}
+	default:
+		return fmt.Errorf("无法识别的字符 %q", c)
+	}
+	return nil
+}
+
+func isIdentChar(c byte, first bool) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+// readString 读取引号中的字符串，反引号中的内容不转义
+func (p *promParser) readString(quote byte) (string, error) {
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && quote != '`' && p.pos < len(p.input):
+			e := p.input[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errors.New("字符串缺少结束引号")
+}
+
+func (p *promParser) isOp(op string) bool {
+	return p.tok.kind == tokOp && p.tok.value == op
+}
+
+func (p *promParser) expect(op string) error {
+	if !p.isOp(op) {
+		return fmt.Errorf("缺少 %s", op)
+	}
+	return p.next()
+}
+
+// matcher 标签匹配条件
+type matcher struct {
+	name  string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (m matcher) matches(labels map[string]string) bool {
+	v := labels[m.name]
+	switch m.op {
+	case "=":
+		return v == m.value
+	case "!=":
+		return v != m.value
+	case "=~":
+		return m.re.MatchString(v)
+	default:
+		return !m.re.MatchString(v)
+	}
+}
+
+// parseMatchers 解析 {a="x",...}，当前 token 为 {
+func (p *promParser) parseMatchers() ([]matcher, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var result []matcher
+	for !p.isOp("}") {
+		if p.tok.kind != tokIdent {
+			return nil, errors.New("缺少标签名")
+		}
+		m := matcher{name: p.tok.value}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokOp || (p.tok.value != "=" && p.tok.value != "!=" && p.tok.value != "=~" && p.tok.value != "!~") {
+			return nil, fmt.Errorf("标签 %s 缺少匹配符", m.name)
+		}
+		m.op = p.tok.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokString {
+			return nil, fmt.Errorf("标签 %s 的值必须用引号", m.name)
+		}
+		m.value = p.tok.value
+		if m.op == "=~" || m.op == "!~" {
+			re, err := regexp.Compile("^(?:" + m.value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("标签 %s 的正则表达式错误", m.name)
+			}
+			m.re = re
+		}
+		result = append(result, m)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.isOp(",") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		} else if !p.isOp("}") {
+			return nil, errors.New("缺少 }")
+		}
+	}
+	return result, p.next()
+}
+
+// promNode 表达式节点
+type promNode interface{}
+
+type numberNode struct{ value float64 }
+
+type selectorNode struct{ matchers []matcher }
+
+type aggregateNode struct {
+	op   string
+	by   []string
+	expr promNode
+}
+
+type binaryNode struct {
+	op       string
+	lhs, rhs promNode
+}
+
+var aggregateOps = map[string]bool{"sum": true, "avg": true, "min": true, "max": true, "count": true}
+
+// parsePromQL 解析查询表达式
+func parsePromQL(query string) (promNode, error) {
+	p := &promParser{input: query}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("无法解析 %q 之后的内容", p.input[:p.pos])
+	}
+	return node, nil
+}
+
+// parseExpr 加减
+func (p *promParser) parseExpr() (promNode, error) {
+	lhs, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.tok.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		rhs, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryNode{op: op, lhs: lhs, rhs: rhs}
+	}
+	return lhs, nil
+}
+
+// parseTerm 乘除
+func (p *promParser) parseTerm() (promNode, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isOp("/") {
+		op := p.tok.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryNode{op: op, lhs: lhs, rhs: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *promParser) parseUnary() (promNode, error) {
+	if p.isOp("-") || p.isOp("+") {
+		op := p.tok.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		node, err := p.parseUnary()
+		if err != nil || op == "+" {
+			return node, err
+		}
+		return binaryNode{op: "-", lhs: numberNode{}, rhs: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *promParser) parsePrimary() (promNode, error) {
+	switch {
+	case p.tok.kind == tokNumber:
+		v, err := strconv.ParseFloat(p.tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("数字格式错误: %s", p.tok.value)
+		}
+		return numberNode{value: v}, p.next()
+	case p.isOp("("):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	case p.isOp("{"):
+		matchers, err := p.parseMatchers()
+		if err != nil {
+			return nil, err
+		}
+		return checkSelector(matchers)
+	case p.tok.kind == tokIdent:
+		name := p.tok.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if aggregateOps[name] && (p.isOp("(") || (p.tok.kind == tokIdent && p.tok.value == "by")) {
+			return p.parseAggregate(name)
+		}
+		if p.isOp("(") {
+			return nil, fmt.Errorf("不支持函数 %s", name)
+		}
+		matchers := []matcher{{name: "__name__", op: "=", value: name}}
+		if p.isOp("{") {
+			more, err := p.parseMatchers()
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, more...)
+		}
+		if p.isOp("[") {
+			return nil, errors.New("不支持范围向量")
+		}
+		return checkSelector(matchers)
+	}
+	return nil, errors.New("表达式不完整")
+}
+
+// checkSelector 选择器至少要有一个不匹配空值的条件
+func checkSelector(matchers []matcher) (promNode, error) {
+	for _, m := range matchers {
+		if !m.matches(map[string]string{}) {
+			return selectorNode{matchers: matchers}, nil
+		}
+	}
+	return nil, errors.New("选择器必须包含至少一个不匹配空值的条件")
+}
+
+// parseAggregate 解析 op by (l) (expr) 或 op (expr) by (l)，当前 token 为 by 或 (
+func (p *promParser) parseAggregate(op string) (promNode, error) {
+	node := aggregateNode{op: op}
+	var err error
+	if p.tok.kind == tokIdent {
+		if node.by, err = p.parseBy(); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.expect("("); err != nil {
+		return nil, err
+	}
+	if node.expr, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokIdent && p.tok.value == "by" && node.by == nil {
+		if node.by, err = p.parseBy(); err != nil {
+			return nil, err
+		}
+	}
+	if p.tok.kind == tokIdent && p.tok.value == "without" {
+		return nil, errors.New("不支持 without")
+	}
+	return node, nil
+}
+
+// parseBy 解析 by (a, b)，当前 token 为 by
+func (p *promParser) parseBy() ([]string, error) {
+	if p.tok.value != "by" {
+		return nil, fmt.Errorf("不支持 %s", p.tok.value)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	by := []string{}
+	for !p.isOp(")") {
+		if p.tok.kind != tokIdent {
+			return nil, errors.New("by 中缺少标签名")
+		}
+		by = append(by, p.tok.value)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.isOp(",") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return by, p.next()
+}
+
+// promSeries 一个序列在各个求值时间上的值
+type promSeries struct {
+	Labels map[string]string
+	Values map[int64]float64
+}
+
+// promValue 表达式的值，标量或向量
+type promValue struct {
+	scalar bool
+	number float64
+	vector []*promSeries
+}
+
+// lookbackDelta 求值时向前查找数据点的最长时间，与 Prometheus 默认值一致
+const lookbackDelta int64 = 300
+
+// promEvaluator 在一组求值时间上计算表达式
+type promEvaluator struct {
+	times []int64
+	step  int64
+	hosts map[uint64]promHost
+}
+
+func (e *promEvaluator) eval(node promNode) (promValue, error) {
+	switch n := node.(type) {
+	case numberNode:
+		return promValue{scalar: true, number: n.value}, nil
+	case selectorNode:
+		return promValue{vector: e.selectSeries(n.matchers)}, nil
+	case aggregateNode:
+		v, err := e.eval(n.expr)
+		if err != nil {
+			return promValue{}, err
+		}
+		if v.scalar {
+			return promValue{}, fmt.Errorf("%s 的参数必须是序列", n.op)
+		}
+		return promValue{vector: e.aggregate(n.op, n.by, v.vector)}, nil
+	case binaryNode:
+		lhs, err := e.eval(n.lhs)
+		if err != nil {
+			return promValue{}, err
+		}
+		rhs, err := e.eval(n.rhs)
+		if err != nil {
+			return promValue{}, err
+		}
+		return binaryOp(n.op, lhs, rhs), nil
+	}
+	return promValue{}, errors.New("不支持的表达式")
+}
+
+// selectSeries 查询匹配选择器的序列，先按主机标签过滤主机，再逐台查询
+func (e *promEvaluator) selectSeries(matchers []matcher) []*promSeries {
+	start, end := e.times[0]-lookbackDelta, e.times[len(e.times)-1]
+	maxPoints := int((end-start)/e.step) + 1
+	if maxPoints > maxPointsLimit {
+		maxPoints = maxPointsLimit
+	}
+
+	hostIDs := make([]uint64, 0, len(e.hosts))
+	for id := range e.hosts {
+		hostIDs = append(hostIDs, id)
+	}
+	sort.Slice(hostIDs, func(i, j int) bool { return hostIDs[i] < hostIDs[j] })
+
+	var result []*promSeries
+	for _, id := range hostIDs {
+		host := e.hosts[id]
+		hostLabels := host.labelSet()
+		matched := true
+		for _, m := range matchers {
+			if hostLabelNames[m.name] && !m.matches(hostLabels) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		rangeResult := global.TimeSeriesDB.QueryRange(id, start, end, maxPoints)
+		type sample struct {
+			time  int64
+			value float64
+		}
+		byPath := map[string][]sample{}
+		for _, point := range rangeResult.Points {
+			for path, agg := range point.Fields {
+				byPath[path] = append(byPath[path], sample{time: point.Time, value: agg.Last})
+			}
+		}
+		for path, samples := range byPath {
+			name, labels := seriesOf(path)
+			for k, v := range hostLabels {
+				labels[k] = v
+			}
+			labels["__name__"] = name
+			if !matchAll(matchers, labels) {
+				continue
+			}
+			// 每个求值时间取不晚于它的最近一个点
+			lookback := lookbackDelta
+			if rangeResult.Step > lookback {
+				lookback = rangeResult.Step
+			}
+			series := &promSeries{Labels: labels, Values: map[int64]float64{}}
+			for _, t := range e.times {
+				i := sort.Search(len(samples), func(i int) bool { return samples[i].time > t }) - 1
+				if i >= 0 && t-samples[i].time <= lookback {
+					series.Values[t] = samples[i].value
+				}
+			}
+			if len(series.Values) > 0 {
+				result = append(result, series)
+			}
+		}
+	}
+	return result
+}
+
+func matchAll(matchers []matcher, labels map[string]string) bool {
+	for _, m := range matchers {
+		if !m.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// aggregate 按 by 中的标签分组后在每个时间上合并
+func (e *promEvaluator) aggregate(op string, by []string, vector []*promSeries) []*promSeries {
+	agg, _ := aggregator(op)
+	groups := map[string]*promSeries{}
+	values := map[string]map[int64][]float64{}
+	for _, s := range vector {
+		labels := map[string]string{}
+		for _, name := range by {
+			if v, ok := s.Labels[name]; ok && v != "" {
+				labels[name] = v
+			}
+		}
+		key := FormatSeriesKey("", labels)
+		if _, ok := groups[key]; !ok {
+			groups[key] = &promSeries{Labels: labels, Values: map[int64]float64{}}
+			values[key] = map[int64][]float64{}
+		}
+		for t, v := range s.Values {
+			values[key][t] = append(values[key][t], v)
+		}
+	}
+	result := make([]*promSeries, 0, len(groups))
+	for key, g := range groups {
+		for t, v := range values[key] {
+			g.Values[t] = agg(v)
+		}
+		result = append(result, g)
+	}
+	return result
+}
+
+func applyOp(op string, a, b float64) float64 {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	}
+	if b == 0 {
+		// 与 Prometheus 一致：x/0 为 ±Inf，0/0 为 NaN
+		return a / math.Copysign(0, b)
+	}
+	return a / b
+}
+
+// binaryOp 标量与标量、标量与序列、序列与序列（标签完全相同的序列之间）运算，结果去掉序列名
+func binaryOp(op string, lhs, rhs promValue) promValue {
+	if lhs.scalar && rhs.scalar {
+		return promValue{scalar: true, number: applyOp(op, lhs.number, rhs.number)}
+	}
+	if lhs.scalar || rhs.scalar {
+		vector, number := lhs.vector, rhs.number
+		if lhs.scalar {
+			vector, number = rhs.vector, lhs.number
+		}
+		result := make([]*promSeries, 0, len(vector))
+		for _, s := range vector {
+			out := &promSeries{Labels: dropName(s.Labels), Values: make(map[int64]float64, len(s.Values))}
+			for t, v := range s.Values {
+				if lhs.scalar {
+					out.Values[t] = applyOp(op, number, v)
+				} else {
+					out.Values[t] = applyOp(op, v, number)
+				}
+			}
+			result = append(result, out)
+		}
+		return promValue{vector: result}
+	}
+
+	byKey := map[string]*promSeries{}
+	for _, s := range rhs.vector {
+		byKey[FormatSeriesKey("", dropName(s.Labels))] = s
+	}
+	var result []*promSeries
+	for _, s := range lhs.vector {
+		labels := dropName(s.Labels)
+		other, ok := byKey[FormatSeriesKey("", labels)]
+		if !ok {
+			continue
+		}
+		out := &promSeries{Labels: labels, Values: map[int64]float64{}}
+		for t, v := range s.Values {
+			if w, ok := other.Values[t]; ok {
+				out.Values[t] = applyOp(op, v, w)
+			}
+		}
+		if len(out.Values) > 0 {
+			result = append(result, out)
+		}
+	}
+	return promValue{vector: result}
+}
+
+func dropName(labels map[string]string) map[string]string {
+	result := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if k != "__name__" {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// PromResult Prometheus 查询接口 data 字段的内容
+type PromResult struct {
+	ResultType string `json:"resultType"` // matrix / vector / scalar
+	Result     any    `json:"result"`
+}
+
+type promMatrixSeries struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]any          `json:"values"`
+}
+
+type promVectorSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  [2]any            `json:"value"`
+}
+
+// evalAt 在一组时间上计算表达式
+func evalAt(query string, times []int64, step int64) (promValue, error) {
+	node, err := parsePromQL(query)
+	if err != nil {
+		return promValue{}, err
+	}
+	hosts, err := loadPromHosts()
+	if err != nil {
+		return promValue{}, err
+	}
+	e := &promEvaluator{times: times, step: step, hosts: hosts}
+	return e.eval(node)
+}
+
+// PromQuery 即时查询，ts 为求值时间（Unix 秒）
+func PromQuery(query string, ts int64) (*PromResult, error) {
+	v, err := evalAt(query, []int64{ts}, lookbackDelta)
+	if err != nil {
+		return nil, err
+	}
+	if v.scalar {
+		return &PromResult{ResultType: "scalar", Result: [2]any{ts, formatValue(v.number)}}, nil
+	}
+	result := make([]promVectorSample, 0, len(v.vector))
+	for _, s := range sortSeries(v.vector) {
+		if value, ok := s.Values[ts]; ok {
+			result = append(result, promVectorSample{Metric: s.Labels, Value: [2]any{ts, formatValue(value)}})
+		}
+	}
+	return &PromResult{ResultType: "vector", Result: result}, nil
+}
+
+// PromQueryRange 范围查询，在 start 到 end 之间每隔 step 秒求值一次
+func PromQueryRange(query string, start, end, step int64) (*PromResult, error) {
+	if step <= 0 {
+		return nil, errors.New("step 必须大于 0")
+	}
+	if end < start {
+		return nil, errors.New("结束时间不能早于开始时间")
+	}
+	if (end-start)/step+1 > maxPointsLimit {
+		return nil, fmt.Errorf("点数过多，请增大 step，每条曲线最多 %d 个点", maxPointsLimit)
+	}
+	var times []int64
+	for t := start; t <= end; t += step {
+		times = append(times, t)
+	}
+	v, err := evalAt(query, times, step)
+	if err != nil {
+		return nil, err
+	}
+	if v.scalar {
+		series := &promSeries{Labels: map[string]string{}, Values: map[int64]float64{}}
+		for _, t := range times {
+			series.Values[t] = v.number
+		}
+		v.vector = []*promSeries{series}
+	}
+	result := make([]promMatrixSeries, 0, len(v.vector))
+	for _, s := range sortSeries(v.vector) {
+		values := make([][2]any, 0, len(s.Values))
+		for _, t := range times {
+			if value, ok := s.Values[t]; ok {
+				values = append(values, [2]any{t, formatValue(value)})
+			}
+		}
+		if len(values) > 0 {
+			result = append(result, promMatrixSeries{Metric: s.Labels, Values: values})
+		}
+	}
+	return &PromResult{ResultType: "matrix", Result: result}, nil
+}
+
+// sortSeries 按标签排序，结果顺序稳定
+func sortSeries(vector []*promSeries) []*promSeries {
+	keys := make(map[*promSeries]string, len(vector))
+	for _, s := range vector {
+		keys[s] = FormatSeriesKey(s.Labels["__name__"], dropName(s.Labels))
+	}
+	sort.Slice(vector, func(i, j int) bool { return keys[vector[i]] < keys[vector[j]] })
+	return vector
+}
+
+// PromSeries 与任一选择器匹配的序列的标签，取自各主机的最新数据；matches 为空时返回全部
+func PromSeries(matches []string) ([]map[string]string, error) {
+	var selectors [][]matcher
+	for _, match := range matches {
+		node, err := parsePromQL(match)
+		if err != nil {
+			return nil, err
+		}
+		selector, ok := node.(selectorNode)
+		if !ok {
+			return nil, fmt.Errorf("%s 不是序列选择器", match)
+		}
+		selectors = append(selectors, selector.matchers)
+	}
+	hosts, err := loadPromHosts()
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	result := []map[string]string{}
+	for _, sample := range latestSamples(hosts, 0) {
+		matched := len(selectors) == 0
+		for _, matchers := range selectors {
+			if matchAll(matchers, sample.Labels) {
+				matched = true
+				break
+			}
+		}
+		key := FormatSeriesKey("", sample.Labels)
+		if matched && !seen[key] {
+			seen[key] = true
+			result = append(result, sample.Labels)
+		}
+	}
+	return result, nil
+}
+
+// PromLabelNames 序列中出现的标签名
+func PromLabelNames(matches []string) ([]string, error) {
+	series, err := PromSeries(matches)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, labels := range series {
+		for name, value := range labels {
+			if value != "" {
+				names[name] = true
+			}
+		}
+	}
+	return sortedKeys(names), nil
+}
+
+// PromLabelValues 标签在序列中出现的值，__name__ 为序列名
+func PromLabelValues(name string, matches []string) ([]string, error) {
+	series, err := PromSeries(matches)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]bool{}
+	for _, labels := range series {
+		if value := labels[name]; value != "" {
+			values[value] = true
+		}
+	}
+	return sortedKeys(values), nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,266 @@
+package metrics_ser
+
+import (
+	"ccops/global"
+	"ccops/models/monitor"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTestDB 使用内存时序库，测试结束后恢复
+func useTestDB(t *testing.T) {
+	old := global.TimeSeriesDB
+	global.TimeSeriesDB = monitor.NewTimeSeriesDB()
+	t.Cleanup(func() {
+		global.TimeSeriesDB.Close()
+		global.TimeSeriesDB = old
+	})
+}
+
+// testBase 测试数据的时间，十分钟前并按分钟对齐
+func testBase() int64 {
+	now := time.Now().Unix()
+	return now - now%60 - 600
+}
+
+func insertTestPoint(hostID uint64, ts int64, cpu, memory float64, volumes map[string]float64) {
+	point := &monitor.MetricPoint{HostID: hostID, CollectedAt: ts}
+	point.CPU.UsagePercent = cpu
+	point.Memory.UsagePercent = memory
+	mountPoints := make([]string, 0, len(volumes))
+	for mountPoint := range volumes {
+		mountPoints = append(mountPoints, mountPoint)
+	}
+	sort.Strings(mountPoints)
+	for _, mountPoint := range mountPoints {
+		point.Disk.Volumes = append(point.Disk.Volumes, monitor.DiskUsage{MountPoint: mountPoint, UsagePercent: volumes[mountPoint]})
+	}
+	global.TimeSeriesDB.Insert(point)
+}
+
+// formatNode 把表达式树写成带括号的形式，便于比较
+func formatNode(node promNode) string {
+	switch n := node.(type) {
+	case numberNode:
+		return formatValue(n.value)
+	case selectorNode:
+		parts := make([]string, 0, len(n.matchers))
+		for _, m := range n.matchers {
+			parts = append(parts, fmt.Sprintf("%s%s%q", m.name, m.op, m.value))
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	case aggregateNode:
+		if n.by == nil {
+			return fmt.Sprintf("%s(%s)", n.op, formatNode(n.expr))
+		}
+		return fmt.Sprintf("%s by (%s) (%s)", n.op, strings.Join(n.by, ","), formatNode(n.expr))
+	case binaryNode:
+		return fmt.Sprintf("(%s %s %s)", formatNode(n.lhs), n.op, formatNode(n.rhs))
+	}
+	return "?"
+}
+
+func TestParsePromQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string // 为空时应解析失败
+	}{
+		{`ccops_cpu_usage_percent`, `{__name__="ccops_cpu_usage_percent"}`},
+		{`up{host="web1", mountpoint!="/", interface=~"eth.*", job!~'a|b'}`,
+			`{__name__="up",host="web1",mountpoint!="/",interface=~"eth.*",job!~"a|b"}`},
+		{`{__name__="up",}`, `{__name__="up"}`},
+		{`up{path="C:\\data",note="a\"b"}`, `{__name__="up",path="C:\\data",note="a\"b"}`},
+		{"up{path=`C:\\data`}", `{__name__="up",path="C:\\data"}`},
+		{`1 + 2 * 3`, `(1 + (2 * 3))`},
+		{`(1 + 2) * 3`, `((1 + 2) * 3)`},
+		{`a - b - c`, `(({__name__="a"} - {__name__="b"}) - {__name__="c"})`},
+		{`a / b * c`, `(({__name__="a"} / {__name__="b"}) * {__name__="c"})`},
+		{`-a * 2`, `((0 - {__name__="a"}) * 2)`},
+		{`2 - -1`, `(2 - (0 - 1))`},
+		{`+a`, `{__name__="a"}`},
+		{`1e3 + .5`, `(1000 + 0.5)`},
+		{`sum(a)`, `sum({__name__="a"})`},
+		{`sum by (host) (a)`, `sum by (host) ({__name__="a"})`},
+		{`max(a{x="1"}) by (host, mountpoint)`, `max by (host,mountpoint) ({__name__="a",x="1"})`},
+		{`count by () (a)`, `count by () ({__name__="a"})`},
+		{`avg(a) * 100 - 1`, `((avg({__name__="a"}) * 100) - 1)`},
+		{`sum`, `{__name__="sum"}`},
+
+		{``, ``},
+		{`1 +`, ``},
+		{`{a=""}`, ``},
+		{`{a=~".*"}`, ``},
+		{`a{b="x"`, ``},
+		{`a{b=x}`, ``},
+		{`a{b}`, ``},
+		{`a{b=~"("}`, ``},
+		{`a[5m]`, ``},
+		{`rate(a)`, ``},
+		{`sum(a) without (b)`, ``},
+		{`sum by (host (a)`, ``},
+		{`(a`, ``},
+		{`a b`, ``},
+		{`a{b="x}`, ``},
+		{`a # b`, ``},
+	}
+	for _, tt := range tests {
+		node, err := parsePromQL(tt.query)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%q 应解析失败，得到 %s", tt.query, formatNode(node))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q 解析失败: %v", tt.query, err)
+			continue
+		}
+		if got := formatNode(node); got != tt.want {
+			t.Errorf("%q 解析结果为 %s，应为 %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+// vectorAt 向量在时间 t 的值，key 为去掉 host_id 和 label 后的序列名
+func vectorAt(v promValue, t int64) map[string]float64 {
+	result := map[string]float64{}
+	for _, s := range v.vector {
+		value, ok := s.Values[t]
+		if !ok {
+			continue
+		}
+		labels := dropName(s.Labels)
+		delete(labels, "host_id")
+		delete(labels, "label")
+		result[FormatSeriesKey(s.Labels["__name__"], labels)] = value
+	}
+	return result
+}
+
+func TestPromEval(t *testing.T) {
+	useTestDB(t)
+	base := testBase()
+	insertTestPoint(1, base, 10, 40, map[string]float64{"/": 50, "/data": 70})
+	insertTestPoint(2, base, 30, 0, map[string]float64{"/": 20})
+	hosts := map[uint64]promHost{
+		1: {id: 1, name: "web1", labels: "prod"},
+		2: {id: 2, name: "web2", labels: "prod,test"},
+	}
+
+	at := base + 60
+	tests := []struct {
+		query  string
+		scalar float64
+		want   map[string]float64 // 为 nil 时结果应为标量
+	}{
+		{`ccops_cpu_usage_percent`, 0, map[string]float64{
+			`ccops_cpu_usage_percent{host="web1"}`: 10,
+			`ccops_cpu_usage_percent{host="web2"}`: 30,
+		}},
+		{`ccops_cpu_usage_percent{host="web2"}`, 0, map[string]float64{`ccops_cpu_usage_percent{host="web2"}`: 30}},
+		{`ccops_cpu_usage_percent{label=~".*test.*"}`, 0, map[string]float64{`ccops_cpu_usage_percent{host="web2"}`: 30}},
+		{`ccops_cpu_usage_percent{host="web3"}`, 0, map[string]float64{}},
+		{`ccops_disk_volumes_usage_percent{mountpoint=~"/d.*"}`, 0, map[string]float64{
+			`ccops_disk_volumes_usage_percent{host="web1",mountpoint="/data"}`: 70,
+		}},
+		{`{__name__=~"ccops_cpu_usage_.*",host="web1"}`, 0, map[string]float64{`ccops_cpu_usage_percent{host="web1"}`: 10}},
+
+		// 聚合
+		{`sum(ccops_cpu_usage_percent)`, 0, map[string]float64{``: 40}},
+		{`avg(ccops_cpu_usage_percent)`, 0, map[string]float64{``: 20}},
+		{`count(ccops_disk_volumes_usage_percent)`, 0, map[string]float64{``: 3}},
+		{`max by (host) (ccops_disk_volumes_usage_percent)`, 0, map[string]float64{`{host="web1"}`: 70, `{host="web2"}`: 20}},
+		{`min(ccops_disk_volumes_usage_percent) by (mountpoint)`, 0, map[string]float64{`{mountpoint="/"}`: 20, `{mountpoint="/data"}`: 70}},
+
+		// 标量运算
+		{`1 + 2 * 3`, 7, nil},
+		{`-(2 - 5)`, 3, nil},
+		{`1 / 0`, math.Inf(1), nil},
+		{`ccops_cpu_usage_percent * 2`, 0, map[string]float64{`{host="web1"}`: 20, `{host="web2"}`: 60}},
+		{`100 - ccops_cpu_usage_percent`, 0, map[string]float64{`{host="web1"}`: 90, `{host="web2"}`: 70}},
+		{`-ccops_cpu_usage_percent`, 0, map[string]float64{`{host="web1"}`: -10, `{host="web2"}`: -30}},
+
+		// 序列与序列：去掉序列名后标签完全相同的才运算
+		{`ccops_cpu_usage_percent - ccops_memory_usage_percent`, 0, map[string]float64{`{host="web1"}`: -30, `{host="web2"}`: 30}},
+		{`ccops_disk_volumes_usage_percent / ccops_disk_volumes_usage_percent{mountpoint="/"}`, 0, map[string]float64{
+			`{host="web1",mountpoint="/"}`: 1,
+			`{host="web2",mountpoint="/"}`: 1,
+		}},
+		{`ccops_cpu_usage_percent + ccops_disk_volumes_usage_percent`, 0, map[string]float64{}},
+		{`sum(ccops_cpu_usage_percent) / count(ccops_cpu_usage_percent)`, 0, map[string]float64{``: 20}},
+	}
+	for _, tt := range tests {
+		node, err := parsePromQL(tt.query)
+		if err != nil {
+			t.Errorf("%q 解析失败: %v", tt.query, err)
+			continue
+		}
+		e := &promEvaluator{times: []int64{at}, step: lookbackDelta, hosts: hosts}
+		v, err := e.eval(node)
+		if err != nil {
+			t.Errorf("%q 求值失败: %v", tt.query, err)
+			continue
+		}
+		if tt.want == nil {
+			if !v.scalar || v.number != tt.scalar {
+				t.Errorf("%q 应为标量 %v，得到 %+v", tt.query, tt.scalar, v)
+			}
+			continue
+		}
+		got := vectorAt(v, at)
+		if v.scalar || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%q 结果为 %v，应为 %v", tt.query, got, tt.want)
+		}
+	}
+
+	// 标量不能聚合
+	node, _ := parsePromQL(`sum(1)`)
+	if _, err := (&promEvaluator{times: []int64{at}, step: lookbackDelta, hosts: hosts}).eval(node); err == nil {
+		t.Error("sum(1) 应求值失败")
+	}
+}
+
+func TestPromEvalLookback(t *testing.T) {
+	useTestDB(t)
+	base := testBase()
+	insertTestPoint(1, base, 10, 0, nil)
+	insertTestPoint(1, base+120, 20, 0, nil)
+	hosts := map[uint64]promHost{1: {id: 1, name: "web1"}}
+
+	// 每个求值时间取不晚于它、且在回看时间内的最近一个点
+	tests := []struct {
+		at   int64
+		want float64
+		ok   bool
+	}{
+		{base - 60, 0, false},
+		{base, 10, true},
+		{base + 60, 10, true},
+		{base + 120, 20, true},
+		{base + 120 + lookbackDelta, 20, true},
+		{base + 120 + lookbackDelta + 60, 0, false},
+	}
+	times := make([]int64, 0, len(tests))
+	for _, tt := range tests {
+		times = append(times, tt.at)
+	}
+	// 按分钟求值，时序库按原始精度返回数据点
+	node, _ := parsePromQL(`ccops_cpu_usage_percent`)
+	v, err := (&promEvaluator{times: times, step: 60, hosts: hosts}).eval(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.vector) != 1 {
+		t.Fatalf("应有 1 个序列，得到 %d 个", len(v.vector))
+	}
+	for _, tt := range tests {
+		value, ok := v.vector[0].Values[tt.at]
+		if ok != tt.ok || value != tt.want {
+			t.Errorf("时间 %+d 的值为 %v（%v），应为 %v（%v）", tt.at-base, value, ok, tt.want, tt.ok)
+		}
+	}
+}
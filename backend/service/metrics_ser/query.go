@@ -0,0 +1,385 @@
+package metrics_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/models/monitor"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxPoints = 300   // 未指定间隔时每条曲线的点数
+	maxPointsLimit   = 11000 // 单条曲线最多的点数
+)
+
+// QueryRequest 指标查询条件
+type QueryRequest struct {
+	Metric      string `json:"metric"`      // 指标路径，如 cpu.usagePercent、disk.volumes[/data].usagePercent，[*] 匹配所有元素
+	HostIDs     []uint `json:"hostIds"`     // 主机，与标签取并集
+	LabelIDs    []uint `json:"labelIds"`    // 标签，标签下的所有主机
+	Start       int64  `json:"start"`       // 开始时间（Unix 秒），默认结束前 1 小时
+	End         int64  `json:"end"`         // 结束时间（Unix 秒），默认当前时间
+	Step        int64  `json:"step"`        // 间隔（秒），为 0 时按 MaxPoints 计算
+	MaxPoints   int    `json:"maxPoints"`   // 每条曲线最多的点数，默认 300
+	Stat        string `json:"stat"`        // 每个间隔内取的统计值：avg（默认）/ min / max / last
+	Aggregation string `json:"aggregation"` // 多台主机合并的方式：avg（默认）/ min / max / sum / count / p50 / p95 / p99 等
+	GroupBy     string `json:"groupBy"`     // host（默认，每台主机一条曲线）/ label（每个标签一条）/ all（合并为一条）
+}
+
+// Sample 曲线上的一个点
+type Sample struct {
+	Time  int64   `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// Series 一条曲线
+type Series struct {
+	Name    string   `json:"name"`              // 主机名、标签名，通配时追加匹配到的元素
+	HostID  uint     `json:"hostId,omitempty"`  // 按主机分组时的主机
+	LabelID uint     `json:"labelId,omitempty"` // 按标签分组时的标签
+	Metric  string   `json:"metric"`            // 实际的指标路径
+	Points  []Sample `json:"points"`
+}
+
+// QueryResult 查询结果
+type QueryResult struct {
+	Tier   string   `json:"tier"` // 使用的数据粒度
+	Step   int64    `json:"step"` // 相邻两点的间隔（秒）
+	Series []Series `json:"series"`
+}
+
+// hostSeries 一台主机上一个指标路径的数据
+type hostSeries struct {
+	host   models.HostModel
+	metric string
+	values map[int64]float64
+}
+
+// Normalize 校验并补全查询条件
+func (r *QueryRequest) Normalize() error {
+	r.Metric = strings.TrimSpace(r.Metric)
+	if r.Metric == "" {
+		return errors.New("请指定指标")
+	}
+	if r.End == 0 {
+		r.End = time.Now().Unix()
+	}
+	if r.Start == 0 {
+		r.Start = r.End - 3600
+	}
+	if r.Start >= r.End {
+		return errors.New("开始时间必须早于结束时间")
+	}
+	if r.Step > 0 {
+		r.MaxPoints = int((r.End - r.Start + r.Step - 1) / r.Step)
+	}
+	if r.MaxPoints <= 0 {
+		r.MaxPoints = defaultMaxPoints
+	}
+	if r.MaxPoints > maxPointsLimit {
+		return fmt.Errorf("点数过多，请增大间隔，每条曲线最多 %d 个点", maxPointsLimit)
+	}
+	switch r.Stat {
+	case "":
+		r.Stat = "avg"
+	case "avg", "min", "max", "last":
+	default:
+		return errors.New("stat 只能是 avg、min、max、last")
+	}
+	if r.Aggregation == "" {
+		r.Aggregation = "avg"
+	}
+	if _, err := aggregator(r.Aggregation); err != nil {
+		return err
+	}
+	switch r.GroupBy {
+	case "":
+		r.GroupBy = "host"
+	case "host", "label", "all":
+	default:
+		return errors.New("groupBy 只能是 host、label、all")
+	}
+	return nil
+}
+
+// ResolveHosts 查询条件中的主机和标签下的主机，allowed 不为 nil 时只保留其中的主机
+func ResolveHosts(hostIDs, labelIDs []uint, allowed []uint) ([]models.HostModel, error) {
+	ids := map[uint]bool{}
+	for _, id := range hostIDs {
+		ids[id] = true
+	}
+	if len(labelIDs) > 0 {
+		var labelHostIDs []uint
+		global.DB.Table("host_labels").Where("label_model_id IN ?", labelIDs).Pluck("host_model_id", &labelHostIDs)
+		for _, id := range labelHostIDs {
+			ids[id] = true
+		}
+	}
+	if allowed != nil {
+		permitted := map[uint]bool{}
+		for _, id := range allowed {
+			permitted[id] = true
+		}
+		for id := range ids {
+			if !permitted[id] {
+				delete(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("没有可查询的主机")
+	}
+	list := make([]uint, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+	var hosts []models.HostModel
+	if err := global.DB.Select("id", "name").Where("id IN ?", list).Order("id").Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+// Query 查询并按条件合并曲线
+func Query(req QueryRequest, hosts []models.HostModel) (*QueryResult, error) {
+	match, err := metricMatcher(req.Metric)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QueryResult{Series: []Series{}}
+	var all []hostSeries
+	for _, host := range hosts {
+		rangeResult := global.TimeSeriesDB.QueryRange(uint64(host.ID), req.Start, req.End, req.MaxPoints)
+		result.Tier, result.Step = rangeResult.Tier, rangeResult.Step
+		byMetric := map[string]*hostSeries{}
+		for _, point := range rangeResult.Points {
+			for name, agg := range point.Fields {
+				if !match(name) {
+					continue
+				}
+				s, ok := byMetric[name]
+				if !ok {
+					s = &hostSeries{host: host, metric: name, values: map[int64]float64{}}
+					byMetric[name] = s
+				}
+				s.values[point.Time] = statValue(agg, req.Stat)
+			}
+		}
+		names := make([]string, 0, len(byMetric))
+		for name := range byMetric {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			all = append(all, *byMetric[name])
+		}
+	}
+
+	switch req.GroupBy {
+	case "host":
+		for _, s := range all {
+			name := s.host.Name
+			if s.metric != req.Metric {
+				name += " " + s.metric
+			}
+			result.Series = append(result.Series, Series{
+				Name:   name,
+				HostID: s.host.ID,
+				Metric: s.metric,
+				Points: samples(s.values),
+			})
+		}
+	case "all":
+		agg, _ := aggregator(req.Aggregation)
+		if len(all) > 0 {
+			result.Series = append(result.Series, Series{Name: "all", Metric: req.Metric, Points: combine(all, agg)})
+		}
+	case "label":
+		agg, _ := aggregator(req.Aggregation)
+		labels, err := hostLabels(req.LabelIDs, hosts)
+		if err != nil {
+			return nil, err
+		}
+		result.Series = append(result.Series, labelSeries(labels, all, agg, req.Metric)...)
+	}
+	return result, nil
+}
+
+// labelSeries 每个标签合并为一条曲线，标签下没有数据的不返回
+func labelSeries(labels []labelGroup, all []hostSeries, agg func([]float64) float64, metric string) []Series {
+	var result []Series
+	for _, label := range labels {
+		var group []hostSeries
+		for _, s := range all {
+			if label.hosts[s.host.ID] {
+				group = append(group, s)
+			}
+		}
+		if len(group) == 0 {
+			continue
+		}
+		result = append(result, Series{Name: label.name, LabelID: label.id, Metric: metric, Points: combine(group, agg)})
+	}
+	return result
+}
+
+// metricMatcher 指标路径匹配，[*] 匹配列表中的任意元素
+func metricMatcher(metric string) (func(string) bool, error) {
+	if !strings.Contains(metric, "[*]") {
+		return func(name string) bool { return name == metric }, nil
+	}
+	pattern := strings.ReplaceAll(regexp.QuoteMeta(metric), `\[\*\]`, `\[[^\]]*\]`)
+	re, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return nil, errors.New("指标路径格式错误")
+	}
+	return re.MatchString, nil
+}
+
+func statValue(agg monitor.Agg, stat string) float64 {
+	switch stat {
+	case "min":
+		return agg.Min
+	case "max":
+		return agg.Max
+	case "last":
+		return agg.Last
+	}
+	return agg.Avg()
+}
+
+// aggregator 多个值合并为一个，pNN 为百分位数
+func aggregator(name string) (func([]float64) float64, error) {
+	switch name {
+	case "avg":
+		return func(v []float64) float64 {
+			sum := 0.0
+			for _, x := range v {
+				sum += x
+			}
+			return sum / float64(len(v))
+		}, nil
+	case "sum":
+		return func(v []float64) float64 {
+			sum := 0.0
+			for _, x := range v {
+				sum += x
+			}
+			return sum
+		}, nil
+	case "min":
+		return func(v []float64) float64 {
+			m := v[0]
+			for _, x := range v[1:] {
+				m = math.Min(m, x)
+			}
+			return m
+		}, nil
+	case "max":
+		return func(v []float64) float64 {
+			m := v[0]
+			for _, x := range v[1:] {
+				m = math.Max(m, x)
+			}
+			return m
+		}, nil
+	case "count":
+		return func(v []float64) float64 { return float64(len(v)) }, nil
+	}
+	if strings.HasPrefix(name, "p") {
+		p, err := strconv.ParseFloat(name[1:], 64)
+		if err == nil && p > 0 && p <= 100 {
+			return func(v []float64) float64 { return percentile(v, p) }, nil
+		}
+	}
+	return nil, errors.New("aggregation 只能是 avg、min、max、sum、count 或 p50、p95 等百分位数")
+}
+
+// percentile 最近秩法计算百分位数
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// combine 把多条曲线在同一时间点上的值合并
+func combine(group []hostSeries, agg func([]float64) float64) []Sample {
+	byTime := map[int64][]float64{}
+	for _, s := range group {
+		for t, v := range s.values {
+			byTime[t] = append(byTime[t], v)
+		}
+	}
+	merged := make(map[int64]float64, len(byTime))
+	for t, values := range byTime {
+		merged[t] = agg(values)
+	}
+	return samples(merged)
+}
+
+func samples(values map[int64]float64) []Sample {
+	result := make([]Sample, 0, len(values))
+	for t, v := range values {
+		result = append(result, Sample{Time: t, Value: v})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Time < result[j].Time })
+	return result
+}
+
+type labelGroup struct {
+	id    uint
+	name  string
+	hosts map[uint]bool
+}
+
+// hostLabels 按标签分组：指定了标签时只用这些标签，否则取所选主机的所有标签
+func hostLabels(labelIDs []uint, hosts []models.HostModel) ([]labelGroup, error) {
+	hostIDs := make([]uint, 0, len(hosts))
+	for _, host := range hosts {
+		hostIDs = append(hostIDs, host.ID)
+	}
+	var rows []models.HostLabels
+	query := global.DB.Where("host_model_id IN ?", hostIDs)
+	if len(labelIDs) > 0 {
+		query = query.Where("label_model_id IN ?", labelIDs)
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	groups := map[uint]*labelGroup{}
+	for _, row := range rows {
+		g, ok := groups[row.LabelModelID]
+		if !ok {
+			g = &labelGroup{id: row.LabelModelID, hosts: map[uint]bool{}}
+			groups[row.LabelModelID] = g
+		}
+		g.hosts[row.HostModelID] = true
+	}
+	ids := make([]uint, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	var labels []models.LabelModel
+	global.DB.Select("id", "name").Where("id IN ?", ids).Find(&labels)
+	for _, label := range labels {
+		groups[label.ID].name = label.Name
+	}
+	result := make([]labelGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].id < result[j].id })
+	return result, nil
+}
@@ -0,0 +1,209 @@
+package metrics_ser
+
+import (
+	"ccops/models"
+	"fmt"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{7, 3, 10, 1, 5, 2, 9, 4, 8, 6}
+	tests := []struct {
+		values []float64
+		p      float64
+		want   float64
+	}{
+		{values, 1, 1},
+		{values, 10, 1},
+		{values, 11, 2},
+		{values, 50, 5},
+		{values, 90, 9},
+		{values, 95, 10},
+		{values, 100, 10},
+		{[]float64{42}, 50, 42},
+		{[]float64{2, 1}, 50, 1},
+		{[]float64{2, 1}, 51, 2},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.values, tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %v) = %v，应为 %v", tt.values, tt.p, got, tt.want)
+		}
+	}
+	if fmt.Sprint(values) != "[7 3 10 1 5 2 9 4 8 6]" {
+		t.Errorf("percentile 不应修改传入的数据: %v", values)
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	values := []float64{4, 1, 3, 2}
+	tests := []struct {
+		name string
+		want float64
+	}{
+		{"avg", 2.5},
+		{"sum", 10},
+		{"min", 1},
+		{"max", 4},
+		{"count", 4},
+		{"p50", 2},
+		{"p75", 3},
+		{"p99.9", 4},
+		{"p100", 4},
+	}
+	for _, tt := range tests {
+		agg, err := aggregator(tt.name)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := agg(values); got != tt.want {
+			t.Errorf("%s = %v，应为 %v", tt.name, got, tt.want)
+		}
+	}
+	for _, name := range []string{"", "median", "p", "p0", "p101", "p-5", "pxx"} {
+		if _, err := aggregator(name); err == nil {
+			t.Errorf("aggregation %q 应不合法", name)
+		}
+	}
+}
+
+func TestMetricMatcher(t *testing.T) {
+	tests := []struct {
+		metric string
+		name   string
+		want   bool
+	}{
+		{"cpu.usagePercent", "cpu.usagePercent", true},
+		{"cpu.usagePercent", "cpu.usagePercentX", false},
+		{"disk.volumes[/data].usagePercent", "disk.volumes[/data].usagePercent", true},
+		{"disk.volumes[/data].usagePercent", "disk.volumes[/].usagePercent", false},
+		{"disk.volumes[*].usagePercent", "disk.volumes[/].usagePercent", true},
+		{"disk.volumes[*].usagePercent", "disk.volumes[/data/a.b].usagePercent", true},
+		{"disk.volumes[*].usagePercent", "disk.volumes[].usagePercent", true},
+		{"disk.volumes[*].usagePercent", "disk.volumes[/].inodesUsagePercent", false},
+		{"disk.volumes[*].usagePercent", "disk.volumes[/]x.usagePercent", false},
+		{"disk.volumes[*].usagePercent", "disk.volumes[/][/].usagePercent", false},
+		{"network.interfaces[*].bytesRecv", "network.interfaces[eth0].bytesRecv", true},
+		{"a.b[*]", "a.b[x]", true},
+		{"a.b[*]", "a.b[x].c", false},
+		{"a.*", "a.b", false},
+	}
+	for _, tt := range tests {
+		match, err := metricMatcher(tt.metric)
+		if err != nil {
+			t.Errorf("%s: %v", tt.metric, err)
+			continue
+		}
+		if got := match(tt.name); got != tt.want {
+			t.Errorf("%s 匹配 %s 为 %v，应为 %v", tt.metric, tt.name, got, tt.want)
+		}
+	}
+}
+
+// formatSeries 把曲线写成 名称=时间:值,... 的形式，时间为相对 base 的秒数
+func formatSeries(series []Series, base int64) []string {
+	result := make([]string, 0, len(series))
+	for _, s := range series {
+		text := s.Name + "="
+		for i, p := range s.Points {
+			if i > 0 {
+				text += ","
+			}
+			text += fmt.Sprintf("%d:%v", p.Time-base, p.Value)
+		}
+		result = append(result, text)
+	}
+	return result
+}
+
+func TestQuery(t *testing.T) {
+	useTestDB(t)
+	base := testBase()
+	insertTestPoint(1, base, 10, 0, map[string]float64{"/": 50, "/data": 70})
+	insertTestPoint(1, base+60, 20, 0, map[string]float64{"/": 51, "/data": 71})
+	insertTestPoint(2, base, 30, 0, map[string]float64{"/": 20})
+	insertTestPoint(3, base, 60, 0, nil)
+	insertTestPoint(3, base+60, 80, 0, nil)
+	hosts := []models.HostModel{{ID: 1, Name: "web1"}, {ID: 2, Name: "web2"}, {ID: 3, Name: "db1"}}
+
+	tests := []struct {
+		req  QueryRequest
+		want string
+	}{
+		{QueryRequest{Metric: "cpu.usagePercent"},
+			`[web1=0:10,60:20 web2=0:30 db1=0:60,60:80]`},
+		{QueryRequest{Metric: "cpu.usagePercent", GroupBy: "all"},
+			`[all=0:33.333333333333336,60:50]`},
+		{QueryRequest{Metric: "cpu.usagePercent", GroupBy: "all", Aggregation: "max"},
+			`[all=0:60,60:80]`},
+		{QueryRequest{Metric: "cpu.usagePercent", GroupBy: "all", Aggregation: "count"},
+			`[all=0:3,60:2]`},
+		{QueryRequest{Metric: "cpu.usagePercent", GroupBy: "all", Aggregation: "p50"},
+			`[all=0:30,60:20]`},
+		{QueryRequest{Metric: "cpu.usagePercent", GroupBy: "all", Aggregation: "p95"},
+			`[all=0:60,60:80]`},
+
+		// 精确的路径只匹配一个元素，[*] 匹配所有元素，每个元素一条曲线
+		{QueryRequest{Metric: "disk.volumes[/data].usagePercent"},
+			`[web1=0:70,60:71]`},
+		{QueryRequest{Metric: "disk.volumes[*].usagePercent"},
+			`[web1 disk.volumes[/].usagePercent=0:50,60:51 web1 disk.volumes[/data].usagePercent=0:70,60:71 web2 disk.volumes[/].usagePercent=0:20]`},
+		{QueryRequest{Metric: "disk.volumes[*].usagePercent", GroupBy: "all", Aggregation: "max"},
+			`[all=0:70,60:71]`},
+		{QueryRequest{Metric: "disk.volumes[*].usagePercent", GroupBy: "all", Aggregation: "count"},
+			`[all=0:3,60:2]`},
+		{QueryRequest{Metric: "disk.volumes[*].missing"}, `[]`},
+		{QueryRequest{Metric: "disk.volumes[*].missing", GroupBy: "all"}, `[]`},
+	}
+	for _, tt := range tests {
+		req := tt.req
+		req.Start, req.End, req.Step = base, base+600, 60
+		if err := req.Normalize(); err != nil {
+			t.Errorf("%+v: %v", tt.req, err)
+			continue
+		}
+		result, err := Query(req, hosts)
+		if err != nil {
+			t.Errorf("%+v: %v", tt.req, err)
+			continue
+		}
+		if got := fmt.Sprint(formatSeries(result.Series, base)); got != tt.want {
+			t.Errorf("%+v 结果为 %s，应为 %s", tt.req, got, tt.want)
+		}
+	}
+}
+
+func TestLabelSeries(t *testing.T) {
+	all := []hostSeries{
+		{host: models.HostModel{ID: 1}, metric: "cpu.usagePercent", values: map[int64]float64{0: 10, 60: 20}},
+		{host: models.HostModel{ID: 2}, metric: "cpu.usagePercent", values: map[int64]float64{0: 30}},
+		{host: models.HostModel{ID: 3}, metric: "cpu.usagePercent", values: map[int64]float64{0: 60, 60: 80}},
+	}
+	labels := []labelGroup{
+		{id: 1, name: "prod", hosts: map[uint]bool{1: true, 2: true}},
+		{id: 2, name: "db", hosts: map[uint]bool{3: true}},
+		{id: 3, name: "empty", hosts: map[uint]bool{4: true}},
+		{id: 4, name: "all", hosts: map[uint]bool{1: true, 2: true, 3: true}},
+	}
+	tests := []struct {
+		aggregation string
+		want        string
+	}{
+		{"avg", `[prod=0:20,60:20 db=0:60,60:80 all=0:33.333333333333336,60:50]`},
+		{"sum", `[prod=0:40,60:20 db=0:60,60:80 all=0:100,60:100]`},
+		{"count", `[prod=0:2,60:1 db=0:1,60:1 all=0:3,60:2]`},
+		{"p50", `[prod=0:10,60:20 db=0:60,60:80 all=0:30,60:20]`},
+	}
+	for _, tt := range tests {
+		agg, _ := aggregator(tt.aggregation)
+		series := labelSeries(labels, all, agg, "cpu.usagePercent")
+		if got := fmt.Sprint(formatSeries(series, 0)); got != tt.want {
+			t.Errorf("%s 结果为 %s，应为 %s", tt.aggregation, got, tt.want)
+		}
+		for _, s := range series {
+			if s.LabelID == 0 || s.HostID != 0 || s.Metric != "cpu.usagePercent" {
+				t.Errorf("%s 的曲线 %+v 缺少标签或指标", tt.aggregation, s)
+			}
+		}
+	}
+}
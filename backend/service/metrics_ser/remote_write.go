@@ -0,0 +1,381 @@
+package metrics_ser
+
+import (
+	"bytes"
+	"ccops/global"
+	"ccops/models/monitor"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Prometheus 远程写入协议：snappy 压缩的 protobuf WriteRequest。
+// 字段很少，直接用 protowire 编解码，不引入生成的 prompb 代码：
+//   WriteRequest { repeated TimeSeries timeseries = 1; }
+//   TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//   Label        { string name = 1; string value = 2; }
+//   Sample       { double value = 1; int64 timestamp = 2; } // 毫秒
+
+const (
+	maxRemoteWriteBody = 32 << 20 // 解压后的请求体上限
+	maxSendLookback    = 600      // 推送中断后最多补发的时间（秒）
+)
+
+// RemoteSeries 一个序列及其数据点
+type RemoteSeries struct {
+	Labels  map[string]string
+	Samples []RemoteSample
+}
+
+// RemoteSample 数据点，时间为毫秒
+type RemoteSample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// DecodeWriteRequest 解码 snappy 压缩的 WriteRequest
+func DecodeWriteRequest(body []byte) ([]RemoteSeries, error) {
+	if n, err := snappy.DecodedLen(body); err != nil || n > maxRemoteWriteBody {
+		return nil, errors.New("请求体不是有效的 snappy 数据或过大")
+	}
+	data, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, err
+	}
+	var result []RemoteSeries
+	err = eachField(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		series, err := decodeTimeSeries(value)
+		if err != nil {
+			return err
+		}
+		result = append(result, series)
+		return nil
+	})
+	return result, err
+}
+
+func decodeTimeSeries(data []byte) (RemoteSeries, error) {
+	series := RemoteSeries{Labels: map[string]string{}}
+	err := eachField(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			var name, labelValue string
+			err := eachField(value, func(num protowire.Number, typ protowire.Type, v []byte) error {
+				if typ == protowire.BytesType && num == 1 {
+					name = string(v)
+				} else if typ == protowire.BytesType && num == 2 {
+					labelValue = string(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			series.Labels[name] = labelValue
+		case 2:
+			var sample RemoteSample
+			err := eachField(value, func(num protowire.Number, typ protowire.Type, v []byte) error {
+				if num == 1 && typ == protowire.Fixed64Type {
+					bits, _ := protowire.ConsumeFixed64(v)
+					sample.Value = math.Float64frombits(bits)
+				} else if num == 2 && typ == protowire.VarintType {
+					ts, _ := protowire.ConsumeVarint(v)
+					sample.Timestamp = int64(ts)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			series.Samples = append(series.Samples, sample)
+		}
+		return nil
+	})
+	return series, err
+}
+
+// eachField 遍历消息中的字段，value 为长度前缀字段的内容或定长、变长字段的原始编码
+func eachField(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		var value []byte
+		if typ == protowire.BytesType {
+			v, m := protowire.ConsumeBytes(data)
+			if m < 0 {
+				return protowire.ParseError(m)
+			}
+			value, n = v, m
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			value = data[:n]
+		}
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// EncodeWriteRequest 编码并 snappy 压缩 WriteRequest，标签按名称排序
+func EncodeWriteRequest(list []RemoteSeries) []byte {
+	var data []byte
+	for _, series := range list {
+		var ts []byte
+		names := make([]string, 0, len(series.Labels))
+		for name := range series.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, series.Labels[name])
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, label)
+		}
+		for _, s := range series.Samples {
+			var sample []byte
+			sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+			sample = protowire.AppendFixed64(sample, math.Float64bits(s.Value))
+			sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+			sample = protowire.AppendVarint(sample, uint64(s.Timestamp))
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, sample)
+		}
+		data = protowire.AppendTag(data, 1, protowire.BytesType)
+		data = protowire.AppendBytes(data, ts)
+	}
+	return snappy.Encode(nil, data)
+}
+
+// WriteResult 远程写入和自定义指标推送的结果
+type WriteResult struct {
+	Accepted int `json:"accepted"` // 写入的数据点数
+	Dropped  int `json:"dropped"`  // 找不到主机、序列名与 ccops 指标冲突或时间过早而丢弃的数据点数
+}
+
+// ApplyRemoteWrite 把远程写入的序列作为自定义指标写入对应主机。
+// 主机依次按 host_id、host（主机名）、instance（去掉端口后的主机名或主 IP）查找，
+// 序列名和其余标签组成自定义指标的 key，如 http_requests_total{code="200"}
+func ApplyRemoteWrite(list []RemoteSeries) (WriteResult, error) {
+	var result WriteResult
+	hosts, err := loadPromHosts()
+	if err != nil {
+		return result, err
+	}
+	byName := map[string]uint64{}
+	byIP := map[string]uint64{}
+	for id, host := range hosts {
+		byName[host.name] = id
+		if host.ip != "" {
+			byIP[host.ip] = id
+		}
+	}
+
+	type hostTime struct {
+		hostID uint64
+		ts     int64
+	}
+	values := map[hostTime]map[string]float64{}
+	for _, series := range list {
+		name := series.Labels["__name__"]
+		hostID, ok := resolveRemoteHost(series.Labels, hosts, byName, byIP)
+		if !ok || name == "" || strings.HasPrefix(name, metricPrefix) {
+			result.Dropped += len(series.Samples)
+			continue
+		}
+		labels := map[string]string{}
+		for k, v := range series.Labels {
+			if k != "__name__" && !hostLabelNames[k] && v != "" {
+				labels[k] = v
+			}
+		}
+		key := FormatSeriesKey(name, labels)
+		for _, s := range series.Samples {
+			if math.IsNaN(s.Value) {
+				// 过期标记，不保存
+				continue
+			}
+			ht := hostTime{hostID: hostID, ts: s.Timestamp / 1000}
+			if values[ht] == nil {
+				values[ht] = map[string]float64{}
+			}
+			values[ht][key] = s.Value
+		}
+	}
+	// 每台主机用最新一个时间的数据检查自定义指标告警规则
+	latest := map[uint64]int64{}
+	for ht, custom := range values {
+		if global.TimeSeriesDB.InsertCustom(ht.hostID, ht.ts, custom) {
+			result.Accepted += len(custom)
+			if ht.ts > latest[ht.hostID] {
+				latest[ht.hostID] = ht.ts
+			}
+		} else {
+			result.Dropped += len(custom)
+		}
+	}
+	for hostID, ts := range latest {
+		checkCustomAlerts(hostID, ts, values[hostTime{hostID: hostID, ts: ts}])
+	}
+	return result, nil
+}
+
+func resolveRemoteHost(labels map[string]string, hosts map[uint64]promHost, byName, byIP map[string]uint64) (uint64, bool) {
+	if v := labels["host_id"]; v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if _, ok := hosts[id]; err == nil && ok {
+			return id, true
+		}
+	}
+	if id, ok := byName[labels["host"]]; ok {
+		return id, true
+	}
+	instance := labels["instance"]
+	if host, _, err := net.SplitHostPort(instance); err == nil {
+		instance = host
+	}
+	if id, ok := byName[instance]; ok {
+		return id, true
+	}
+	id, ok := byIP[instance]
+	return id, ok
+}
+
+// StartRemoteWrite 按配置定期把 agent 上报的指标推送到远程写入目标。
+// 只推送 ccops_ 开头的指标，推送的自定义指标可能就来自目标本身，不再转发
+func StartRemoteWrite() {
+	conf := global.Config.Prometheus
+	if len(conf.RemoteWrite) == 0 {
+		return
+	}
+	interval := time.Duration(conf.RemoteWriteInterval) * time.Second
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	for _, target := range conf.RemoteWrite {
+		go runRemoteWrite(target.URL, target.Username, target.Password, target.BearerToken, interval)
+	}
+	global.Log.Infof("已启动 %d 个远程写入目标，间隔 %v", len(conf.RemoteWrite), interval)
+}
+
+func runRemoteWrite(url, username, password, bearerToken string, interval time.Duration) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	lastSent := map[uint64]int64{}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		hosts, err := loadPromHosts()
+		if err != nil {
+			global.Log.Errorf("远程写入 %s: 查询主机失败: %v", url, err)
+			continue
+		}
+		now := time.Now().Unix()
+		series, sent := collectRemoteSeries(hosts, lastSent, now)
+		if len(series) == 0 {
+			continue
+		}
+		if err := sendRemoteWrite(client, url, username, password, bearerToken, EncodeWriteRequest(series)); err != nil {
+			// 失败时不更新发送进度，下次补发，最多补发 maxSendLookback 秒
+			global.Log.Warnf("远程写入 %s 失败: %v", url, err)
+			continue
+		}
+		for hostID, ts := range sent {
+			lastSent[hostID] = ts
+		}
+	}
+}
+
+// collectRemoteSeries 各主机在上次发送之后的数据点，返回序列和每台主机发送到的时间
+func collectRemoteSeries(hosts map[uint64]promHost, lastSent map[uint64]int64, now int64) ([]RemoteSeries, map[uint64]int64) {
+	bySeries := map[string]*RemoteSeries{}
+	sent := map[uint64]int64{}
+	for hostID, host := range hosts {
+		from := lastSent[hostID] + 1
+		if from < now-maxSendLookback {
+			from = now - maxSendLookback
+		}
+		points := global.TimeSeriesDB.Query(hostID, from, now)
+		// Query 按时间倒序返回，远程写入要求同一序列的数据点按时间升序
+		for i := len(points) - 1; i >= 0; i-- {
+			point := points[i]
+			for path, value := range monitor.Flatten(point) {
+				name, labels := seriesOf(path)
+				if !strings.HasPrefix(name, metricPrefix) {
+					continue
+				}
+				for k, v := range host.labelSet() {
+					if v != "" {
+						labels[k] = v
+					}
+				}
+				labels["__name__"] = name
+				key := FormatSeriesKey(name, labels)
+				s, ok := bySeries[key]
+				if !ok {
+					s = &RemoteSeries{Labels: labels}
+					bySeries[key] = s
+				}
+				s.Samples = append(s.Samples, RemoteSample{Value: value, Timestamp: point.CollectedAt * 1000})
+			}
+			sent[hostID] = point.CollectedAt
+		}
+	}
+	result := make([]RemoteSeries, 0, len(bySeries))
+	for _, s := range bySeries {
+		result = append(result, *s)
+	}
+	return result, sent
+}
+
+func sendRemoteWrite(client *http.Client, url, username, password, bearerToken string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "ccops")
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	} else if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
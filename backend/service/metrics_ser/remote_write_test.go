@@ -0,0 +1,63 @@
+package metrics_ser
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/golang/snappy"
+)
+
+func TestRemoteWriteRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		list []RemoteSeries
+	}{
+		{"空请求", nil},
+		{"单个序列", []RemoteSeries{{
+			Labels:  map[string]string{"__name__": "up", "instance": "web1:9100"},
+			Samples: []RemoteSample{{Value: 1, Timestamp: 1700000000000}},
+		}}},
+		{"多个序列和数据点", []RemoteSeries{
+			{
+				Labels: map[string]string{"__name__": "queue_depth", "queue": "订单", "empty": ""},
+				Samples: []RemoteSample{
+					{Value: -1.5, Timestamp: 1700000000000},
+					{Value: 0, Timestamp: 1700000015000},
+					{Value: 1e300, Timestamp: 1700000030000},
+				},
+			},
+			{
+				Labels:  map[string]string{"__name__": "inf"},
+				Samples: []RemoteSample{{Value: math.Inf(1), Timestamp: 1}, {Value: math.Inf(-1), Timestamp: 2}},
+			},
+		}},
+		{"没有标签和数据点的序列", []RemoteSeries{{Labels: map[string]string{}}}},
+	}
+	for _, tt := range tests {
+		got, err := DecodeWriteRequest(EncodeWriteRequest(tt.list))
+		if err != nil {
+			t.Errorf("%s: 解码失败: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.list) {
+			t.Errorf("%s: 解码结果为 %+v，应为 %+v", tt.name, got, tt.list)
+		}
+	}
+}
+
+func TestDecodeWriteRequestInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{"不是 snappy 数据", []byte("not snappy")},
+		{"字段不完整", snappy.Encode(nil, []byte{0x0a, 0x05, 0x0a})},
+		{"标签不完整", snappy.Encode(nil, []byte{0x0a, 0x02, 0x0a, 0x05})},
+	}
+	for _, tt := range tests {
+		if _, err := DecodeWriteRequest(tt.body); err == nil {
+			t.Errorf("%s: 应解码失败", tt.name)
+		}
+	}
+}
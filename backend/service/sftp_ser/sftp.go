@@ -0,0 +1,133 @@
+package sftp_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/service/ssh_ser"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Client 主机的 SFTP 连接，与 Web 终端使用相同的密钥和跳板机配置
+type Client struct {
+	*sftp.Client
+	conn *ssh.Client
+}
+
+// Open 以用户的证书连接主机并启动 SFTP 子系统
+func Open(host models.HostModel, userID uint, username string) (*Client, error) {
+	conn, err := ssh_ser.DialAs(host, ssh_ser.CertUser{
+		UserID:   userID,
+		Username: username,
+		Purpose:  models.CertPurposeTerminal,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("SSH 连接失败: %v", err)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("启动 SFTP 失败: %v", err)
+	}
+	return &Client{Client: client, conn: conn}, nil
+}
+
+// Close 关闭 SFTP 和 SSH 连接
+func (c *Client) Close() error {
+	c.Client.Close()
+	return c.conn.Close()
+}
+
+// FileInfo 文件信息
+type FileInfo struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"` // 如 -rw-r--r--
+	Perm    string    `json:"perm"` // 如 0644
+	IsDir   bool      `json:"isDir"`
+	IsLink  bool      `json:"isLink"`
+	ModTime time.Time `json:"modTime"`
+	UID     uint32    `json:"uid"`
+	GID     uint32    `json:"gid"`
+}
+
+// NewFileInfo 转换 os.FileInfo
+func NewFileInfo(dir string, fi os.FileInfo) FileInfo {
+	info := FileInfo{
+		Name:    fi.Name(),
+		Path:    path.Join(dir, fi.Name()),
+		Size:    fi.Size(),
+		Mode:    fi.Mode().String(),
+		Perm:    fmt.Sprintf("%04o", fi.Mode().Perm()),
+		IsDir:   fi.IsDir(),
+		IsLink:  fi.Mode()&os.ModeSymlink != 0,
+		ModTime: fi.ModTime(),
+	}
+	if stat, ok := fi.Sys().(*sftp.FileStat); ok {
+		info.UID = stat.UID
+		info.GID = stat.GID
+	}
+	return info
+}
+
+// CleanPath 只接受绝对路径，返回清理后的路径
+func CleanPath(p string) (string, error) {
+	if p == "" || !strings.HasPrefix(p, "/") {
+		return "", fmt.Errorf("路径必须是绝对路径")
+	}
+	return path.Clean(p), nil
+}
+
+// Operation 一次文件操作的审计信息
+type Operation struct {
+	UserID    uint
+	Username  string
+	Host      models.HostModel
+	ClientIP  string
+	Operation string
+	Path      string
+	Target    string
+	Size      int64
+}
+
+// Audit 记录文件操作，err 为空表示成功
+func Audit(op Operation, err error) {
+	record := models.FileOperationModel{
+		UserID:    op.UserID,
+		Username:  op.Username,
+		HostID:    op.Host.ID,
+		HostName:  op.Host.Name,
+		ClientIP:  op.ClientIP,
+		Operation: op.Operation,
+		Path:      op.Path,
+		Target:    op.Target,
+		Size:      op.Size,
+		Success:   err == nil,
+	}
+	if err != nil {
+		record.Error = err.Error()
+		if len(record.Error) > 512 {
+			record.Error = record.Error[:512]
+		}
+	}
+	if e := global.DB.Create(&record).Error; e != nil {
+		global.Log.Errorf("记录文件操作失败: %v", e)
+	}
+}
+
+// MaxUpload 单个文件上传上限（字节），0 表示不限制
+func MaxUpload() int64 {
+	return global.Config.Terminal.SftpMaxUpload * 1024 * 1024
+}
+
+// MaxDownload 单个文件下载上限（字节），0 表示不限制
+func MaxDownload() int64 {
+	return global.Config.Terminal.SftpMaxDownload * 1024 * 1024
+}
@@ -0,0 +1,249 @@
+package ssh_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// TaskPrincipal 任务和平台自身连接使用的证书主体，所有主机都允许
+const TaskPrincipal = "ccops-task"
+
+// UserPrincipal 用户证书的主体，主机只允许有终端权限的用户。
+// 使用用户ID并放在 ccops-user- 下，与 TaskPrincipal 不会重名，也不暴露用户名
+func UserPrincipal(userID uint) string {
+	return fmt.Sprintf("ccops-user-%d", userID)
+}
+
+// 证书生效时间提前，容忍主机与平台的时钟偏差
+const clockSkew = time.Minute
+
+var (
+	caMu     sync.Mutex
+	caSigner ssh.Signer
+)
+
+// CertUser 证书代表的身份，UserID 为 0 表示平台自身
+type CertUser struct {
+	UserID   uint
+	Username string
+	Purpose  string // models.CertPurposeXxx
+	HostID   uint
+	TaskID   uint
+}
+
+func (u CertUser) principal() string {
+	if u.UserID == 0 {
+		return TaskPrincipal
+	}
+	return UserPrincipal(u.UserID)
+}
+
+func (u CertUser) ttl() time.Duration {
+	minutes := global.Config.SSHCA.SessionTTL
+	if u.Purpose == models.CertPurposeTask {
+		minutes = global.Config.SSHCA.TaskTTL
+	}
+	if minutes <= 0 {
+		minutes = 5
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// LoadCA 读取 core.InitSSHCA 生成的 CA 私钥
+func LoadCA() (ssh.Signer, error) {
+	caMu.Lock()
+	defer caMu.Unlock()
+	if caSigner != nil {
+		return caSigner, nil
+	}
+	var privateKey string
+	err := global.DB.Model(&models.Configuration{}).
+		Where("type = ? AND field_name = ?", models.ConfigurationTypeKey, "CAPrivateKey").
+		Select("field_value").Scan(&privateKey).Error
+	if err != nil || privateKey == "" {
+		return nil, errors.New("CA 私钥不存在")
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+	if err != nil {
+		return nil, fmt.Errorf("解析 CA 私钥失败: %w", err)
+	}
+	caSigner = signer
+	return caSigner, nil
+}
+
+// CAPublicKey 返回 CA 公钥（authorized_keys 格式），未启用 CA 时为空
+func CAPublicKey() string {
+	if !global.Config.SSHCA.Enabled {
+		return ""
+	}
+	signer, err := LoadCA()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+}
+
+// issueCert 生成一次性密钥并签发证书，返回私钥和证书，签发记录写入数据库
+func issueCert(user CertUser) (ed25519.PrivateKey, *ssh.Certificate, error) {
+	ca, err := LoadCA()
+	if err != nil {
+		return nil, nil, err
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("生成证书密钥失败: %w", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var serialBytes [8]byte
+	rand.Read(serialBytes[:])
+	now := time.Now()
+	record := models.SSHCertificateModel{
+		Serial:      binary.BigEndian.Uint64(serialBytes[:]),
+		Principals:  user.principal(),
+		Purpose:     user.Purpose,
+		UserID:      user.UserID,
+		Username:    user.Username,
+		HostID:      user.HostID,
+		TaskID:      user.TaskID,
+		ValidAfter:  now.Add(-clockSkew),
+		ValidBefore: now.Add(user.ttl()),
+	}
+	if user.UserID == 0 {
+		record.KeyID = fmt.Sprintf("ccops:%s", user.Purpose)
+	} else {
+		record.KeyID = fmt.Sprintf("ccops:%s:%s", user.Purpose, user.Username)
+	}
+	if user.TaskID != 0 {
+		record.KeyID += fmt.Sprintf(":task-%d", user.TaskID)
+	}
+
+	cert := &ssh.Certificate{
+		Key:             sshPub,
+		Serial:          record.Serial,
+		CertType:        ssh.UserCert,
+		KeyId:           record.KeyID,
+		ValidPrincipals: []string{record.Principals},
+		ValidAfter:      uint64(record.ValidAfter.Unix()),
+		ValidBefore:     uint64(record.ValidBefore.Unix()),
+		Permissions: ssh.Permissions{
+			Extensions: map[string]string{
+				"permit-pty":             "",
+				"permit-port-forwarding": "",
+			},
+		},
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		return nil, nil, fmt.Errorf("签发证书失败: %w", err)
+	}
+	if err := global.DB.Create(&record).Error; err != nil {
+		global.Log.Errorf("记录 SSH 证书失败: %v", err)
+	}
+	return priv, cert, nil
+}
+
+// authMethods 连接主机使用的认证方式：启用 CA 时优先使用新签发的证书，保留静态密钥时追加平台密钥
+func authMethods(user CertUser) ([]ssh.AuthMethod, error) {
+	var signers []ssh.Signer
+	var certErr error
+	if global.Config.SSHCA.Enabled {
+		priv, cert, err := issueCert(user)
+		if err == nil {
+			keySigner, _ := ssh.NewSignerFromKey(priv)
+			certSigner, err := ssh.NewCertSigner(cert, keySigner)
+			if err == nil {
+				signers = append(signers, certSigner)
+			} else {
+				certErr = err
+			}
+		} else {
+			certErr = err
+		}
+	}
+	if !global.Config.SSHCA.Enabled || global.Config.SSHCA.StaticKey {
+		signer, err := LoadSigner()
+		if err != nil && len(signers) == 0 {
+			return nil, err
+		}
+		if err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) == 0 {
+		return nil, certErr
+	}
+	return []ssh.AuthMethod{ssh.PublicKeys(signers...)}, nil
+}
+
+// taskKeyPath 任务证书私钥路径，证书位于同目录的 -cert.pub 文件，ssh 会自动加载
+func taskKeyPath(taskID uint) string {
+	return filepath.Join(filepath.Dir(PrivateKeyPath), fmt.Sprintf("task_%d", taskID))
+}
+
+// PrepareTaskKey 为任务签发证书并写入私钥和证书文件，返回 ansible 使用的私钥路径。
+// 未启用 CA 时返回平台静态密钥路径。
+func PrepareTaskKey(taskID uint) (string, error) {
+	if !global.Config.SSHCA.Enabled {
+		return PrivateKeyPath, nil
+	}
+	priv, cert, err := issueCert(CertUser{Purpose: models.CertPurposeTask, TaskID: taskID})
+	if err != nil {
+		return "", err
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		return "", err
+	}
+	keyPath := taskKeyPath(taskID)
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		return "", errors.New("写入任务私钥失败")
+	}
+	if err := os.WriteFile(keyPath+"-cert.pub", ssh.MarshalAuthorizedKey(cert), 0600); err != nil {
+		os.Remove(keyPath)
+		return "", errors.New("写入任务证书失败")
+	}
+	return keyPath, nil
+}
+
+// RemoveTaskKey 任务结束后删除证书和跳板机私钥文件
+func RemoveTaskKey(taskID uint) {
+	keyPath := taskKeyPath(taskID)
+	os.Remove(keyPath)
+	os.Remove(keyPath + "-cert.pub")
+	// 跳板机的独立私钥
+	jumpKeys, _ := filepath.Glob(keyPath + "-jump_*")
+	for _, path := range jumpKeys {
+		os.Remove(path)
+	}
+}
+
+// StaticKeyArgs 保留静态密钥时，让 ssh 在证书之外再尝试平台密钥
+func StaticKeyArgs() string {
+	if !global.Config.SSHCA.Enabled || !global.Config.SSHCA.StaticKey {
+		return ""
+	}
+	path, err := filepath.Abs(PrivateKeyPath)
+	if err != nil {
+		path = PrivateKeyPath
+	}
+	return "-o IdentityFile=" + path
+}
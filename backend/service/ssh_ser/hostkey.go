@@ -0,0 +1,379 @@
+package ssh_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	alertmodel "ccops/models/alert"
+	"ccops/service/alert"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// KnownHostsPath 由已信任的主机密钥生成的 known_hosts，供 ansible 使用
+const KnownHostsPath = "./.ssh/known_hosts"
+
+// 确认主机密钥时同时连接的主机数
+const ensureKeyConcurrency = 10
+
+// agent 上报时每个主机最多记录的待确认密钥数
+const maxPendingKeys = 10
+
+// knownHostsMu 串行写入 known_hosts，避免并发任务互相覆盖
+var knownHostsMu sync.Mutex
+
+// keyOwner 主机密钥的归属，主机和跳板机二选一
+type keyOwner struct {
+	hostID     uint
+	jumpHostID uint
+	name       string
+}
+
+func hostOwner(host models.HostModel) keyOwner {
+	return keyOwner{hostID: host.ID, name: host.Name}
+}
+
+func jumpHostOwner(jumpHost *models.JumpHostModel) keyOwner {
+	return keyOwner{jumpHostID: jumpHost.ID, name: "跳板机 " + jumpHost.Name}
+}
+
+func (o keyOwner) keys(status string) []models.HostKeyModel {
+	var keys []models.HostKeyModel
+	global.DB.Where("host_id = ? AND jump_host_id = ? AND status = ?", o.hostID, o.jumpHostID, status).Find(&keys)
+	return keys
+}
+
+// hostKeyConfig 返回校验主机密钥的回调，以及让服务端优先出示已信任类型密钥的算法列表
+func hostKeyConfig(owner keyOwner) (ssh.HostKeyCallback, []string) {
+	trusted := owner.keys(models.HostKeyTrusted)
+	var algorithms []string
+	seen := map[string]bool{}
+	for _, key := range trusted {
+		for _, algo := range hostKeyAlgorithms(key.Algorithm) {
+			if !seen[algo] {
+				seen[algo] = true
+				algorithms = append(algorithms, algo)
+			}
+		}
+	}
+
+	callback := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		// 首次连接，信任并记录
+		if len(trusted) == 0 {
+			trustKey(owner, key, models.HostKeySourceSSH)
+			return nil
+		}
+		if matchTrusted(trusted, key) {
+			return nil
+		}
+		recordMismatch(owner, key, models.HostKeySourceSSH)
+		return fmt.Errorf("%s 的主机密钥与已信任的不一致（%s），可能存在中间人攻击，请管理员核实后接受新密钥",
+			owner.name, ssh.FingerprintSHA256(key))
+	}
+	return callback, algorithms
+}
+
+// hostKeyAlgorithms 密钥类型对应的主机密钥算法，RSA 密钥可以用多种签名算法
+func hostKeyAlgorithms(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+func matchTrusted(trusted []models.HostKeyModel, key ssh.PublicKey) bool {
+	fingerprint := ssh.FingerprintSHA256(key)
+	for _, t := range trusted {
+		if t.Fingerprint == fingerprint {
+			global.DB.Model(&models.HostKeyModel{}).Where("id = ?", t.ID).Update("last_seen", time.Now())
+			return true
+		}
+	}
+	return false
+}
+
+// trustKey 记录为已信任，指纹已存在时只更新状态
+func trustKey(owner keyOwner, key ssh.PublicKey, source string) {
+	record := models.HostKeyModel{
+		HostID:      owner.hostID,
+		JumpHostID:  owner.jumpHostID,
+		Algorithm:   key.Type(),
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		Source:      source,
+		Status:      models.HostKeyTrusted,
+		LastSeen:    time.Now(),
+	}
+	err := global.DB.Where("host_id = ? AND jump_host_id = ? AND fingerprint = ?", owner.hostID, owner.jumpHostID, record.Fingerprint).
+		Assign(map[string]any{"status": models.HostKeyTrusted, "last_seen": record.LastSeen}).
+		FirstOrCreate(&record).Error
+	if err != nil {
+		global.Log.Errorf("记录 %s 的主机密钥失败: %v", owner.name, err)
+		return
+	}
+	global.Log.Infof("信任 %s 的主机密钥 %s（%s）", owner.name, record.Fingerprint, source)
+}
+
+// recordMismatch 记录未信任的密钥（与已信任的不一致或新类型的密钥），第一次见到时生成告警记录，
+// 配置了通知时同时发送通知。同一主机已有未解除的密钥告警时不再重复告警
+func recordMismatch(owner keyOwner, key ssh.PublicKey, source string) {
+	if !recordPending(owner, key, source) {
+		return
+	}
+	if hostKeyAlerting(owner) {
+		return
+	}
+	fingerprint := ssh.FingerprintSHA256(key)
+	var changed int64
+	global.DB.Model(&models.HostKeyModel{}).
+		Where("host_id = ? AND jump_host_id = ? AND algorithm = ? AND status = ?", owner.hostID, owner.jumpHostID, key.Type(), models.HostKeyTrusted).
+		Count(&changed)
+	reason := "出现未信任的新类型 SSH 主机密钥，在管理员确认前不会使用该密钥"
+	if changed > 0 {
+		reason = "SSH 主机密钥发生变化，在管理员确认前，终端和任务都会拒绝连接该主机"
+	}
+	now := time.Now()
+	description := fmt.Sprintf("%s：%s，新指纹: %s，来源: %s", owner.name, reason, fingerprint, source)
+	alertRecord := alertmodel.AlertRecord{
+		RuleID:      alertmodel.SystemAlertRuleID,
+		HostID:      uint64(owner.hostID),
+		Status:      alertmodel.AlertStatusAlerting,
+		StartTime:   now,
+		Description: description,
+	}
+	if err := global.DB.Create(&alertRecord).Error; err != nil {
+		global.Log.Errorf("生成主机密钥告警失败: %v", err)
+	}
+
+	notificationId := global.Config.System.HostKeyNotificationId
+	if notificationId == 0 {
+		return
+	}
+	content := fmt.Sprintf("【主机密钥告警】%s\n时间: %s", description, now.Format("2006-01-02 15:04:05"))
+	go func() {
+		if err := alert.SendNotification(notificationId, content); err != nil {
+			global.Log.Errorf("发送主机密钥告警失败: %v", err)
+		}
+	}()
+}
+
+// recordPending 记录待确认的密钥，返回是否为第一次见到的密钥。
+// agent 上报的待确认密钥每个主机有上限，避免伪造的上报无限写入
+func recordPending(owner keyOwner, key ssh.PublicKey, source string) bool {
+	fingerprint := ssh.FingerprintSHA256(key)
+	global.Log.Warnf("%s 的主机密钥未信任: %s（%s）", owner.name, fingerprint, source)
+
+	var existing models.HostKeyModel
+	err := global.DB.Where("host_id = ? AND jump_host_id = ? AND fingerprint = ?", owner.hostID, owner.jumpHostID, fingerprint).
+		First(&existing).Error
+	if err == nil {
+		global.DB.Model(&existing).Update("last_seen", time.Now())
+		return false
+	}
+	var pending int64
+	global.DB.Model(&models.HostKeyModel{}).
+		Where("host_id = ? AND jump_host_id = ? AND status = ?", owner.hostID, owner.jumpHostID, models.HostKeyPending).
+		Count(&pending)
+	if source == models.HostKeySourceAgent && pending >= maxPendingKeys {
+		global.Log.Warnf("%s 待确认的主机密钥已达 %d 个，不再记录新的密钥", owner.name, maxPendingKeys)
+		return false
+	}
+	record := models.HostKeyModel{
+		HostID:      owner.hostID,
+		JumpHostID:  owner.jumpHostID,
+		Algorithm:   key.Type(),
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+		Fingerprint: fingerprint,
+		Source:      source,
+		Status:      models.HostKeyPending,
+		LastSeen:    time.Now(),
+	}
+	if err := global.DB.Create(&record).Error; err != nil {
+		global.Log.Errorf("记录 %s 的主机密钥失败: %v", owner.name, err)
+		return false
+	}
+	return true
+}
+
+// hostKeyAlerting 主机是否已有未解除的密钥告警
+func hostKeyAlerting(owner keyOwner) bool {
+	var count int64
+	global.DB.Model(&alertmodel.AlertRecord{}).
+		Where("rule_id = ? AND host_id = ? AND status = ?", alertmodel.SystemAlertRuleID, owner.hostID, alertmodel.AlertStatusAlerting).
+		Count(&count)
+	return count > 0
+}
+
+// ReportHostKeys 处理 agent 上报的主机密钥（/etc/ssh/ssh_host_*_key.pub 的内容）。
+// 只有携带认证令牌的 agent 上报、且平台还没有该主机任何已信任的密钥时才全部信任；
+// 未认证的上报可能来自主机上的任意用户，与已信任密钥不一致的都记为待确认，由管理员接受。
+// 还没有已信任密钥时，未认证上报的密钥只记为待确认、不告警，由平台首次 SSH 连接时记录
+func ReportHostKeys(host models.HostModel, lines []string, authenticated bool) {
+	owner := hostOwner(host)
+	trusted := owner.keys(models.HostKeyTrusted)
+	firstSeen := len(trusted) == 0
+
+	for _, line := range lines {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			global.Log.Warnf("主机 %s 上报的主机密钥格式错误: %v", host.Name, err)
+			continue
+		}
+		switch {
+		case firstSeen && authenticated:
+			trustKey(owner, key, models.HostKeySourceAgent)
+		case firstSeen:
+			recordPending(owner, key, models.HostKeySourceAgent)
+		case matchTrusted(trusted, key):
+		default:
+			recordMismatch(owner, key, models.HostKeySourceAgent)
+		}
+	}
+}
+
+// AcceptHostKey 管理员接受待确认的密钥，同类型的旧密钥不再信任
+func AcceptHostKey(hostID, jumpHostID uint, fingerprint string) error {
+	var key models.HostKeyModel
+	if err := global.DB.Where("host_id = ? AND jump_host_id = ? AND fingerprint = ?", hostID, jumpHostID, fingerprint).
+		First(&key).Error; err != nil {
+		return errors.New("密钥不存在")
+	}
+	tx := global.DB.Begin()
+	if err := tx.Where("host_id = ? AND jump_host_id = ? AND algorithm = ? AND id <> ?", hostID, jumpHostID, key.Algorithm, key.ID).
+		Delete(&models.HostKeyModel{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Model(&key).Updates(map[string]any{
+		"status": models.HostKeyTrusted,
+		"source": models.HostKeySourceAdmin,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	resolveHostKeyAlerts(hostID)
+	if err := WriteKnownHosts(); err != nil {
+		global.Log.Errorf("生成 known_hosts 失败: %v", err)
+	}
+	return nil
+}
+
+// resolveHostKeyAlerts 没有待确认的密钥后解除主机密钥告警，跳板机的告警记录主机ID都为 0
+func resolveHostKeyAlerts(hostID uint) {
+	var pending int64
+	global.DB.Model(&models.HostKeyModel{}).Where("host_id = ? AND status = ?", hostID, models.HostKeyPending).Count(&pending)
+	if pending > 0 {
+		return
+	}
+	now := time.Now()
+	global.DB.Model(&alertmodel.AlertRecord{}).
+		Where("rule_id = ? AND host_id = ? AND status = ?", alertmodel.SystemAlertRuleID, hostID, alertmodel.AlertStatusAlerting).
+		Updates(map[string]any{"status": alertmodel.AlertStatusResolved, "end_time": &now})
+}
+
+// KnownHostsArgs ssh 命令行使用平台 known_hosts 严格校验主机密钥的参数
+func KnownHostsArgs() string {
+	path, err := filepath.Abs(KnownHostsPath)
+	if err != nil {
+		path = KnownHostsPath
+	}
+	return fmt.Sprintf("-o UserKnownHostsFile=%s -o StrictHostKeyChecking=yes", path)
+}
+
+// PrepareKnownHosts 任务执行前调用：还没有已信任密钥的主机先连接一次记录密钥，再重新生成 known_hosts
+func PrepareKnownHosts(hosts []models.HostModel) error {
+	var pending []models.HostModel
+	jumpHosts := LoadJumpHosts(hosts)
+	for _, host := range hosts {
+		owner := hostOwner(host)
+		// 经过的跳板机也需要已信任的密钥
+		if jumpHost := jumpHosts.Resolve(host); jumpHost != nil && len(jumpHostOwner(jumpHost).keys(models.HostKeyTrusted)) == 0 {
+			pending = append(pending, host)
+			continue
+		}
+		if len(owner.keys(models.HostKeyTrusted)) == 0 {
+			pending = append(pending, host)
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, ensureKeyConcurrency)
+	for _, host := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host models.HostModel) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			client, err := Dial(host)
+			if err != nil {
+				global.Log.Warnf("获取主机 %s 的主机密钥失败: %v", host.Name, err)
+				return
+			}
+			client.Close()
+		}(host)
+	}
+	wg.Wait()
+	return WriteKnownHosts()
+}
+
+// WriteKnownHosts 用所有已信任的密钥重新生成 known_hosts
+func WriteKnownHosts() error {
+	var keys []models.HostKeyModel
+	if err := global.DB.Where("status = ?", models.HostKeyTrusted).Find(&keys).Error; err != nil {
+		return err
+	}
+	var hosts []models.HostModel
+	global.DB.Select("id", "host_server_url").Find(&hosts)
+	hostAddr := map[uint]string{}
+	for _, host := range hosts {
+		hostAddr[host.ID] = host.HostServerUrl
+	}
+	var jumpHosts []models.JumpHostModel
+	global.DB.Find(&jumpHosts)
+	jumpAddr := map[uint]string{}
+	for _, jumpHost := range jumpHosts {
+		jumpAddr[jumpHost.ID] = net.JoinHostPort(jumpHost.Address, strconv.Itoa(jumpPort(&jumpHost)))
+	}
+
+	var b strings.Builder
+	for _, key := range keys {
+		addr := hostAddr[key.HostID]
+		if key.JumpHostID != 0 {
+			addr = jumpAddr[key.JumpHostID]
+		}
+		if addr == "" {
+			continue
+		}
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key.PublicKey))
+		if err != nil {
+			continue
+		}
+		b.WriteString(knownhosts.Line([]string{knownhosts.Normalize(addr)}, publicKey))
+		b.WriteString("\n")
+	}
+
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(KnownHostsPath), 0700); err != nil {
+		return err
+	}
+	tmp := KnownHostsPath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, KnownHostsPath)
+}
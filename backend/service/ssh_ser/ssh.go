@@ -0,0 +1,278 @@
+package ssh_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PrivateKeyPath 平台密钥路径，由 core.InitKeysConfiguration 写入
+const PrivateKeyPath = "./.ssh/ccops"
+
+// dialTimeout SSH 建连超时时间
+const dialTimeout = 10 * time.Second
+
+// LoadSigner 读取平台私钥并转换成可用于 SSH 认证的格式
+func LoadSigner() (ssh.Signer, error) {
+	key, err := os.ReadFile(PrivateKeyPath)
+	if err != nil {
+		absPath, _ := filepath.Abs(PrivateKeyPath)
+		return nil, fmt.Errorf("读取私钥失败(%s): %w", absPath, err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败: %w", err)
+	}
+	return signer, nil
+}
+
+// ResolveJumpHost 获取主机需要经过的跳板机：主机上直接配置的优先，其次取主机所属标签上配置的跳板机
+func ResolveJumpHost(host models.HostModel) *models.JumpHostModel {
+	return LoadJumpHosts([]models.HostModel{host}).Resolve(host)
+}
+
+// JumpHosts 一批主机的跳板机，生成 inventory 等需要处理大量主机时一次查出，避免逐台查询
+type JumpHosts struct {
+	byID    map[uint]*models.JumpHostModel
+	byLabel map[uint]uint // 主机 -> 主机所属标签上配置的跳板机
+}
+
+// LoadJumpHosts 查询主机直接配置的和所属标签上配置的跳板机
+func LoadJumpHosts(hosts []models.HostModel) *JumpHosts {
+	j := &JumpHosts{byID: map[uint]*models.JumpHostModel{}, byLabel: map[uint]uint{}}
+	ids := map[uint]bool{}
+	var labelHostIDs []uint
+	for _, host := range hosts {
+		if host.JumpHostID != 0 {
+			ids[host.JumpHostID] = true
+		} else {
+			labelHostIDs = append(labelHostIDs, host.ID)
+		}
+	}
+	if len(labelHostIDs) > 0 {
+		var rows []struct {
+			HostModelID uint
+			JumpHostID  uint
+		}
+		global.DB.Model(&models.LabelModel{}).
+			Select("host_labels.host_model_id, label_models.jump_host_id").
+			Joins("JOIN host_labels ON host_labels.label_model_id = label_models.id").
+			Where("host_labels.host_model_id IN ? AND label_models.jump_host_id > 0", labelHostIDs).
+			Order("label_models.id").
+			Scan(&rows)
+		for _, row := range rows {
+			// 多个标签都配置了跳板机时取标签ID最小的
+			if _, ok := j.byLabel[row.HostModelID]; !ok {
+				j.byLabel[row.HostModelID] = row.JumpHostID
+				ids[row.JumpHostID] = true
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return j
+	}
+
+	list := make([]uint, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+	var jumpHosts []models.JumpHostModel
+	global.DB.Where("id IN ?", list).Find(&jumpHosts)
+	for i := range jumpHosts {
+		j.byID[jumpHosts[i].ID] = &jumpHosts[i]
+	}
+	return j
+}
+
+// Resolve 主机需要经过的跳板机，无需跳板机时返回 nil
+func (j *JumpHosts) Resolve(host models.HostModel) *models.JumpHostModel {
+	jumpHostID := host.JumpHostID
+	if jumpHostID == 0 {
+		jumpHostID = j.byLabel[host.ID]
+	}
+	if jumpHostID == 0 {
+		return nil
+	}
+	jumpHost, ok := j.byID[jumpHostID]
+	if !ok {
+		global.Log.Warnf("主机 %s 配置的跳板机 %d 不存在", host.Name, jumpHostID)
+		return nil
+	}
+	return jumpHost
+}
+
+var (
+	// 跳板机地址和用户会拼接到 ssh 命令行中，只允许主机名、IP 和普通用户名中的字符
+	jumpAddressRe = regexp.MustCompile(`^[A-Za-z0-9_.:][A-Za-z0-9_.:-]*$`)
+	jumpUserRe    = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+)
+
+// ValidateJumpHost 检查跳板机地址和登录用户，用户为空时使用 root
+func ValidateJumpHost(address, user string) error {
+	if !jumpAddressRe.MatchString(address) {
+		return fmt.Errorf("跳板机地址 %q 格式错误", address)
+	}
+	if user != "" && !jumpUserRe.MatchString(user) {
+		return fmt.Errorf("跳板机登录用户 %q 格式错误", user)
+	}
+	return nil
+}
+
+// Dial 以平台身份连接主机，用于任务和获取主机密钥等平台自身的操作
+func Dial(host models.HostModel) (*ssh.Client, error) {
+	return DialAs(host, CertUser{Purpose: models.CertPurposePlatform})
+}
+
+// DialAs 以 root 身份连接主机，证书中记录实际操作的用户，必要时经过跳板机
+func DialAs(host models.HostModel, user CertUser) (*ssh.Client, error) {
+	user.HostID = host.ID
+	auth, err := authMethods(user)
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, hostKeyAlgorithms := hostKeyConfig(hostOwner(host))
+	config := &ssh.ClientConfig{
+		User:              "root",
+		Auth:              auth,
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: hostKeyAlgorithms,
+		Timeout:           dialTimeout,
+	}
+	address := net.JoinHostPort(host.HostServerUrl, "22")
+
+	jumpHost := ResolveJumpHost(host)
+	if jumpHost == nil {
+		return ssh.Dial("tcp", address, config)
+	}
+
+	jumpClient, err := dialJumpHost(jumpHost)
+	if err != nil {
+		return nil, err
+	}
+
+	// 通过跳板机打开到目标主机的 TCP 通道，再在其上完成 SSH 握手
+	conn, err := jumpClient.Dial("tcp", address)
+	if err != nil {
+		jumpClient.Close()
+		return nil, fmt.Errorf("跳板机 %s 无法连接目标主机: %w", jumpHost.Name, err)
+	}
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		conn.Close()
+		jumpClient.Close()
+		return nil, err
+	}
+	client := ssh.NewClient(clientConn, chans, reqs)
+
+	// 目标连接断开后一并关闭跳板机连接
+	go func() {
+		client.Wait()
+		jumpClient.Close()
+	}()
+	return client, nil
+}
+
+// dialJumpHost 连接跳板机，跳板机不由 agent 管理，未单独配置私钥时使用平台静态密钥
+func dialJumpHost(jumpHost *models.JumpHostModel) (*ssh.Client, error) {
+	var signer ssh.Signer
+	if jumpHost.PrivateKey == "" {
+		platformSigner, err := LoadSigner()
+		if err != nil {
+			return nil, err
+		}
+		signer = platformSigner
+	} else {
+		jumpSigner, err := ssh.ParsePrivateKey([]byte(jumpHost.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("解析跳板机 %s 私钥失败: %w", jumpHost.Name, err)
+		}
+		signer = jumpSigner
+	}
+
+	hostKeyCallback, hostKeyAlgorithms := hostKeyConfig(jumpHostOwner(jumpHost))
+	config := &ssh.ClientConfig{
+		User: jumpUser(jumpHost),
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		},
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: hostKeyAlgorithms,
+		Timeout:           dialTimeout,
+	}
+	client, err := ssh.Dial("tcp", net.JoinHostPort(jumpHost.Address, strconv.Itoa(jumpPort(jumpHost))), config)
+	if err != nil {
+		return nil, fmt.Errorf("连接跳板机 %s 失败: %w", jumpHost.Name, err)
+	}
+	return client, nil
+}
+
+// AnsibleSSHArgs 生成 inventory 中的 ansible_ssh_common_args，jumpHost 为 nil 时返回空字符串。
+// 命令行上的 -i 等参数不会传递给 ProxyJump 建立的跳板机连接，
+// 所以这里用等价的 ProxyCommand 显式指定跳板机使用的私钥。
+// 跳板机的独立私钥写入任务的临时文件，任务结束后由 RemoveTaskKey 删除
+func AnsibleSSHArgs(jumpHost *models.JumpHostModel, taskID uint) (string, error) {
+	if jumpHost == nil {
+		return "", nil
+	}
+	if err := ValidateJumpHost(jumpHost.Address, jumpHost.User); err != nil {
+		return "", err
+	}
+
+	keyPath, err := jumpKeyPath(jumpHost, taskID)
+	if err != nil {
+		return "", err
+	}
+	proxyCommand := fmt.Sprintf("ssh -W %%h:%%p -q -i %s -p %d %s %s@%s",
+		keyPath, jumpPort(jumpHost), KnownHostsArgs(), jumpUser(jumpHost), jumpHost.Address)
+	return fmt.Sprintf(`-o ProxyCommand="%s"`, proxyCommand), nil
+}
+
+// jumpKeyPath 返回 ssh 命令行连接跳板机时使用的私钥文件，独立私钥写入任务的临时文件
+func jumpKeyPath(jumpHost *models.JumpHostModel, taskID uint) (string, error) {
+	if jumpHost.PrivateKey == "" {
+		return PrivateKeyPath, nil
+	}
+	keyPath := fmt.Sprintf("%s-jump_%d", taskKeyPath(taskID), jumpHost.ID)
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(keyPath, []byte(jumpHost.PrivateKey), 0600); err != nil {
+		return "", errors.New("写入跳板机私钥失败")
+	}
+	return keyPath, nil
+}
+
+func jumpUser(jumpHost *models.JumpHostModel) string {
+	if jumpHost.User == "" {
+		return "root"
+	}
+	return jumpHost.User
+}
+
+func jumpPort(jumpHost *models.JumpHostModel) int {
+	if jumpHost.Port == 0 {
+		return 22
+	}
+	return jumpHost.Port
+}
+
+// ProxyJumpArgs 生成导出给外部使用的跳板机参数，不引用平台本地的私钥文件，jumpHost 为 nil 时返回空字符串
+func ProxyJumpArgs(jumpHost *models.JumpHostModel) string {
+	if jumpHost == nil {
+		return ""
+	}
+	if err := ValidateJumpHost(jumpHost.Address, jumpHost.User); err != nil {
+		global.Log.Warnf("跳板机 %s 配置错误，不导出跳板机参数: %v", jumpHost.Name, err)
+		return ""
+	}
+	return fmt.Sprintf("-o ProxyJump=%s@%s:%d", jumpUser(jumpHost), jumpHost.Address, jumpPort(jumpHost))
+}
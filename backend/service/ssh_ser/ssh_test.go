@@ -0,0 +1,33 @@
+package ssh_ser
+
+import "testing"
+
+func TestValidateJumpHost(t *testing.T) {
+	tests := []struct {
+		address string
+		user    string
+		ok      bool
+	}{
+		{"10.0.0.1", "root", true},
+		{"bastion.example.com", "", true},
+		{"::1", "ops_user", true},
+		{"fe80::1", "deploy-1", true},
+		{"jump_1", "user.name", true},
+
+		{"", "root", false},
+		{"-oProxyCommand=sh", "root", false},
+		{"10.0.0.1", "-oProxyCommand=sh", false},
+		{"10.0.0.1 -v", "root", false},
+		{`10.0.0.1"`, "root", false},
+		{"10.0.0.1';id;'", "root", false},
+		{"10.0.0.1", "root@evil", false},
+		{"10.0.0.1", "a b", false},
+		{"10.0.0.1$(id)", "root", false},
+	}
+	for _, tt := range tests {
+		err := ValidateJumpHost(tt.address, tt.user)
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidateJumpHost(%q, %q) = %v，是否应通过: %v", tt.address, tt.user, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package task_ser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 任务事件类型
+const (
+	EventTaskStarted  = "task_started"
+	EventHostStarted  = "host_started"
+	EventStepResult   = "step_result"
+	EventHostFinished = "host_finished"
+	EventTaskFinished = "task_finished"
+	EventOutput       = "output" // 原始输出行，只在请求时推送
+)
+
+// Event 任务事件。事件由输出逐行解析得到，重放时结果一致，
+// 因此 ID 按推送顺序编号即可用于断线续传
+type Event struct {
+	ID      int            `json:"id"`
+	Type    string         `json:"type"`
+	TaskID  uint           `json:"taskId"`
+	Seq     int            `json:"seq"`              // 产生该事件的输出行号
+	Host    string         `json:"host,omitempty"`   // 主机名
+	Play    string         `json:"play,omitempty"`   // 所属 play
+	Step    string         `json:"step,omitempty"`   // ansible task 名称
+	Status  string         `json:"status,omitempty"` // step_result: ok/changed/failed/unreachable/skipped/rescued/ignored；host_finished: ok/failed/unreachable/unknown；task_finished: 任务最终状态
+	Message string         `json:"message,omitempty"`
+	Stats   map[string]int `json:"stats,omitempty"` // host_finished 时的 PLAY RECAP 统计
+}
+
+var (
+	playRe       = regexp.MustCompile(`^PLAY \[(.*)\] \**\s*$`)
+	recapRe      = regexp.MustCompile(`^PLAY RECAP \**\s*$`)
+	stepRe       = regexp.MustCompile(`^(?:TASK|RUNNING HANDLER) \[(.*)\] \**\s*$`)
+	stepResultRe = regexp.MustCompile(`^(ok|changed|failed|fatal|skipping|unreachable|rescued|ignored): \[([^\]]+)\](.*)$`)
+	recapLineRe  = regexp.MustCompile(`^(\S+)\s+:\s+((?:\w+=\d+\s*)+)$`)
+	recapStatRe  = regexp.MustCompile(`(\w+)=(\d+)`)
+	adhocRe      = regexp.MustCompile(`^(\S+) \| (SUCCESS|CHANGED|FAILED!?|UNREACHABLE!)(?: \| rc=(\d+))? (?:>>|=>)(.*)$`)
+)
+
+// maxMessageLen 事件中携带的 ansible 返回内容的最大长度
+const maxMessageLen = 2048
+
+// EventParser 从 ansible-playbook 或 ad-hoc 的默认输出中解析事件，需按行号顺序调用
+type EventParser struct {
+	taskID   uint
+	play     string
+	step     string
+	inRecap  bool
+	started  map[string]bool
+	finished map[string]bool
+	hosts    []string // 按出现顺序记录的主机
+}
+
+func NewEventParser(taskID uint) *EventParser {
+	return &EventParser{
+		taskID:   taskID,
+		started:  make(map[string]bool),
+		finished: make(map[string]bool),
+	}
+}
+
+// Parse 解析一行输出
+func (p *EventParser) Parse(line Line) []Event {
+	text := strings.TrimRight(line.Text, "\r")
+	if m := playRe.FindStringSubmatch(text); m != nil {
+		p.play, p.step, p.inRecap = m[1], "", false
+		return nil
+	}
+	if recapRe.MatchString(text) {
+		p.inRecap = true
+		return nil
+	}
+	if m := stepRe.FindStringSubmatch(text); m != nil {
+		p.step = m[1]
+		return nil
+	}
+
+	if p.inRecap {
+		m := recapLineRe.FindStringSubmatch(text)
+		if m == nil {
+			return nil
+		}
+		stats := make(map[string]int)
+		for _, s := range recapStatRe.FindAllStringSubmatch(m[2], -1) {
+			stats[s[1]], _ = strconv.Atoi(s[2])
+		}
+		status := "ok"
+		if stats["unreachable"] > 0 {
+			status = "unreachable"
+		} else if stats["failed"] > 0 {
+			status = "failed"
+		}
+		events := p.hostStarted(line.Seq, m[1])
+		return append(events, p.hostFinished(line.Seq, m[1], status, stats)...)
+	}
+
+	if m := stepResultRe.FindStringSubmatch(text); m != nil {
+		host := m[2]
+		// 委托执行时输出为 [host -> delegate]
+		if i := strings.Index(host, " -> "); i >= 0 {
+			host = host[:i]
+		}
+		status := m[1]
+		rest := m[3]
+		switch status {
+		case "fatal":
+			status = "failed"
+		case "skipping":
+			status = "skipped"
+		}
+		if strings.HasPrefix(strings.TrimSpace(rest), ": UNREACHABLE!") {
+			status = "unreachable"
+		}
+		events := p.hostStarted(line.Seq, host)
+		return append(events, Event{
+			Type:    EventStepResult,
+			TaskID:  p.taskID,
+			Seq:     line.Seq,
+			Host:    host,
+			Play:    p.play,
+			Step:    p.step,
+			Status:  status,
+			Message: resultMessage(rest),
+		})
+	}
+
+	// ad-hoc 每个主机只有一条结果
+	if m := adhocRe.FindStringSubmatch(text); m != nil {
+		host := m[1]
+		status := "ok"
+		switch strings.TrimSuffix(m[2], "!") {
+		case "CHANGED":
+			status = "changed"
+		case "FAILED":
+			status = "failed"
+		case "UNREACHABLE":
+			status = "unreachable"
+		}
+		message := strings.TrimSpace(m[4])
+		if m[3] != "" {
+			message = "rc=" + m[3]
+		}
+		events := p.hostStarted(line.Seq, host)
+		events = append(events, Event{
+			Type:    EventStepResult,
+			TaskID:  p.taskID,
+			Seq:     line.Seq,
+			Host:    host,
+			Step:    "shell",
+			Status:  status,
+			Message: message,
+		})
+		finishStatus := status
+		if finishStatus == "changed" {
+			finishStatus = "ok"
+		}
+		return append(events, p.hostFinished(line.Seq, host, finishStatus, nil)...)
+	}
+	return nil
+}
+
+// Finish 输出结束时调用，为没有出现在 PLAY RECAP 中的主机补发 host_finished
+func (p *EventParser) Finish(seq int) []Event {
+	var events []Event
+	for _, host := range p.hosts {
+		if !p.finished[host] {
+			events = append(events, p.hostFinished(seq, host, "unknown", nil)...)
+		}
+	}
+	return events
+}
+
+func (p *EventParser) hostStarted(seq int, host string) []Event {
+	if p.started[host] {
+		return nil
+	}
+	p.started[host] = true
+	p.hosts = append(p.hosts, host)
+	return []Event{{Type: EventHostStarted, TaskID: p.taskID, Seq: seq, Host: host, Play: p.play}}
+}
+
+func (p *EventParser) hostFinished(seq int, host, status string, stats map[string]int) []Event {
+	if p.finished[host] {
+		return nil
+	}
+	p.finished[host] = true
+	return []Event{{Type: EventHostFinished, TaskID: p.taskID, Seq: seq, Host: host, Status: status, Stats: stats}}
+}
+
+// resultMessage 取 "=>" 之后的返回内容
+func resultMessage(rest string) string {
+	i := strings.Index(rest, "=>")
+	if i < 0 {
+		return ""
+	}
+	message := strings.TrimSpace(rest[i+2:])
+	if len(message) > maxMessageLen {
+		message = message[:maxMessageLen] + "..."
+	}
+	return message
+}
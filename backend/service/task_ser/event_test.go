@@ -0,0 +1,70 @@
+package task_ser
+
+import (
+	"strings"
+	"testing"
+)
+
+func parseAll(output string) []Event {
+	parser := NewEventParser(1)
+	var events []Event
+	for i, text := range strings.Split(output, "\n") {
+		events = append(events, parser.Parse(Line{Seq: i, Text: text})...)
+	}
+	return append(events, parser.Finish(len(output))...)
+}
+
+func TestEventParserPlaybook(t *testing.T) {
+	output := `PLAY [deploy] ******************************************************************
+
+TASK [Gathering Facts] *********************************************************
+ok: [web1]
+fatal: [web2]: UNREACHABLE! => {"changed": false, "msg": "timeout", "unreachable": true}
+
+TASK [nginx : install] *********************************************************
+changed: [web1]
+
+PLAY RECAP *********************************************************************
+web1                       : ok=2    changed=1    unreachable=0    failed=0    skipped=0    rescued=0    ignored=0
+web2                       : ok=0    changed=0    unreachable=1    failed=0    skipped=0    rescued=0    ignored=0`
+
+	var types []string
+	for _, e := range parseAll(output) {
+		types = append(types, e.Type+":"+e.Host+":"+e.Status)
+	}
+	want := []string{
+		"host_started:web1:",
+		"step_result:web1:ok",
+		"host_started:web2:",
+		"step_result:web2:unreachable",
+		"step_result:web1:changed",
+		"host_finished:web1:ok",
+		"host_finished:web2:unreachable",
+	}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("事件不符:\n got %v\nwant %v", types, want)
+	}
+}
+
+func TestEventParserAdHoc(t *testing.T) {
+	output := `web1 | CHANGED | rc=0 >>
+hello
+web2 | FAILED | rc=1 >>
+no such file`
+
+	var types []string
+	for _, e := range parseAll(output) {
+		types = append(types, e.Type+":"+e.Host+":"+e.Status)
+	}
+	want := []string{
+		"host_started:web1:",
+		"step_result:web1:changed",
+		"host_finished:web1:ok",
+		"host_started:web2:",
+		"step_result:web2:failed",
+		"host_finished:web2:failed",
+	}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("事件不符:\n got %v\nwant %v", types, want)
+	}
+}
@@ -0,0 +1,286 @@
+package task_ser
+
+import (
+	"bytes"
+	"ccops/global"
+	"ccops/models"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// chunkLines 每个分块最多包含的行数
+	chunkLines = 500
+	// chunkBytes 每个分块压缩前的最大字节数
+	chunkBytes = 256 * 1024
+	// subscriberBuffer 订阅者缓冲的行数，消费跟不上时断开订阅，由客户端按偏移量重连
+	subscriberBuffer = 1024
+	// closeFlushRetries 结束时剩余输出落库的尝试次数，都失败时改为写入 result 字段
+	closeFlushRetries = 3
+)
+
+// Line 一行任务输出，Seq 为从0开始的行号
+type Line struct {
+	Seq  int    `json:"seq"`
+	Text string `json:"text"`
+}
+
+// Output 正在执行的任务的输出。内存中只保留尚未落库的一块，写满后压缩写入 TaskOutputChunkModel
+type Output struct {
+	TaskID uint
+
+	mu           sync.Mutex
+	flushMu      sync.Mutex // 保证分块按顺序写入，写库时不持有 mu
+	flushing     []string   // 正在写入数据库的行，紧接在 pending 之前
+	pending      []string   // 尚未落库的行
+	pendingStart int        // pending 第一行的行号
+	pendingBytes int
+	chunkIndex   int
+	closed       bool
+	subscribers  map[chan Line]struct{}
+}
+
+var (
+	outputs   = make(map[uint]*Output)
+	outputsMu sync.Mutex
+)
+
+// NewOutput 创建任务输出并登记为运行中，任务结束时需调用 Close
+func NewOutput(taskID uint) *Output {
+	out := &Output{
+		TaskID:      taskID,
+		subscribers: make(map[chan Line]struct{}),
+	}
+	outputsMu.Lock()
+	outputs[taskID] = out
+	outputsMu.Unlock()
+	return out
+}
+
+// GetOutput 获取运行中的任务输出，任务未运行时返回 nil
+func GetOutput(taskID uint) *Output {
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+	return outputs[taskID]
+}
+
+// Append 追加一行输出并推送给订阅者，攒满一块后写入数据库
+func (o *Output) Append(text string) {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return
+	}
+
+	line := Line{Seq: o.pendingStart + len(o.pending), Text: text}
+	o.pending = append(o.pending, text)
+	o.pendingBytes += len(text) + 1
+
+	for ch := range o.subscribers {
+		select {
+		case ch <- line:
+		default:
+			// 订阅者消费过慢，断开后由客户端从已收到的行号续传
+			delete(o.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	full := o.full()
+	o.mu.Unlock()
+
+	if full {
+		if err := o.flush(false); err != nil {
+			global.Log.Errorf("任务 %d 输出落库失败: %v", o.TaskID, err)
+		}
+	}
+}
+
+// full 尚未落库的行是否已满一块，调用方需持有 mu
+func (o *Output) full() bool {
+	return len(o.pending) >= chunkLines || o.pendingBytes >= chunkBytes
+}
+
+// Total 当前已产生的行数
+func (o *Output) Total() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.pendingStart + len(o.pending)
+}
+
+// Subscribe 订阅之后产生的输出，返回订阅时已有的行数。
+// 任务结束或消费过慢时 channel 会被关闭；任务已结束时返回 nil channel
+func (o *Output) Subscribe() (<-chan Line, int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	total := o.pendingStart + len(o.pending)
+	if o.closed {
+		return nil, total
+	}
+	ch := make(chan Line, subscriberBuffer)
+	o.subscribers[ch] = struct{}{}
+	return ch, total
+}
+
+// Unsubscribe 取消订阅
+func (o *Output) Unsubscribe(ch <-chan Line) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for sub := range o.subscribers {
+		if sub == ch {
+			delete(o.subscribers, sub)
+			close(sub)
+			return
+		}
+	}
+}
+
+// Close 将剩余输出落库，关闭所有订阅并从运行中列表移除。
+// 落库失败时重试，仍然失败则把未落库的行写入任务的 result 字段，避免输出静默丢失
+func (o *Output) Close() error {
+	o.mu.Lock()
+	o.closed = true
+	for ch := range o.subscribers {
+		delete(o.subscribers, ch)
+		close(ch)
+	}
+	o.mu.Unlock()
+
+	var err error
+	for attempt := 1; attempt <= closeFlushRetries; attempt++ {
+		if err = o.flush(true); err == nil {
+			break
+		}
+		if attempt < closeFlushRetries {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+	if err != nil {
+		o.mu.Lock()
+		lines, start := o.pending, o.pendingStart
+		o.mu.Unlock()
+		if saveErr := saveUnflushed(o.TaskID, start, lines); saveErr != nil {
+			err = fmt.Errorf("%w; 写入 result 字段也失败: %v", err, saveErr)
+		}
+	}
+
+	outputsMu.Lock()
+	if outputs[o.TaskID] == o {
+		delete(outputs, o.TaskID)
+	}
+	outputsMu.Unlock()
+	return err
+}
+
+// saveUnflushed 分块写入失败时，把未落库的行写入 result 字段，超出长度时只保留末尾部分
+func saveUnflushed(taskID uint, start int, lines []string) error {
+	texts := make([]string, 0, len(lines))
+	size := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		size += len(lines[i]) + 1
+		if size > summaryBytes {
+			break
+		}
+		texts = append(texts, lines[i])
+	}
+	for i, j := 0, len(texts)-1; i < j; i, j = i+1, j-1 {
+		texts[i], texts[j] = texts[j], texts[i]
+	}
+	header := fmt.Sprintf("... 输出落库失败，以下为第 %d 行起未能保存到任务日志的输出 ...", start+len(lines)-len(texts)+1)
+	result := header + "\n" + strings.Join(texts, "\n")
+	return global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Update("result", result).Error
+}
+
+// snapshot 复制尚未落库的行（包括正在写入的），用于读取时补齐数据库中还没有的部分
+func (o *Output) snapshot() ([]string, int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	lines := make([]string, 0, len(o.flushing)+len(o.pending))
+	lines = append(lines, o.flushing...)
+	lines = append(lines, o.pending...)
+	return lines, o.pendingStart - len(o.flushing)
+}
+
+// flush 把尚未落库的行压缩写入一个分块，force 为 false 时只在满一块时写入。
+// 写库时不持有 mu，追加、读取和订阅不会被数据库阻塞；写库失败时放回这些行，下次继续尝试
+func (o *Output) flush(force bool) error {
+	o.flushMu.Lock()
+	defer o.flushMu.Unlock()
+
+	o.mu.Lock()
+	if len(o.pending) == 0 || (!force && !o.full()) {
+		o.mu.Unlock()
+		return nil
+	}
+	lines, start, index := o.pending, o.pendingStart, o.chunkIndex
+	o.flushing = lines
+	o.pending = nil
+	o.pendingStart += len(lines)
+	o.pendingBytes = 0
+	o.mu.Unlock()
+
+	err := insertChunk(o.TaskID, index, start, lines)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.flushing = nil
+	if err != nil {
+		o.pending = append(lines, o.pending...)
+		o.pendingStart = start
+		o.pendingBytes = 0
+		for _, text := range o.pending {
+			o.pendingBytes += len(text) + 1
+		}
+		return err
+	}
+	o.chunkIndex++
+	return nil
+}
+
+func insertChunk(taskID uint, index, start int, lines []string) error {
+	data, err := compressLines(lines)
+	if err != nil {
+		return err
+	}
+	chunk := models.TaskOutputChunkModel{
+		TaskID:     taskID,
+		ChunkIndex: index,
+		StartLine:  start,
+		LineCount:  len(lines),
+		Data:       data,
+	}
+	return global.DB.Create(&chunk).Error
+}
+
+func compressLines(lines []string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(strings.Join(lines, "\n"))); err != nil {
+		return nil, fmt.Errorf("压缩任务输出失败: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("压缩任务输出失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decompressLines(data []byte, count int) ([]string, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解压任务输出失败: %w", err)
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("解压任务输出失败: %w", err)
+	}
+	lines := strings.Split(string(raw), "\n")
+	if len(lines) != count {
+		return nil, fmt.Errorf("任务输出分块行数不一致: 期望 %d, 实际 %d", count, len(lines))
+	}
+	return lines, nil
+}
@@ -0,0 +1,201 @@
+package task_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"fmt"
+	"strings"
+)
+
+const (
+	// summaryLines 任务结束后写入 result 字段的末尾行数
+	summaryLines = 200
+	// summaryBytes result 字段为 text 类型，摘要需小于 64KB
+	summaryBytes = 60 * 1024
+)
+
+// Total 任务输出的总行数
+func Total(taskID uint) (int, error) {
+	if out := GetOutput(taskID); out != nil {
+		return out.Total(), nil
+	}
+	total, ok, err := storedTotal(taskID)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return total, nil
+	}
+	return len(legacyLines(taskID)), nil
+}
+
+// ReadLines 读取 [offset, offset+limit) 范围内的输出行，返回读取到的行和当前总行数
+func ReadLines(taskID uint, offset, limit int) ([]Line, int, error) {
+	var pending []string
+	pendingStart := -1
+	var total int
+
+	if out := GetOutput(taskID); out != nil {
+		pending, pendingStart = out.snapshot()
+		total = pendingStart + len(pending)
+	} else {
+		stored, ok, err := storedTotal(taskID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			return sliceLines(legacyLines(taskID), offset, limit)
+		}
+		total = stored
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	if offset >= end {
+		return []Line{}, total, nil
+	}
+
+	texts := make([]string, end-offset)
+	filled := make([]bool, end-offset)
+
+	var chunks []models.TaskOutputChunkModel
+	if err := global.DB.Where("task_id = ? AND start_line < ? AND start_line + line_count > ?", taskID, end, offset).
+		Order("start_line").Find(&chunks).Error; err != nil {
+		return nil, 0, fmt.Errorf("读取任务输出失败: %w", err)
+	}
+	for _, chunk := range chunks {
+		lines, err := decompressLines(chunk.Data, chunk.LineCount)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i, text := range lines {
+			seq := chunk.StartLine + i
+			if seq >= offset && seq < end {
+				texts[seq-offset] = text
+				filled[seq-offset] = true
+			}
+		}
+	}
+	// 运行中的任务补上尚未落库的部分
+	for i, text := range pending {
+		seq := pendingStart + i
+		if seq >= offset && seq < end {
+			texts[seq-offset] = text
+			filled[seq-offset] = true
+		}
+	}
+
+	result := make([]Line, 0, len(texts))
+	for i, text := range texts {
+		if !filled[i] {
+			// 分块缺失的行不返回，其余行的 Seq 仍是原来的行号
+			continue
+		}
+		result = append(result, Line{Seq: offset + i, Text: text})
+	}
+	return result, total, nil
+}
+
+// Summary 生成写入 result 字段的输出摘要，只保留末尾部分
+func Summary(taskID uint) string {
+	total, err := Total(taskID)
+	if err != nil {
+		global.Log.Error(err)
+		return ""
+	}
+	offset := total - summaryLines
+	if offset < 0 {
+		offset = 0
+	}
+	lines, _, err := ReadLines(taskID, offset, summaryLines)
+	if err != nil {
+		global.Log.Error(err)
+		return ""
+	}
+
+	texts := make([]string, 0, len(lines))
+	size := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		size += len(lines[i].Text) + 1
+		if size > summaryBytes {
+			offset = lines[i].Seq + 1
+			break
+		}
+		texts = append(texts, lines[i].Text)
+	}
+	for i, j := 0, len(texts)-1; i < j; i, j = i+1, j-1 {
+		texts[i], texts[j] = texts[j], texts[i]
+	}
+
+	summary := strings.Join(texts, "\n")
+	if offset > 0 {
+		summary = fmt.Sprintf("... 省略前 %d 行，完整输出请查看任务日志 ...\n", offset) + summary
+	}
+	return summary
+}
+
+// eachChunk 按行号顺序逐个读取任务的输出分块，fn 返回 false 时停止。
+// 每次只从数据库取一个分块，避免输出很大的任务一次占用过多内存
+func eachChunk(taskID uint, fn func(chunk models.TaskOutputChunkModel) (bool, error)) error {
+	var ids []uint
+	if err := global.DB.Model(&models.TaskOutputChunkModel{}).Where("task_id = ?", taskID).
+		Order("start_line").Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("读取任务输出失败: %w", err)
+	}
+	for _, id := range ids {
+		var chunk models.TaskOutputChunkModel
+		if err := global.DB.Take(&chunk, id).Error; err != nil {
+			return fmt.Errorf("读取任务输出失败: %w", err)
+		}
+		next, err := fn(chunk)
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
+
+// storedTotal 已落库的总行数，没有任何分块时 ok 为 false
+func storedTotal(taskID uint) (total int, ok bool, err error) {
+	var last models.TaskOutputChunkModel
+	result := global.DB.Select("start_line", "line_count").Where("task_id = ?", taskID).
+		Order("start_line DESC").Limit(1).Find(&last)
+	if result.Error != nil {
+		return 0, false, fmt.Errorf("读取任务输出失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return 0, false, nil
+	}
+	return last.StartLine + last.LineCount, true, nil
+}
+
+// legacyLines 分块存储之前的任务，输出完整保存在 result 字段
+func legacyLines(taskID uint) []string {
+	var result string
+	global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Select("result").Scan(&result)
+	if result == "" {
+		return nil
+	}
+	return strings.Split(result, "\n")
+}
+
+func sliceLines(texts []string, offset, limit int) ([]Line, int, error) {
+	total := len(texts)
+	if offset < 0 {
+		offset = 0
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	lines := make([]Line, 0)
+	for seq := offset; seq < end; seq++ {
+		lines = append(lines, Line{Seq: seq, Text: texts[seq]})
+	}
+	return lines, total, nil
+}
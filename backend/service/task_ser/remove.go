@@ -0,0 +1,159 @@
+package task_ser
+
+import (
+	"bufio"
+	"ccops/global"
+	"ccops/models"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// RemoveTask 删除任务及其关联的角色、目标和输出
+func RemoveTask(taskID uint) error {
+	if GetOutput(taskID) != nil {
+		return fmt.Errorf("任务 %d 正在执行", taskID)
+	}
+
+	// 开始事务
+	tx := global.DB.Begin()
+
+	// 删除任务关联记录
+	if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskAssociationModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("删除任务关联的角色失败: %w", err)
+	}
+
+	// 删除任务发版记录
+	if err := tx.Where("task_id = ?", taskID).Delete(&models.TargetAssociationModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("删除任务关联的目标失败: %w", err)
+	}
+
+	// 删除任务输出
+	if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskOutputChunkModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("删除任务输出失败: %w", err)
+	}
+
+	// 删除任务本身
+	if err := tx.Where("id = ?", taskID).Delete(&models.TaskModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("删除任务失败: %w", err)
+	}
+
+	// 提交事务
+	return tx.Commit().Error
+}
+
+// TaskArchive 归档文件内容
+type TaskArchive struct {
+	Task    models.TaskModel                `json:"task"`
+	Roles   []models.TaskAssociationModel   `json:"roles"`
+	Targets []models.TargetAssociationModel `json:"targets"`
+	Output  []string                        `json:"output"`
+}
+
+// ArchiveTask 将任务及完整输出写入 dir/task_<id>.json.gz，返回归档文件路径。
+// 输出按分块逐个解压写入，不一次读入内存
+func ArchiveTask(taskID uint, dir string) (string, error) {
+	var archive TaskArchive
+	if err := global.DB.Take(&archive.Task, taskID).Error; err != nil {
+		return "", fmt.Errorf("获取任务失败: %w", err)
+	}
+	global.DB.Where("task_id = ?", taskID).Find(&archive.Roles)
+	global.DB.Where("task_id = ?", taskID).Find(&archive.Targets)
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("创建归档目录失败: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("task_%d.json.gz", taskID))
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return "", fmt.Errorf("创建归档文件失败: %w", err)
+	}
+	zw := gzip.NewWriter(file)
+	err = writeArchive(zw, taskID, archive)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("写入归档文件失败: %w", err)
+	}
+	// 写完整后再改名，避免中途失败留下不完整的归档
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", fmt.Errorf("写入归档文件失败: %w", err)
+	}
+	return path, nil
+}
+
+// writeArchive 按 TaskArchive 的 JSON 格式写入归档，output 数组逐行写入
+func writeArchive(w io.Writer, taskID uint, archive TaskArchive) error {
+	bw := bufio.NewWriter(w)
+	fields := []struct {
+		name  string
+		value any
+	}{
+		{"task", archive.Task},
+		{"roles", archive.Roles},
+		{"targets", archive.Targets},
+	}
+	bw.WriteString("{")
+	for _, field := range fields {
+		data, err := json.Marshal(field.value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "%q:", field.name)
+		bw.Write(data)
+		bw.WriteString(",")
+	}
+	bw.WriteString(`"output":[`)
+
+	count := 0
+	writeLine := func(text string) error {
+		data, err := json.Marshal(text)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			bw.WriteString(",")
+		}
+		count++
+		_, err = bw.Write(data)
+		return err
+	}
+	err := eachChunk(taskID, func(chunk models.TaskOutputChunkModel) (bool, error) {
+		lines, err := decompressLines(chunk.Data, chunk.LineCount)
+		if err != nil {
+			return false, err
+		}
+		for _, text := range lines {
+			if err := writeLine(text); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		// 分块存储之前的任务，输出在 result 字段
+		for _, text := range legacyLines(taskID) {
+			if err := writeLine(text); err != nil {
+				return err
+			}
+		}
+	}
+	bw.WriteString("]}\n")
+	return bw.Flush()
+}
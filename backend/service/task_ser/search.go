@@ -0,0 +1,121 @@
+package task_ser
+
+import (
+	"bytes"
+	"ccops/global"
+	"ccops/models"
+	"compress/gzip"
+	"io"
+	"strings"
+)
+
+// SearchLimit 输出全文搜索最多扫描的任务数，输出压缩存储无法在数据库中检索，只能逐个解压
+const SearchLimit = 500
+
+// MatchOutput 返回输出中包含 keyword 的任务ID，保持 taskIDs 的顺序
+func MatchOutput(taskIDs []uint, keyword string) []uint {
+	matched := make([]uint, 0)
+	if keyword == "" || len(taskIDs) == 0 {
+		return matched
+	}
+	needle := []byte(keyword)
+
+	// 分块存储之前的任务输出在 result 字段
+	found := make(map[uint]bool)
+	var legacyIDs []uint
+	global.DB.Model(&models.TaskModel{}).Where("id IN ? AND result LIKE ?", taskIDs, "%"+escapeLike(keyword)+"%").Pluck("id", &legacyIDs)
+	for _, taskID := range legacyIDs {
+		found[taskID] = true
+	}
+
+	var chunkIDs []uint
+	global.DB.Model(&models.TaskOutputChunkModel{}).Where("task_id IN ?", taskIDs).Distinct("task_id").Pluck("task_id", &chunkIDs)
+	hasChunks := make(map[uint]bool, len(chunkIDs))
+	for _, taskID := range chunkIDs {
+		hasChunks[taskID] = true
+	}
+
+	for _, taskID := range taskIDs {
+		if found[taskID] {
+			continue
+		}
+		// 运行中的任务还有未落库的行，先取快照再读分块，期间落库的行会重复查找而不会漏掉
+		var pending []string
+		out := GetOutput(taskID)
+		if out != nil {
+			pending, _ = out.snapshot()
+		}
+		if !hasChunks[taskID] && out == nil {
+			continue
+		}
+
+		// 逐个分块读取解压，带上前一分块的末尾，已命中时跳过剩余分块
+		var tail []byte
+		if hasChunks[taskID] {
+			taskID := taskID
+			err := eachChunk(taskID, func(chunk models.TaskOutputChunkModel) (bool, error) {
+				var ok bool
+				ok, tail = chunkContains(chunk.Data, needle, tail)
+				if ok {
+					found[taskID] = true
+				}
+				return !ok, nil
+			})
+			if err != nil {
+				global.Log.Error(err)
+			}
+		}
+		if !found[taskID] && len(pending) > 0 {
+			text := append(tail, strings.Join(pending, "\n")...)
+			found[taskID] = bytes.Contains(text, needle)
+		}
+	}
+
+	for _, taskID := range taskIDs {
+		if found[taskID] {
+			matched = append(matched, taskID)
+		}
+	}
+	return matched
+}
+
+// chunkContains 边解压边查找，只保留与下一段可能拼成关键字的末尾部分。
+// tail 是同一任务前一分块的末尾，返回本分块的末尾（含分隔行的换行符），供下一分块拼接，
+// 关键字跨过分块边界时也能找到
+func chunkContains(data []byte, needle []byte, tail []byte) (bool, []byte) {
+	keep := len(needle) - 1
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return false, nil
+	}
+	defer zr.Close()
+	buf := make([]byte, 0, 32*1024+len(needle))
+	buf = append(buf, tail...)
+	for {
+		n, err := zr.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if bytes.Contains(buf, needle) {
+			return true, nil
+		}
+		if err != nil {
+			if err != io.EOF {
+				return false, nil
+			}
+			break
+		}
+		if len(buf) > keep {
+			buf = append(buf[:0], buf[len(buf)-keep:]...)
+		}
+	}
+	// 分块之间按行分隔，末尾补上换行符
+	buf = append(buf, '\n')
+	if len(buf) > keep {
+		buf = buf[len(buf)-keep:]
+	}
+	return false, append([]byte(nil), buf...)
+}
+
+// escapeLike 转义 LIKE 的通配符，使关键字按字面匹配
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
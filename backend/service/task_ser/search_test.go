@@ -0,0 +1,86 @@
+package task_ser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunkContains(t *testing.T) {
+	// 关键字跨过解压时的读取边界
+	long := strings.Repeat("x", 32*1024-3)
+	tests := []struct {
+		lines   []string
+		keyword string
+		want    bool
+	}{
+		{[]string{"ok: [web1]", "fatal: [web2]"}, "fatal", true},
+		{[]string{"ok: [web1]", "fatal: [web2]"}, "web3", false},
+		{[]string{"a", "b"}, "a\nb", true},
+		{[]string{long + "needle"}, "needle", true},
+		{[]string{long + "needl"}, "needle", false},
+		{[]string{strings.Repeat(long, 4), "end"}, "x\nend", true},
+		{[]string{""}, "a", false},
+	}
+	for _, tt := range tests {
+		data, err := compressLines(tt.lines)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := chunkContains(data, []byte(tt.keyword), nil); got != tt.want {
+			t.Errorf("查找 %q 的结果为 %v，应为 %v", tt.keyword, got, tt.want)
+		}
+	}
+	if got, _ := chunkContains([]byte("not gzip"), []byte("gzip"), nil); got {
+		t.Error("无法解压的分块不应命中")
+	}
+}
+
+func TestChunkContainsAcrossChunks(t *testing.T) {
+	tests := []struct {
+		chunks  [][]string
+		keyword string
+		want    bool
+	}{
+		{[][]string{{"ok: [web1]", "fatal: [we"}, {"b2] unreachable"}}, "fatal: [web2]", false},
+		{[][]string{{"ok: [web1]", "fatal"}, {": [web2]"}}, "fatal\n: [web2]", true},
+		{[][]string{{"PLAY RECAP"}, {"web1 : ok=1"}}, "RECAP\nweb1", true},
+		{[][]string{{"a"}, {""}, {"b"}}, "a\n\nb", true},
+		{[][]string{{"a"}, {"b"}}, "ab", false},
+		{[][]string{{strings.Repeat("x", 40*1024) + "nee"}, {"dle"}}, "nee\ndle", true},
+		{[][]string{{"nee"}, {"x"}, {"dle"}}, "nee\ndle", false},
+	}
+	for _, tt := range tests {
+		var (
+			tail  []byte
+			found bool
+		)
+		for _, lines := range tt.chunks {
+			data, err := compressLines(lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found, tail = chunkContains(data, []byte(tt.keyword), tail); found {
+				break
+			}
+		}
+		if found != tt.want {
+			t.Errorf("在 %q 中查找 %q 的结果为 %v，应为 %v", tt.chunks, tt.keyword, found, tt.want)
+		}
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := map[string]string{
+		"error":     "error",
+		"100%":      `100\%`,
+		"task_name": `task\_name`,
+		`C:\tmp`:    `C:\\tmp`,
+		`50%_\`:     `50\%\_\\`,
+		"中文关键字":     "中文关键字",
+	}
+	for input, want := range tests {
+		if got := escapeLike(input); got != want {
+			t.Errorf("escapeLike(%q) = %q，应为 %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package task_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"context"
+	"fmt"
+)
+
+// eventStream 按顺序编号事件，跳过客户端已收到的部分
+type eventStream struct {
+	next  int
+	after int
+	emit  func(Event) error
+}
+
+func (s *eventStream) send(events ...Event) error {
+	for _, event := range events {
+		event.ID = s.next
+		s.next++
+		if event.ID <= s.after {
+			continue
+		}
+		if err := s.emit(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamEvents 推送任务事件：先从头解析已有输出重放，再跟随运行中的任务，任务结束后以 task_finished 收尾。
+// after 为客户端最后收到的事件 ID，-1 表示从头开始；withOutput 为 true 时同时推送原始输出行，
+// 续传时需保持与首次请求一致
+func StreamEvents(ctx context.Context, taskID uint, after int, withOutput bool, emit func(Event) error) error {
+	var task models.TaskModel
+	if err := global.DB.Select("id", "task_name", "type").Take(&task, taskID).Error; err != nil {
+		return fmt.Errorf("任务不存在: %w", err)
+	}
+
+	stream := &eventStream{after: after, emit: emit}
+	parser := NewEventParser(taskID)
+	handle := func(line Line) error {
+		if withOutput {
+			if err := stream.send(Event{Type: EventOutput, TaskID: taskID, Seq: line.Seq, Message: line.Text}); err != nil {
+				return err
+			}
+		}
+		return stream.send(parser.Parse(line)...)
+	}
+
+	if err := stream.send(Event{Type: EventTaskStarted, TaskID: taskID, Step: task.Type, Message: task.TaskName}); err != nil {
+		return err
+	}
+
+	offset := 0
+	for {
+		// 先订阅再补历史，保证两段输出之间不丢行
+		var live <-chan Line
+		var total int
+		var err error
+		out := GetOutput(taskID)
+		if out != nil {
+			live, total = out.Subscribe()
+		} else if total, err = Total(taskID); err != nil {
+			return err
+		}
+
+		if err := replay(taskID, &offset, total, handle); err != nil {
+			if out != nil {
+				out.Unsubscribe(live)
+			}
+			return err
+		}
+		if live == nil {
+			break
+		}
+
+		// channel 关闭说明任务结束或推送过慢被断开，两种情况都回到循环开头补齐剩余输出
+	follow:
+		for {
+			select {
+			case line, ok := <-live:
+				if !ok {
+					break follow
+				}
+				if line.Seq < offset {
+					continue
+				}
+				if err := handle(line); err != nil {
+					out.Unsubscribe(live)
+					return err
+				}
+				offset = line.Seq + 1
+			case <-ctx.Done():
+				out.Unsubscribe(live)
+				return ctx.Err()
+			}
+		}
+	}
+
+	var status string
+	global.DB.Model(&models.TaskModel{}).Where("id = ?", taskID).Select("status").Scan(&status)
+	if err := stream.send(parser.Finish(offset)...); err != nil {
+		return err
+	}
+	return stream.send(Event{Type: EventTaskFinished, TaskID: taskID, Seq: offset, Status: status})
+}
+
+// replay 按批读取 [offset, total) 的输出交给 handle 处理
+func replay(taskID uint, offset *int, total int, handle func(Line) error) error {
+	const batch = 1000
+	for *offset < total {
+		limit := total - *offset
+		if limit > batch {
+			limit = batch
+		}
+		lines, _, err := ReadLines(taskID, *offset, limit)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return nil
+		}
+		for _, line := range lines {
+			if err := handle(line); err != nil {
+				return err
+			}
+		}
+		*offset = lines[len(lines)-1].Seq + 1
+	}
+	return nil
+}
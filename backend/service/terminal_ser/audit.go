@@ -0,0 +1,75 @@
+package terminal_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"time"
+	"unicode/utf8"
+)
+
+// 结束原因最大长度，与 TerminalSessionModel.ExitReason 的列长度一致
+const maxExitReasonLength = 256
+
+// 终端会话结束原因
+const (
+	ExitClientClosed    = "client_closed"    // 浏览器关闭或断开
+	ExitShellExited     = "shell_exited"     // 远端 shell 退出
+	ExitConnectFailed   = "connect_failed"   // 连接主机失败
+	ExitError           = "error"            // 读写出错
+	ExitIdleTimeout     = "idle_timeout"     // 长时间无操作
+	ExitKeepaliveFailed = "keepalive_failed" // SSH 保活失败
+)
+
+// StartAudit 记录终端会话开始
+func StartAudit(userID uint, username string, host models.HostModel, clientIP string) *models.TerminalSessionModel {
+	record := &models.TerminalSessionModel{
+		UserID:    userID,
+		Username:  username,
+		HostID:    host.ID,
+		HostName:  host.Name,
+		HostIP:    host.HostServerUrl,
+		ClientIP:  clientIP,
+		StartTime: time.Now(),
+	}
+	if err := global.DB.Create(record).Error; err != nil {
+		global.Log.Errorf("记录终端会话失败: %v", err)
+	}
+	return record
+}
+
+// EndAudit 记录终端会话结束时间和原因，同时保存录像信息
+func EndAudit(record *models.TerminalSessionModel, reason string, recorder *Recorder) {
+	if record == nil || record.ID == 0 {
+		return
+	}
+	now := time.Now()
+	size, truncated := recorder.Close()
+	reason = truncateReason(reason)
+	record.EndTime = &now
+	record.ExitReason = reason
+	if err := global.DB.Model(record).Updates(map[string]any{
+		"end_time":            now,
+		"exit_reason":         reason,
+		"recording_path":      recorder.Path(),
+		"recording_size":      size,
+		"recording_truncated": truncated,
+	}).Error; err != nil {
+		global.Log.Errorf("更新终端会话失败: %v", err)
+	}
+}
+
+// truncateReason 截断过长的结束原因，连接失败时会带上完整的 SSH 错误信息。
+// 列长度按字符计算，截断时不拆开 UTF-8 字符
+func truncateReason(reason string) string {
+	if utf8.RuneCountInString(reason) <= maxExitReasonLength {
+		return reason
+	}
+	n := 0
+	for i := range reason {
+		if n == maxExitReasonLength {
+			return reason[:i]
+		}
+		n++
+	}
+	return reason
+}
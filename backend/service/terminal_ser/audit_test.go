@@ -0,0 +1,24 @@
+package terminal_ser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateReason(t *testing.T) {
+	long := "connect_failed: " + strings.Repeat("x", 300)
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{ExitClientClosed, ExitClientClosed},
+		{strings.Repeat("a", 256), strings.Repeat("a", 256)},
+		{long, long[:256]},
+		{strings.Repeat("错", 300), strings.Repeat("错", 256)},
+	}
+	for _, tt := range tests {
+		if got := truncateReason(tt.input); got != tt.want {
+			t.Errorf("truncateReason(%d 字符) 的结果为 %d 字符，应为 %d 字符", len([]rune(tt.input)), len([]rune(got)), len([]rune(tt.want)))
+		}
+	}
+}
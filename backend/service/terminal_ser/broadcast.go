@@ -0,0 +1,179 @@
+package terminal_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 同时建立连接的主机数
+const broadcastDialConcurrency = 10
+
+// BroadcastOptions 广播终端的参数
+type BroadcastOptions struct {
+	UserID   uint
+	Username string
+	Role     string
+	ClientIP string
+	Cols     int
+	Rows     int
+	Hosts    []models.HostModel // 有终端权限的主机
+	Denied   []models.HostModel // 没有终端权限的主机，只回复错误
+}
+
+type broadcastTarget struct {
+	session *Session
+	client  *Client
+	muted   bool
+}
+
+// ServeBroadcast 多主机广播终端，一个 WebSocket 驱动多台主机的会话，只支持 JSON 协议
+//
+//	客户端 -> 服务端: {"type":"input","data":"..."} 发送给所有未静音的主机，带 hostId 时只发给该主机
+//	                  {"type":"resize","cols":120,"rows":40} {"type":"mute","hostId":1} {"type":"unmute","hostId":1} {"type":"ping"}
+//	服务端 -> 客户端: 单主机终端的消息都带上 hostId，另有 {"type":"error","hostId":1,"reason":"..."} {"type":"mute"/"unmute","hostId":1}
+//
+// 每台主机都是独立的会话，各自记录审计、录像并执行命令规则
+func ServeBroadcast(conn *websocket.Conn, opts BroadcastOptions) {
+	w := newConnWriter(conn)
+	control := func(msg ControlMessage) {
+		data, _ := json.Marshal(msg)
+		w.enqueue(wsMessage{typ: websocket.TextMessage, data: data})
+	}
+	for _, host := range opts.Denied {
+		control(ControlMessage{Type: "error", HostID: host.ID, Reason: "权限错误"})
+	}
+
+	broadcastID := newSessionID()
+	clientOpts := ClientOptions{
+		UserID:   opts.UserID,
+		Username: opts.Username,
+		ClientIP: opts.ClientIP,
+		JSON:     true,
+		Mode:     models.TerminalModeOwner,
+	}
+
+	var mu sync.Mutex
+	targets := map[uint]*broadcastTarget{}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, broadcastDialConcurrency)
+	for _, host := range opts.Hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host models.HostModel) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			session, err := NewSession(SessionOptions{
+				UserID:      opts.UserID,
+				Username:    opts.Username,
+				Role:        opts.Role,
+				Host:        host,
+				ClientIP:    opts.ClientIP,
+				Cols:        opts.Cols,
+				Rows:        opts.Rows,
+				BroadcastID: broadcastID,
+			})
+			if err != nil {
+				control(ControlMessage{Type: "error", HostID: host.ID, Reason: err.Error()})
+				return
+			}
+			c := &Client{w: w, jsonMode: true, hostID: host.ID, shared: true, opts: clientOpts}
+			if !session.attach(c) {
+				return
+			}
+			mu.Lock()
+			targets[host.ID] = &broadcastTarget{session: session, client: c}
+			mu.Unlock()
+		}(host)
+	}
+	wg.Wait()
+
+	if len(targets) == 0 {
+		w.close("no session")
+		return
+	}
+
+	// 所有主机的会话都结束后关闭连接
+	go func() {
+		for _, t := range targets {
+			<-t.session.done
+		}
+		w.close("all sessions ended")
+	}()
+
+	defer func() {
+		for _, t := range targets {
+			t.session.detach(t.client)
+		}
+		w.close(ExitClientClosed)
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(readWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readWait))
+	})
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if !IsNormalClose(err) {
+				global.Log.Debugf("读取 WebSocket 消息失败: %v", err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(readWait))
+
+		var msg ControlMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			continue
+		}
+		switch msg.Type {
+		case "ping":
+			control(ControlMessage{Type: "pong"})
+		case "mute", "unmute":
+			if t, ok := targets[msg.HostID]; ok {
+				mu.Lock()
+				t.muted = msg.Type == "mute"
+				mu.Unlock()
+				control(ControlMessage{Type: msg.Type, HostID: msg.HostID})
+			}
+		case "resize":
+			for _, t := range targets {
+				t.session.Resize(msg.Cols, msg.Rows)
+			}
+		case "input":
+			for _, id := range sortedTargetIDs(targets) {
+				t := targets[id]
+				mu.Lock()
+				muted := t.muted
+				mu.Unlock()
+				if msg.HostID != 0 && msg.HostID != id {
+					continue
+				}
+				if msg.HostID == 0 && muted {
+					continue
+				}
+				if err := t.session.Input(t.client, []byte(msg.Data)); err != nil {
+					t.session.Close(fmt.Sprintf("%s: %v", ExitError, err))
+				}
+			}
+		}
+	}
+}
+
+// sortedTargetIDs 按主机ID顺序写入，保证每次广播的顺序一致
+func sortedTargetIDs(targets map[uint]*broadcastTarget) []uint {
+	ids := make([]uint, 0, len(targets))
+	for id := range targets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,233 @@
+package terminal_ser
+
+import (
+	"ccops/models"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second // 单次写入超时
+	pingPeriod     = 30 * time.Second // WebSocket ping 间隔
+	readWait       = 90 * time.Second // 超过该时间没有收到任何消息视为断线
+	clientSendSize = 256              // 发送队列长度，写满说明客户端过慢
+)
+
+// ControlMessage JSON 控制协议消息，?protocol=json 时启用
+//
+//	客户端 -> 服务端: {"type":"input","data":"ls\r"} {"type":"resize","cols":120,"rows":40} {"type":"ping"}
+//	服务端 -> 客户端: {"type":"output","data":"..."} {"type":"session","sessionId":"...","mode":"..."} {"type":"pong"} {"type":"exit","reason":"..."}
+//	                  {"type":"join","username":"...","mode":"..."} {"type":"leave","username":"..."}
+type ControlMessage struct {
+	Type      string `json:"type"`
+	Data      string `json:"data,omitempty"`
+	Cols      int    `json:"cols,omitempty"`
+	Rows      int    `json:"rows,omitempty"`
+	SessionID string `json:"sessionId,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Mode      string `json:"mode,omitempty"`
+	HostID    uint   `json:"hostId,omitempty"` // 广播终端中区分主机
+}
+
+type wsMessage struct {
+	typ  int
+	data []byte
+}
+
+// ClientOptions 挂载会话的浏览器连接信息
+type ClientOptions struct {
+	UserID   uint
+	Username string
+	ClientIP string
+	JSON     bool   // 使用 JSON 控制协议
+	Mode     string // models.TerminalModeXxx
+}
+
+// connWriter 一个 WebSocket 连接的发送队列，所有写入由 writeLoop 串行完成
+type connWriter struct {
+	conn *websocket.Conn
+
+	mu        sync.Mutex
+	send      chan wsMessage
+	closed    bool
+	closeText string
+}
+
+func newConnWriter(conn *websocket.Conn) *connWriter {
+	w := &connWriter{
+		conn: conn,
+		send: make(chan wsMessage, clientSendSize),
+	}
+	go w.writeLoop()
+	return w
+}
+
+// enqueue 非阻塞写入发送队列，队列已满时断开该连接，避免拖慢整个会话
+func (w *connWriter) enqueue(msg wsMessage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.send <- msg:
+	default:
+		w.closeLocked("client too slow")
+	}
+}
+
+func (w *connWriter) close(reason string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closeLocked(reason)
+}
+
+func (w *connWriter) closeLocked(reason string) {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	// 关闭帧的原因最长 123 字节
+	if len(reason) > 120 {
+		reason = reason[:120]
+	}
+	w.closeText = reason
+	close(w.send)
+}
+
+func (w *connWriter) writeLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		w.conn.Close()
+	}()
+	for {
+		select {
+		case msg, ok := <-w.send:
+			w.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				w.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, w.closeText))
+				return
+			}
+			if err := w.conn.WriteMessage(msg.typ, msg.data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := w.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Client 挂载在会话上的一个浏览器连接
+type Client struct {
+	w           *connWriter
+	jsonMode    bool
+	hostID      uint // 广播终端中标记消息来自哪台主机
+	shared      bool // 与其他会话共用连接，会话结束时不关闭连接
+	opts        ClientOptions
+	participant *models.TerminalParticipantModel
+}
+
+func newClient(conn *websocket.Conn, opts ClientOptions) *Client {
+	return &Client{
+		w:        newConnWriter(conn),
+		jsonMode: opts.JSON,
+		opts:     opts,
+	}
+}
+
+// Output 发送终端输出
+func (c *Client) Output(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	if c.jsonMode {
+		c.Control(ControlMessage{Type: "output", Data: string(data)})
+		return
+	}
+	c.w.enqueue(wsMessage{typ: websocket.TextMessage, data: append([]byte(nil), data...)})
+}
+
+// Control 发送控制消息，旧协议下忽略
+func (c *Client) Control(msg ControlMessage) {
+	if !c.jsonMode {
+		return
+	}
+	msg.HostID = c.hostID
+	data, _ := json.Marshal(msg)
+	c.w.enqueue(wsMessage{typ: websocket.TextMessage, data: data})
+}
+
+// Close 通知客户端会话结束并断开连接
+func (c *Client) Close(reason string) {
+	c.Control(ControlMessage{Type: "exit", Reason: reason})
+	if !c.shared {
+		c.w.close(reason)
+	}
+}
+
+// clientRequest 解析后的客户端消息
+type clientRequest struct {
+	kind       string // input / resize / ping
+	data       []byte
+	cols, rows int
+}
+
+// parseMessage 解析客户端消息，兼容旧协议：单字节 0 为心跳，ESC[8;rows,cols 为调整大小，其余为输入
+func (c *Client) parseMessage(message []byte) (clientRequest, bool) {
+	if c.jsonMode {
+		var msg ControlMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			return clientRequest{}, false
+		}
+		switch msg.Type {
+		case "input":
+			return clientRequest{kind: "input", data: []byte(msg.Data)}, true
+		case "resize":
+			return clientRequest{kind: "resize", cols: msg.Cols, rows: msg.Rows}, true
+		case "ping":
+			return clientRequest{kind: "ping"}, true
+		}
+		return clientRequest{}, false
+	}
+
+	if len(message) == 1 && message[0] == 0 {
+		return clientRequest{kind: "ping"}, true
+	}
+	if len(message) > 4 && message[0] == 0x1b && message[1] == '[' && message[2] == '8' && message[3] == ';' {
+		dims := strings.Split(string(message[4:]), ",")
+		if len(dims) == 2 {
+			rows, _ := strconv.Atoi(strings.TrimSpace(dims[0]))
+			cols, _ := strconv.Atoi(strings.TrimSpace(dims[1]))
+			return clientRequest{kind: "resize", cols: cols, rows: rows}, true
+		}
+	}
+	return clientRequest{kind: "input", data: message}, true
+}
+
+// ReadOnly 只读客户端的输入和调整大小会被忽略
+func (c *Client) ReadOnly() bool {
+	return c.opts.Mode == models.TerminalModeReadOnly
+}
+
+// pong 回复心跳
+func (c *Client) pong() {
+	if c.jsonMode {
+		c.Control(ControlMessage{Type: "pong"})
+		return
+	}
+	c.w.enqueue(wsMessage{typ: websocket.TextMessage, data: []byte{0}})
+}
+
+// IsNormalClose 判断是否为浏览器正常关闭连接
+func IsNormalClose(err error) bool {
+	return websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived)
+}
@@ -0,0 +1,260 @@
+package terminal_ser
+
+import (
+	"bytes"
+	"ccops/global"
+	"ccops/models"
+	"ccops/service/alert"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type commandRule struct {
+	models.CommandRuleModel
+	re *regexp.Regexp
+}
+
+// loadCommandRules 加载对指定用户角色和主机生效的命令规则
+func loadCommandRules(role string, hostID uint) []commandRule {
+	var rules []models.CommandRuleModel
+	global.DB.Where("enabled = ?", true).Order("id").Find(&rules)
+
+	var hostLabelIds []uint
+	global.DB.Model(&models.HostLabels{}).Where("host_model_id = ?", hostID).Pluck("label_model_id", &hostLabelIds)
+	hostLabels := make(map[uint]bool)
+	for _, id := range hostLabelIds {
+		hostLabels[id] = true
+	}
+
+	var result []commandRule
+	for _, rule := range rules {
+		if len(rule.Roles) > 0 && !containsString(rule.Roles, role) {
+			continue
+		}
+		if len(rule.LabelIds) > 0 {
+			matched := false
+			for _, id := range rule.LabelIds {
+				if hostLabels[id] {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			global.Log.Warnf("命令规则 %d 正则表达式无效: %v", rule.ID, err)
+			continue
+		}
+		result = append(result, commandRule{CommandRuleModel: rule, re: re})
+	}
+	return result
+}
+
+// CommandFilter 从终端输入中还原命令行并按规则拦截。
+// 只能还原直接键入的内容，Tab 补全、方向键编辑和历史命令无法感知；
+// 全屏程序（vim、top 等切换到备用屏幕时）中的输入不是命令，只检查拦截规则，不做确认和告警。
+// 备用屏幕由输出判断，用户可以自己输出切换序列，所以不能因此停止拦截
+type CommandFilter struct {
+	mu        sync.Mutex
+	rules     []commandRule
+	user      string
+	host      models.HostModel
+	line      []rune
+	inEscape  bool
+	altScreen bool
+	pending   string // 等待二次确认的命令
+}
+
+// FilterResult 输入经过过滤后的处理结果
+type FilterResult struct {
+	Forward []byte // 发送给远端 shell 的内容
+	Notice  string // 直接显示给用户的提示
+	Marker  string // 写入录像的标记
+}
+
+// 确认时在远端取消当前行使用的 Ctrl-C
+const ctrlC = 0x03
+
+var (
+	altScreenOn  = []byte("\x1b[?1049h")
+	altScreenOff = []byte("\x1b[?1049l")
+)
+
+// NewCommandFilter 为终端会话创建命令过滤器，规则在会话开始时加载
+func NewCommandFilter(role string, user string, host models.HostModel) *CommandFilter {
+	return &CommandFilter{rules: loadCommandRules(role, host.ID), user: user, host: host}
+}
+
+// Output 观察终端输出，识别是否进入全屏程序
+func (f *CommandFilter) Output(data []byte) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	on := bytes.LastIndex(data, altScreenOn)
+	off := bytes.LastIndex(data, altScreenOff)
+	if on > off {
+		f.altScreen = true
+	} else if off > on {
+		f.altScreen = false
+		f.line = f.line[:0]
+	}
+}
+
+// Input 处理一次用户输入
+func (f *CommandFilter) Input(data []byte) FilterResult {
+	if f == nil || len(f.rules) == 0 {
+		return FilterResult{Forward: data}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// 等待确认时只看第一个字符
+	if f.pending != "" {
+		command := f.pending
+		f.pending = ""
+		if len(data) > 0 && (data[0] == 'y' || data[0] == 'Y') {
+			return FilterResult{Forward: []byte{'\r'}, Notice: "y\r\n", Marker: "confirmed: " + command}
+		}
+		return FilterResult{Forward: []byte{ctrlC}, Notice: "n\r\n\x1b[33m已取消执行\x1b[0m\r\n", Marker: "cancelled: " + command}
+	}
+
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		switch {
+		case f.inEscape:
+			// CSI/SS3 序列以 0x40-0x7E 之间的字符结束，ESC [ 和 ESC O 本身不算结束
+			if r >= 0x40 && r <= 0x7e && !(r == '[' || r == 'O') {
+				f.inEscape = false
+			}
+		case r == 0x1b:
+			f.inEscape = true
+		case r == 0x7f || r == 0x08:
+			if len(f.line) > 0 {
+				f.line = f.line[:len(f.line)-1]
+			}
+		case r == 0x15 || r == ctrlC:
+			f.line = f.line[:0]
+		case r == 0x17:
+			f.deleteWord()
+		case r == '\r' || r == '\n':
+			command := strings.TrimSpace(string(f.line))
+			f.line = f.line[:0]
+			if result, blocked := f.check(command, data[:i]); blocked {
+				return result
+			}
+		case r >= 0x20:
+			f.line = append(f.line, r)
+		}
+		i += size
+	}
+	return FilterResult{Forward: data}
+}
+
+// check 检查一条完整命令，被拦截或需要确认时返回处理结果，prefix 为回车之前需要照常发送的输入
+func (f *CommandFilter) check(command string, prefix []byte) (FilterResult, bool) {
+	if command == "" {
+		return FilterResult{}, false
+	}
+
+	var matched []commandRule
+	action := ""
+	for _, rule := range f.rules {
+		if f.altScreen && rule.Action != models.CommandActionDeny {
+			continue
+		}
+		if !rule.re.MatchString(command) {
+			continue
+		}
+		matched = append(matched, rule)
+		switch rule.Action {
+		case models.CommandActionDeny:
+			action = models.CommandActionDeny
+		case models.CommandActionConfirm:
+			if action != models.CommandActionDeny {
+				action = models.CommandActionConfirm
+			}
+		default:
+			if action == "" {
+				action = models.CommandActionAlert
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return FilterResult{}, false
+	}
+
+	for _, rule := range matched {
+		global.Log.Warnf("终端命令命中规则: user=%s host=%s command=%q rule=%s action=%s", f.user, f.host.Name, command, rule.Name, rule.Action)
+		if rule.NotificationId > 0 {
+			go f.notify(rule, command, action)
+		}
+	}
+
+	forward := append([]byte{}, prefix...)
+	switch action {
+	case models.CommandActionDeny:
+		// 远端已经收到键入的内容，用 Ctrl-C 丢弃当前行
+		return FilterResult{
+			Forward: append(forward, ctrlC),
+			Notice:  fmt.Sprintf("\r\n\x1b[31m命令已被拦截（规则：%s）\x1b[0m\r\n", matched[0].Name),
+			Marker:  "denied: " + command,
+		}, true
+	case models.CommandActionConfirm:
+		f.pending = command
+		return FilterResult{
+			Forward: forward,
+			Notice:  fmt.Sprintf("\r\n\x1b[33m高危命令（规则：%s），确认执行请输入 y，其他任意键取消：\x1b[0m", matched[0].Name),
+			Marker:  "confirm: " + command,
+		}, true
+	}
+	return FilterResult{}, false
+}
+
+func (f *CommandFilter) notify(rule commandRule, command string, action string) {
+	content := fmt.Sprintf("━━━━━━━━━━ CCOPS终端命令告警 ━━━━━━━━━━\n"+
+		"📅 触发时间：%s\n"+
+		"👤 操作用户：%s\n"+
+		"🖥 目标主机：%s (%s)\n"+
+		"⌨ 执行命令：%s\n"+
+		"📌 命中规则：%s\n"+
+		"🛡 处理方式：%s\n"+
+		"━━━━━━━━━━━━━━━━━━━━━━━━━━━━",
+		time.Now().Format("2006-01-02 15:04:05"),
+		f.user,
+		f.host.Name, f.host.HostServerUrl,
+		command,
+		rule.Name,
+		action)
+	if err := alert.SendNotification(rule.NotificationId, content); err != nil {
+		global.Log.Errorf("发送终端命令告警失败: %v", err)
+	}
+}
+
+func (f *CommandFilter) deleteWord() {
+	i := len(f.line)
+	for i > 0 && f.line[i-1] == ' ' {
+		i--
+	}
+	for i > 0 && f.line[i-1] != ' ' {
+		i--
+	}
+	f.line = f.line[:i]
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package terminal_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"io"
+	"regexp"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestFilter(t *testing.T) *CommandFilter {
+	if global.Log == nil {
+		global.Log = logrus.New()
+		global.Log.SetOutput(io.Discard)
+		t.Cleanup(func() { global.Log = nil })
+	}
+	rule := func(name, pattern, action string) commandRule {
+		return commandRule{
+			CommandRuleModel: models.CommandRuleModel{Name: name, Pattern: pattern, Action: action},
+			re:               regexp.MustCompile(pattern),
+		}
+	}
+	return &CommandFilter{
+		rules: []commandRule{
+			rule("删除根目录", `rm\s+-rf\s+/`, models.CommandActionDeny),
+			rule("重启", `^reboot`, models.CommandActionConfirm),
+		},
+		user: "alice",
+		host: models.HostModel{Name: "web1"},
+	}
+}
+
+func TestCommandFilterInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		inputs  []string
+		forward string // 最后一次输入发送给远端的内容
+		marker  string
+	}{
+		{"普通命令", []string{"ls -l\r"}, "ls -l\r", ""},
+		{"拦截", []string{"rm -rf /\r"}, "rm -rf /\x03", "denied: rm -rf /"},
+		{"逐字输入", []string{"r", "m", " -rf /", "\r"}, "\x03", "denied: rm -rf /"},
+		{"退格后拼出命令", []string{"rm -rf /tmpx\x7f\x7f\x7f\x7f\r"}, "rm -rf /tmpx\x7f\x7f\x7f\x7f\x03", "denied: rm -rf /"},
+		{"Ctrl-U 清空后", []string{"rm -rf /\x15ls\r"}, "rm -rf /\x15ls\r", ""},
+		{"方向键不计入命令", []string{"rm -rf \x1b[D/\r"}, "rm -rf \x1b[D/\x03", "denied: rm -rf /"},
+		{"需要确认", []string{"reboot\r"}, "reboot", "confirm: reboot"},
+		{"确认执行", []string{"reboot\r", "y"}, "\r", "confirmed: reboot"},
+		{"取消执行", []string{"reboot\r", "n"}, "\x03", "cancelled: reboot"},
+	}
+	for _, tt := range tests {
+		f := newTestFilter(t)
+		var result FilterResult
+		for _, input := range tt.inputs {
+			result = f.Input([]byte(input))
+		}
+		if string(result.Forward) != tt.forward || result.Marker != tt.marker {
+			t.Errorf("%s: 发送 %q、标记 %q，应为 %q、%q", tt.name, result.Forward, result.Marker, tt.forward, tt.marker)
+		}
+	}
+}
+
+func TestCommandFilterAltScreen(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string // 输入之前的终端输出
+		input  string
+		marker string
+	}{
+		// 用户自己输出切换备用屏幕的序列（printf '\e[?1049h'、tput smcup），拦截规则仍然生效
+		{"输出切换序列后仍拦截", "\x1b[?1049h", "rm -rf /\r", "denied: rm -rf /"},
+		{"全屏程序中不需要确认", "\x1b[?1049h", "reboot\r", ""},
+		{"退出全屏程序后恢复确认", "\x1b[?1049h~\x1b[?1049l$ ", "reboot\r", "confirm: reboot"},
+	}
+	for _, tt := range tests {
+		f := newTestFilter(t)
+		f.Output([]byte(tt.output))
+		result := f.Input([]byte(tt.input))
+		if result.Marker != tt.marker {
+			t.Errorf("%s: 标记 %q，应为 %q", tt.name, result.Marker, tt.marker)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package terminal_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"sort"
+	"time"
+)
+
+// ParticipantInfo 当前挂载在会话上的用户
+type ParticipantInfo struct {
+	UserID   uint      `json:"userId"`
+	Username string    `json:"username"`
+	ClientIP string    `json:"clientIp"`
+	Mode     string    `json:"mode"`
+	JoinTime time.Time `json:"joinTime"`
+}
+
+// SessionInfo 进行中的终端会话
+type SessionInfo struct {
+	ID           string            `json:"id"`        // 挂载会话使用的 sessionId
+	AuditID      uint              `json:"auditId"`   // 对应的审计记录
+	UserID       uint              `json:"userId"`    // 创建者
+	Username     string            `json:"username"`  // 创建者用户名
+	HostID       uint              `json:"hostId"`    // 主机
+	HostName     string            `json:"hostName"`  // 主机名称
+	StartTime    time.Time         `json:"startTime"` // 开始时间
+	Cols         int               `json:"cols"`
+	Rows         int               `json:"rows"`
+	Participants []ParticipantInfo `json:"participants"` // 当前挂载的用户
+}
+
+// Info 返回会话当前状态
+func (s *Session) Info() SessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := SessionInfo{
+		ID:           s.ID,
+		AuditID:      s.audit.ID,
+		UserID:       s.UserID,
+		Username:     s.Username,
+		HostID:       s.Host.ID,
+		HostName:     s.Host.Name,
+		StartTime:    s.audit.StartTime,
+		Cols:         s.cols,
+		Rows:         s.rows,
+		Participants: []ParticipantInfo{},
+	}
+	for c := range s.clients {
+		p := ParticipantInfo{
+			UserID:   c.opts.UserID,
+			Username: c.opts.Username,
+			ClientIP: c.opts.ClientIP,
+			Mode:     c.opts.Mode,
+		}
+		if c.participant != nil {
+			p.JoinTime = c.participant.JoinTime
+		}
+		info.Participants = append(info.Participants, p)
+	}
+	sort.Slice(info.Participants, func(i, j int) bool {
+		return info.Participants[i].JoinTime.Before(info.Participants[j].JoinTime)
+	})
+	return info
+}
+
+// ListSessions 列出进行中的会话，按开始时间倒序
+func ListSessions(filter func(s *Session) bool) []SessionInfo {
+	sessionsMu.Lock()
+	list := make([]*Session, 0, len(sessions))
+	for _, s := range sessions {
+		if filter == nil || filter(s) {
+			list = append(list, s)
+		}
+	}
+	sessionsMu.Unlock()
+
+	infos := make([]SessionInfo, 0, len(list))
+	for _, s := range list {
+		infos = append(infos, s.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].StartTime.After(infos[j].StartTime)
+	})
+	return infos
+}
+
+// joinParticipant 记录用户加入会话
+func joinParticipant(sessionID uint, opts ClientOptions) *models.TerminalParticipantModel {
+	record := &models.TerminalParticipantModel{
+		SessionID: sessionID,
+		UserID:    opts.UserID,
+		Username:  opts.Username,
+		ClientIP:  opts.ClientIP,
+		Mode:      opts.Mode,
+		JoinTime:  time.Now(),
+	}
+	if err := global.DB.Create(record).Error; err != nil {
+		global.Log.Errorf("记录终端会话参与者失败: %v", err)
+	}
+	return record
+}
+
+// leaveParticipant 记录用户离开会话
+func leaveParticipant(record *models.TerminalParticipantModel) {
+	if record == nil || record.ID == 0 || record.LeaveTime != nil {
+		return
+	}
+	now := time.Now()
+	record.LeaveTime = &now
+	if err := global.DB.Model(record).Update("leave_time", now).Error; err != nil {
+		global.Log.Errorf("更新终端会话参与者失败: %v", err)
+	}
+}
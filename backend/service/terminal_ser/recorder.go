@@ -0,0 +1,140 @@
+package terminal_ser
+
+import (
+	"bufio"
+	"ccops/global"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Recorder 以 asciicast v2 格式录制终端会话，记录输出、输入和窗口大小变化
+type Recorder struct {
+	mu        sync.Mutex
+	file      *os.File
+	w         *bufio.Writer
+	path      string
+	start     time.Time
+	size      int64
+	limit     int64
+	truncated bool
+}
+
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// NewRecorder 为终端会话创建录像文件，未配置录像目录时返回 nil，Recorder 的方法均可在 nil 上调用
+func NewRecorder(sessionID uint, width, height int, title string) (*Recorder, error) {
+	conf := global.Config.Terminal
+	if conf.RecordingDir == "" {
+		return nil, nil
+	}
+
+	now := time.Now()
+	dir := filepath.Join(conf.RecordingDir, now.Format("20060102"))
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("创建录像目录失败: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("session_%d.cast", sessionID))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("创建录像文件失败: %w", err)
+	}
+
+	r := &Recorder{
+		file:  file,
+		w:     bufio.NewWriter(file),
+		path:  path,
+		start: now,
+		limit: conf.RecordingMaxSize * 1024 * 1024,
+	}
+	header, _ := json.Marshal(castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: now.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": "xterm"},
+	})
+	r.writeLine(header)
+	return r, nil
+}
+
+// Path 录像文件路径
+func (r *Recorder) Path() string {
+	if r == nil {
+		return ""
+	}
+	return r.path
+}
+
+// Output 记录终端输出
+func (r *Recorder) Output(data []byte) {
+	r.event("o", string(data))
+}
+
+// Input 记录用户输入
+func (r *Recorder) Input(data []byte) {
+	r.event("i", string(data))
+}
+
+// Resize 记录窗口大小变化
+func (r *Recorder) Resize(cols, rows int) {
+	r.event("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Marker 写入标记，回放时可直接跳转，用于标出被拦截或确认的命令
+func (r *Recorder) Marker(label string) {
+	r.event("m", label)
+}
+
+// Close 结束录制，返回录像大小和是否被截断
+func (r *Recorder) Close() (int64, bool) {
+	if r == nil {
+		return 0, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file != nil {
+		r.w.Flush()
+		r.file.Close()
+		r.file = nil
+	}
+	return r.size, r.truncated
+}
+
+func (r *Recorder) event(code string, data string) {
+	if r == nil {
+		return
+	}
+	line, _ := json.Marshal([]any{time.Since(r.start).Seconds(), code, data})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writeLine(line)
+}
+
+// writeLine 写入一行，调用方需持有锁。超出大小限制后不再写入
+func (r *Recorder) writeLine(line []byte) {
+	if r.file == nil || r.truncated {
+		return
+	}
+	if r.limit > 0 && r.size+int64(len(line))+1 > r.limit {
+		r.truncated = true
+		return
+	}
+	n, err := r.w.Write(append(line, '\n'))
+	r.size += int64(n)
+	if err != nil {
+		global.Log.Errorf("写入终端录像失败: %v", err)
+		r.truncated = true
+	}
+}
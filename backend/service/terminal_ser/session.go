@@ -0,0 +1,504 @@
+package terminal_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/service/ssh_ser"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultCols    = 120
+	defaultRows    = 40
+	maxTermSize    = 1000
+	scrollbackSize = 64 * 1024 // 重连时回放的输出长度
+)
+
+// 会话注册表，浏览器断开后在宽限期内可通过 sessionId 重新挂载
+var (
+	sessionsMu sync.Mutex
+	sessions   = map[string]*Session{}
+)
+
+// GetSession 查找仍在运行的会话
+func GetSession(id string) *Session {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	return sessions[id]
+}
+
+// Session 一个 SSH 终端会话，与浏览器连接解耦，可被多次挂载
+type Session struct {
+	ID       string
+	UserID   uint
+	Username string
+	Host     models.HostModel
+
+	audit    *models.TerminalSessionModel
+	client   *ssh.Client
+	session  *ssh.Session
+	stdin    io.WriteCloser
+	recorder *Recorder
+	filter   *CommandFilter
+
+	mu         sync.Mutex
+	clients    map[*Client]struct{}
+	scrollback []byte
+	cols       int
+	rows       int
+	lastInput  time.Time
+	graceTimer *time.Timer
+	closed     bool
+	done       chan struct{}
+}
+
+// SessionOptions 创建会话的参数
+type SessionOptions struct {
+	UserID   uint
+	Username string
+	Role     string
+	Host     models.HostModel
+	ClientIP string
+	Cols     int
+	Rows     int
+
+	BroadcastID string // 属于广播终端时的批次ID
+}
+
+// NewSession 连接主机并启动 shell，失败时同样记录审计
+func NewSession(opts SessionOptions) (*Session, error) {
+	cols, rows := opts.Cols, opts.Rows
+	if !validSize(cols, rows) {
+		cols, rows = defaultCols, defaultRows
+	}
+
+	audit := StartAudit(opts.UserID, opts.Username, opts.Host, opts.ClientIP)
+	if opts.BroadcastID != "" && audit.ID != 0 {
+		audit.BroadcastID = opts.BroadcastID
+		global.DB.Model(audit).Update("broadcast_id", opts.BroadcastID)
+	}
+
+	// 连接主机，配置了跳板机时经由跳板机建立连接
+	// 使用以当前用户签发的短期证书登录
+	client, err := ssh_ser.DialAs(opts.Host, ssh_ser.CertUser{
+		UserID:   opts.UserID,
+		Username: opts.Username,
+		Purpose:  models.CertPurposeTerminal,
+	})
+	if err != nil {
+		EndAudit(audit, fmt.Sprintf("%s: %v", ExitConnectFailed, err), nil)
+		return nil, fmt.Errorf("SSH 连接失败: %v", err)
+	}
+	fail := func(format string, err error) (*Session, error) {
+		client.Close()
+		EndAudit(audit, fmt.Sprintf("%s: %v", ExitError, err), nil)
+		return nil, fmt.Errorf(format, err)
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fail("创建 SSH 会话失败: %v", err)
+	}
+	if err := session.RequestPty("xterm-256color", rows, cols, ssh.TerminalModes{
+		ssh.ECHO:          1,     // 启用回显
+		ssh.TTY_OP_ISPEED: 14400, // 输入速度
+		ssh.TTY_OP_OSPEED: 14400, // 输出速度
+	}); err != nil {
+		return fail("请求 PTY 失败: %v", err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return fail("创建 stdin 管道失败: %v", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return fail("创建 stdout 管道失败: %v", err)
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		return fail("创建 stderr 管道失败: %v", err)
+	}
+	if err := session.Shell(); err != nil {
+		return fail("启动 shell 失败: %v", err)
+	}
+
+	s := &Session{
+		ID:        newSessionID(),
+		UserID:    opts.UserID,
+		Username:  opts.Username,
+		Host:      opts.Host,
+		audit:     audit,
+		client:    client,
+		session:   session,
+		stdin:     stdin,
+		filter:    NewCommandFilter(opts.Role, opts.Username, opts.Host),
+		clients:   map[*Client]struct{}{},
+		cols:      cols,
+		rows:      rows,
+		lastInput: time.Now(),
+		done:      make(chan struct{}),
+	}
+
+	// 录制终端会话
+	s.recorder, err = NewRecorder(audit.ID, cols, rows, fmt.Sprintf("%s@%s", opts.Username, opts.Host.Name))
+	if err != nil {
+		global.Log.Errorf("创建终端录像失败: %v", err)
+	} else if s.recorder != nil {
+		global.DB.Model(audit).Update("recording_path", s.recorder.Path())
+	}
+
+	sessionsMu.Lock()
+	sessions[s.ID] = s
+	sessionsMu.Unlock()
+
+	go s.pump(stderr)
+	go func() {
+		// stdout 读完说明远端 shell 已退出
+		s.pump(stdout)
+		session.Wait()
+		s.Close(ExitShellExited)
+	}()
+	go s.keepalive()
+	go s.watchIdle()
+	return s, nil
+}
+
+// Serve 把一个浏览器连接挂载到会话上，直到连接断开
+func (s *Session) Serve(conn *websocket.Conn, opts ClientOptions) {
+	c := newClient(conn, opts)
+	if !s.attach(c) {
+		c.Close(ExitShellExited)
+		return
+	}
+	defer s.detach(c)
+
+	conn.SetReadDeadline(time.Now().Add(readWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readWait))
+	})
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if !IsNormalClose(err) {
+				global.Log.Debugf("读取 WebSocket 消息失败: %v", err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(readWait))
+
+		req, ok := c.parseMessage(message)
+		if !ok {
+			continue
+		}
+		// 只读观察者只能收到输出
+		if c.ReadOnly() && req.kind != "ping" {
+			continue
+		}
+		switch req.kind {
+		case "ping":
+			c.pong()
+		case "resize":
+			s.Resize(req.cols, req.rows)
+		case "input":
+			if err := s.Input(c, req.data); err != nil {
+				global.Log.Errorf("写入命令失败: %v", err)
+				s.Close(fmt.Sprintf("%s: %v", ExitError, err))
+				return
+			}
+		}
+	}
+}
+
+// attach 挂载客户端并回放最近的输出，同时通知其他参与者。
+// 参与记录在加锁前写入，数据库慢时不阻塞会话的输出
+func (s *Session) attach(c *Client) bool {
+	participant := joinParticipant(s.audit.ID, c.opts)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		go leaveParticipant(participant)
+		return false
+	}
+	if s.graceTimer != nil {
+		s.graceTimer.Stop()
+		s.graceTimer = nil
+	}
+	c.participant = participant
+	if c.opts.Mode != models.TerminalModeOwner {
+		s.recorder.Marker(fmt.Sprintf("%s 加入会话（%s）", c.opts.Username, c.opts.Mode))
+	}
+	for other := range s.clients {
+		other.Control(ControlMessage{Type: "join", Username: c.opts.Username, Mode: c.opts.Mode})
+	}
+	s.clients[c] = struct{}{}
+	c.Control(ControlMessage{Type: "session", SessionID: s.ID, Cols: s.cols, Rows: s.rows, Mode: c.opts.Mode})
+	c.Output(s.scrollback)
+	return true
+}
+
+// detach 卸载客户端，没有客户端时等待宽限期后结束会话
+func (s *Session) detach(c *Client) {
+	c.Close(ExitClientClosed)
+	if s.removeClient(c) {
+		leaveParticipant(c.participant)
+	}
+}
+
+// removeClient 从会话中移除客户端并通知其他参与者，客户端已不在会话中时返回 false
+func (s *Session) removeClient(c *Client) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 会话已结束时由 Close 负责收尾
+	if _, ok := s.clients[c]; !ok {
+		return false
+	}
+	delete(s.clients, c)
+	if c.opts.Mode != models.TerminalModeOwner {
+		s.recorder.Marker(fmt.Sprintf("%s 离开会话", c.opts.Username))
+	}
+	for other := range s.clients {
+		other.Control(ControlMessage{Type: "leave", Username: c.opts.Username})
+	}
+	if s.closed || len(s.clients) > 0 {
+		return true
+	}
+	grace := time.Duration(global.Config.Terminal.ReconnectGrace) * time.Second
+	if grace <= 0 {
+		go s.Close(ExitClientClosed)
+		return true
+	}
+	s.graceTimer = time.AfterFunc(grace, func() {
+		s.mu.Lock()
+		idle := len(s.clients) == 0
+		s.mu.Unlock()
+		if idle {
+			s.Close(ExitClientClosed)
+		}
+	})
+	return true
+}
+
+// Input 处理用户输入，经过命令过滤后写入远端 shell，提示只发给输入的客户端
+func (s *Session) Input(c *Client, data []byte) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastInput = time.Now()
+	s.mu.Unlock()
+
+	s.recorder.Input(data)
+	result := s.filter.Input(data)
+	if result.Marker != "" {
+		s.recorder.Marker(result.Marker)
+	}
+	if result.Notice != "" {
+		s.recorder.Output([]byte(result.Notice))
+		c.Output([]byte(result.Notice))
+	}
+	if len(result.Forward) == 0 {
+		return nil
+	}
+	_, err := s.stdin.Write(result.Forward)
+	return err
+}
+
+// Resize 调整远端 PTY 大小
+func (s *Session) Resize(cols, rows int) {
+	if !validSize(cols, rows) {
+		return
+	}
+	s.mu.Lock()
+	if s.closed || (s.cols == cols && s.rows == rows) {
+		s.mu.Unlock()
+		return
+	}
+	s.cols, s.rows = cols, rows
+	s.lastInput = time.Now()
+	s.mu.Unlock()
+
+	if err := s.session.WindowChange(rows, cols); err != nil {
+		global.Log.Debugf("调整终端大小失败: %v", err)
+		return
+	}
+	s.recorder.Resize(cols, rows)
+}
+
+// broadcast 把输出写入回放缓冲、录像和所有客户端
+func (s *Session) broadcast(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.recorder.Output(data)
+	s.filter.Output(data)
+	s.scrollback = append(s.scrollback, data...)
+	if over := len(s.scrollback) - scrollbackSize; over > 0 {
+		// 丢弃开头的数据，并跳过被截断的 UTF-8 字符
+		cut := over
+		for cut < len(s.scrollback) && !utf8.RuneStart(s.scrollback[cut]) {
+			cut++
+		}
+		s.scrollback = append(s.scrollback[:0:0], s.scrollback[cut:]...)
+	}
+	for c := range s.clients {
+		c.Output(data)
+	}
+}
+
+// notice 向所有客户端发送提示
+func (s *Session) notice(text string) {
+	s.broadcast([]byte(text))
+}
+
+// Close 结束会话，只有第一次调用生效
+func (s *Session) Close(reason string) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	if s.graceTimer != nil {
+		s.graceTimer.Stop()
+	}
+	clients := s.clients
+	s.clients = map[*Client]struct{}{}
+	s.mu.Unlock()
+
+	for c := range clients {
+		c.Close(reason)
+		leaveParticipant(c.participant)
+	}
+	s.session.Close()
+	s.client.Close()
+	EndAudit(s.audit, reason, s.recorder)
+
+	sessionsMu.Lock()
+	delete(sessions, s.ID)
+	sessionsMu.Unlock()
+	close(s.done)
+}
+
+// pump 读取远端输出，保留不完整的 UTF-8 字符到下一次读取
+func (s *Session) pump(r io.Reader) {
+	buf := make([]byte, 8192)
+	var pending []byte
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			data := append(pending, buf[:n]...)
+			pending = nil
+			if tail := incompleteTail(data); tail > 0 {
+				pending = append([]byte(nil), data[len(data)-tail:]...)
+				data = data[:len(data)-tail]
+			}
+			if len(data) > 0 {
+				s.broadcast([]byte(strings.ToValidUTF8(string(data), "�")))
+			}
+		}
+		if err != nil {
+			if len(pending) > 0 {
+				s.broadcast([]byte(strings.ToValidUTF8(string(pending), "�")))
+			}
+			return
+		}
+	}
+}
+
+// keepalive 定期发送 SSH 保活请求，防止中间设备断开空闲连接，失败时结束会话
+func (s *Session) keepalive() {
+	interval := time.Duration(global.Config.Terminal.KeepaliveInterval) * time.Second
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			result := make(chan error, 1)
+			go func() {
+				_, _, err := s.client.SendRequest("keepalive@openssh.com", true, nil)
+				result <- err
+			}()
+			select {
+			case err := <-result:
+				// 服务端不认识该请求时会回复失败，但连接仍然可用
+				if err != nil {
+					s.Close(fmt.Sprintf("%s: %v", ExitKeepaliveFailed, err))
+					return
+				}
+			case <-time.After(interval):
+				s.Close(ExitKeepaliveFailed)
+				return
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// watchIdle 长时间没有输入时断开会话，远端输出不算活动
+func (s *Session) watchIdle() {
+	timeout := time.Duration(global.Config.Terminal.IdleTimeout) * time.Minute
+	if timeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			idle := time.Since(s.lastInput)
+			s.mu.Unlock()
+			if idle >= timeout {
+				s.notice(fmt.Sprintf("\r\n\033[33m会话已超时（%d 分钟无操作），连接已断开。\033[0m\r\n", global.Config.Terminal.IdleTimeout))
+				s.Close(ExitIdleTimeout)
+				return
+			}
+		}
+	}
+}
+
+// incompleteTail 返回末尾不完整的 UTF-8 字符的字节数
+func incompleteTail(data []byte) int {
+	for i := 1; i <= utf8.UTFMax-1 && i <= len(data); i++ {
+		b := data[len(data)-i]
+		if !utf8.RuneStart(b) {
+			continue
+		}
+		if !utf8.FullRune(data[len(data)-i:]) {
+			return i
+		}
+		return 0
+	}
+	return 0
+}
+
+func validSize(cols, rows int) bool {
+	return cols > 0 && rows > 0 && cols <= maxTermSize && rows <= maxTermSize
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,239 @@
+package tunnel_ser
+
+import (
+	"ccops/global"
+	"ccops/models"
+	"ccops/service/ssh_ser"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pingPeriod = 30 * time.Second
+	bufferSize = 32 * 1024
+)
+
+// activeTunnel 正在使用的转发，同一转发的多个连接共用一条 SSH 连接
+type activeTunnel struct {
+	mu     sync.Mutex
+	client *ssh.Client
+	conns  map[*websocket.Conn]struct{}
+	timer  *time.Timer
+	closed bool
+}
+
+var (
+	mu      sync.Mutex
+	tunnels = map[uint]*activeTunnel{}
+)
+
+// NewToken 生成连接令牌
+func NewToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// getTunnel 取得转发的运行状态，第一次使用时建立 SSH 连接，并在过期时关闭
+func getTunnel(tunnel models.TunnelModel) (*activeTunnel, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if t, ok := tunnels[tunnel.ID]; ok && !t.closed {
+		return t, nil
+	}
+
+	var host models.HostModel
+	if err := global.DB.First(&host, tunnel.HostID).Error; err != nil {
+		return nil, fmt.Errorf("主机不存在")
+	}
+	client, err := ssh_ser.DialAs(host, ssh_ser.CertUser{
+		UserID:   tunnel.UserID,
+		Username: tunnel.Username,
+		Purpose:  models.CertPurposeTerminal,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("SSH 连接失败: %v", err)
+	}
+	t := &activeTunnel{
+		client: client,
+		conns:  map[*websocket.Conn]struct{}{},
+	}
+	id := tunnel.ID
+	t.timer = time.AfterFunc(time.Until(tunnel.ExpiresAt), func() {
+		Close(id)
+	})
+	tunnels[id] = t
+	// SSH 连接断开后丢弃，下次连接时重新建立
+	go func() {
+		client.Wait()
+		mu.Lock()
+		if tunnels[id] == t {
+			delete(tunnels, id)
+		}
+		mu.Unlock()
+	}()
+	return t, nil
+}
+
+// Close 关闭转发的 SSH 连接和所有桥接中的连接，用于过期和手动关闭
+func Close(id uint) {
+	mu.Lock()
+	t, ok := tunnels[id]
+	delete(tunnels, id)
+	mu.Unlock()
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	t.closed = true
+	t.timer.Stop()
+	for conn := range t.conns {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, "tunnel closed"), time.Now().Add(writeWait))
+		conn.Close()
+	}
+	t.mu.Unlock()
+	t.client.Close()
+}
+
+func (t *activeTunnel) add(conn *websocket.Conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return false
+	}
+	t.conns[conn] = struct{}{}
+	return true
+}
+
+func (t *activeTunnel) remove(conn *websocket.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.conns, conn)
+}
+
+// Serve 把一个 WebSocket 连接桥接到目标端口，二进制消息即 TCP 数据，连接结束后累计审计信息
+func Serve(ws *websocket.Conn, tunnel models.TunnelModel) {
+	defer ws.Close()
+
+	t, err := getTunnel(tunnel)
+	if err != nil {
+		ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, truncate(err.Error())), time.Now().Add(writeWait))
+		return
+	}
+	target := net.JoinHostPort(tunnel.TargetHost, strconv.Itoa(tunnel.TargetPort))
+	remote, err := t.client.Dial("tcp", target)
+	if err != nil {
+		ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, truncate("连接目标失败: "+err.Error())), time.Now().Add(writeWait))
+		return
+	}
+	defer remote.Close()
+	if !t.add(ws) {
+		return
+	}
+	defer t.remove(ws)
+
+	var bytesIn, bytesOut int64
+	done := make(chan struct{})
+
+	// 目标 -> WebSocket
+	go func() {
+		defer close(done)
+		buf := make([]byte, bufferSize)
+		var writeMu sync.Mutex
+		stopPing := make(chan struct{})
+		defer close(stopPing)
+		go func() {
+			ticker := time.NewTicker(pingPeriod)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stopPing:
+					return
+				case <-ticker.C:
+					writeMu.Lock()
+					err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+					writeMu.Unlock()
+					if err != nil {
+						return
+					}
+				}
+			}
+		}()
+		for {
+			n, err := remote.Read(buf)
+			if n > 0 {
+				atomic.AddInt64(&bytesOut, int64(n))
+				writeMu.Lock()
+				ws.SetWriteDeadline(time.Now().Add(writeWait))
+				werr := ws.WriteMessage(websocket.BinaryMessage, buf[:n])
+				writeMu.Unlock()
+				if werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				writeMu.Lock()
+				ws.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, "remote closed"), time.Now().Add(writeWait))
+				writeMu.Unlock()
+				ws.Close()
+				return
+			}
+		}
+	}()
+
+	// WebSocket -> 目标
+	for {
+		messageType, reader, err := ws.NextReader()
+		if err != nil {
+			break
+		}
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+		n, err := io.Copy(remote, reader)
+		atomic.AddInt64(&bytesIn, n)
+		if err != nil {
+			break
+		}
+	}
+	remote.Close()
+	<-done
+
+	now := time.Now()
+	global.DB.Model(&models.TunnelModel{}).Where("id = ?", tunnel.ID).Updates(map[string]any{
+		"connections":  gorm.Expr("connections + 1"),
+		"bytes_in":     gorm.Expr("bytes_in + ?", atomic.LoadInt64(&bytesIn)),
+		"bytes_out":    gorm.Expr("bytes_out + ?", atomic.LoadInt64(&bytesOut)),
+		"last_used_at": now,
+	})
+}
+
+// truncate 关闭帧的原因最长 123 字节，截断时不拆开 UTF-8 字符
+func truncate(s string) string {
+	if len(s) <= 120 {
+		return s
+	}
+	n := 120
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
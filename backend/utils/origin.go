@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"ccops/global"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CheckOrigin WebSocket 来源校验。没有 Origin 头的请求（命令行、CI 等非浏览器客户端）直接放行，
+// 浏览器请求需同源或在 system.allowed_origins 中
+func CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range global.Config.System.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimRight(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"ccops/global"
 	"ccops/models"
 	"ccops/models/ctype"
+	"sort"
 )
 
 func IsAdmin(id uint) bool {
@@ -106,3 +107,65 @@ func IsPermissionForHosts(userId uint, hostIds []uint) bool {
 
 	return true
 }
+
+// IsTerminalPermission 检查用户是否有权限打开指定主机的终端，终端权限独立于查看权限单独授予
+func IsTerminalPermission(userId uint, hostId uint) bool {
+	if IsAdmin(userId) {
+		return true
+	}
+
+	// 直接授予的主机终端权限
+	var count int64
+	global.DB.Model(&models.HostPermission{}).
+		Where("user_id = ? AND host_id = ? AND terminal = ?", userId, hostId, true).
+		Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	// 通过标签授予的终端权限
+	global.DB.Model(&models.UserLabels{}).
+		Joins("JOIN host_labels ON host_labels.label_model_id = user_labels.label_id").
+		Where("user_labels.user_id = ? AND user_labels.terminal = ? AND host_labels.host_model_id = ?", userId, true, hostId).
+		Count(&count)
+	return count > 0
+}
+
+// TerminalUserIds 获取有指定主机终端权限的所有用户ID，与 IsTerminalPermission 的判断一致
+func TerminalUserIds(hostId uint) []uint {
+	// 管理员
+	var adminIds []uint
+	global.DB.Model(&models.UserModel{}).
+		Where("role = ?", ctype.PermissionAdmin).
+		Pluck("id", &adminIds)
+
+	// 用户删除时不清理标签关联，只统计仍存在的用户
+	users := global.DB.Model(&models.UserModel{}).Select("id")
+
+	// 直接授予的主机终端权限
+	var directIds []uint
+	global.DB.Model(&models.HostPermission{}).
+		Where("host_id = ? AND terminal = ? AND user_id IN (?)", hostId, true, users).
+		Pluck("user_id", &directIds)
+
+	// 通过标签授予的终端权限
+	var labelIds []uint
+	global.DB.Model(&models.UserLabels{}).
+		Joins("JOIN host_labels ON host_labels.label_model_id = user_labels.label_id").
+		Where("user_labels.terminal = ? AND host_labels.host_model_id = ? AND user_labels.user_id IN (?)", true, hostId, users).
+		Pluck("user_labels.user_id", &labelIds)
+
+	// 合并去重，按ID排序使下发的主体顺序稳定
+	userMap := make(map[uint]bool)
+	var result []uint
+	for _, ids := range [][]uint{adminIds, directIds, labelIds} {
+		for _, id := range ids {
+			if !userMap[id] {
+				userMap[id] = true
+				result = append(result, id)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}